@@ -2,79 +2,418 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"flag"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"pemesananTiketOnlineGo/internal/domain"
 	"pemesananTiketOnlineGo/internal/handler"
+	"pemesananTiketOnlineGo/internal/payment"
 	"pemesananTiketOnlineGo/internal/repository"
 	"pemesananTiketOnlineGo/internal/usecase"
 	"runtime"
 	"sync"
+	"time"
+	_ "time/tzdata" // the event time zone still load on a machine without zoneinfo
 )
 
 func main() {
+	dbBackend := flag.String("db", "memory", "storage backend: memory or sqlite")
+	dbPath := flag.String("dbpath", "tiket.db", "sqlite database file, used when -db=sqlite")
+	holdWindow := flag.Duration("holdwindow", 10*time.Minute, "how long a reservation hold the ticket before it expire")
+	reaperInterval := flag.Duration("reaperinterval", 30*time.Second, "how often expired reservation, unpaid order and idempotency key is checked")
+	secret := flag.String("secret", os.Getenv("TIKET_SECRET"), "secret for signing login token, default is $TIKET_SECRET")
+	tokenTTL := flag.Duration("tokenttl", 24*time.Hour, "how long a login token is valid")
+	adminName := flag.String("adminname", "admin", "name of the admin made on startup")
+	adminPassword := flag.String("adminpassword", os.Getenv("TIKET_ADMIN_PASSWORD"), "password of the admin made on startup, default is $TIKET_ADMIN_PASSWORD, empty mean no admin is made")
+	paymentSecret := flag.String("paymentsecret", os.Getenv("TIKET_PAYMENT_SECRET"), "secret the payment simulator sign its callback with, default is $TIKET_PAYMENT_SECRET")
+	paymentOutcome := flag.String("paymentoutcome", domain.SimulateSuccess, "what the payment simulator do with QRIS, bank transfer and card payment: success, fail, timeout or manual")
+	paymentDelay := flag.Duration("paymentdelay", 2*time.Second, "how long the payment simulator wait before the callback")
+	paymentWindow := flag.Duration("paymentwindow", 15*time.Minute, "how long an order wait for its payment before it expire")
+	queueInterval := flag.Duration("queueinterval", time.Second, "how often the waiting room let the next user in")
+	waitlistInterval := flag.Duration("waitlistinterval", 5*time.Second, "how often the stock that came back is offered to the waitlist")
+	ticketSecret := flag.String("ticketsecret", os.Getenv("TIKET_TICKET_SECRET"), "secret the Ed25519 key that sign the ticket QR is made from, default is $TIKET_TICKET_SECRET")
+	idempotencyTTL := flag.Duration("idempotencyttl", 24*time.Hour, "how long the response of an Idempotency-Key is kept for a retry")
+	flag.Parse()
+
+	// the seeding, the reapers and the workers are not a request, they run as the server itself
+	system := domain.WithSystem(context.Background())
+
+	// without a secret every restart sign with a new random one, so old token stop working
+	signingKey := []byte(*secret)
+	if len(signingKey) == 0 {
+		signingKey = make([]byte, 32)
+		if _, err := rand.Read(signingKey); err != nil {
+			log.Fatal("Error making token secret: ", err)
+		}
+		fmt.Println("No -secret given, login token will not survive a restart")
+	}
+	switch *paymentOutcome {
+	case domain.SimulateSuccess, domain.SimulateFail, domain.SimulateTimeout, domain.SimulateManual:
+	default:
+		log.Fatal("Unknown -paymentoutcome: ", *paymentOutcome)
+	}
+	paymentKey := []byte(*paymentSecret)
+	if len(paymentKey) == 0 {
+		paymentKey = make([]byte, 32)
+		if _, err := rand.Read(paymentKey); err != nil {
+			log.Fatal("Error making payment secret: ", err)
+		}
+	}
+	// the same secret always make the same key, so a printed QR still scan after a restart
+	var ticketSeed [ed25519.SeedSize]byte
+	if *ticketSecret != "" {
+		ticketSeed = sha256.Sum256([]byte(*ticketSecret))
+	} else {
+		if _, err := rand.Read(ticketSeed[:]); err != nil {
+			log.Fatal("Error making ticket key: ", err)
+		}
+		fmt.Println("No -ticketsecret given, ticket QR will not survive a restart")
+	}
+	passKey := ed25519.NewKeyFromSeed(ticketSeed[:])
+
 	runtime.GOMAXPROCS(4)
 	var wg sync.WaitGroup
 
+	// pick the storage backend
+	var eventRepo repository.EventRepoInterface
+	var userRepo repository.UserRepoInterface
+	var orderRepo repository.OrderRepoInterface
+	var holdRepo repository.HoldRepoInterface
+	var walletRepo repository.WalletRepoInterface
+	var idempotencyRepo repository.IdempotencyRepoInterface
+	var promoRepo repository.PromoRepoInterface
+	var pricingRepo repository.PricingRepoInterface
+	var seatRepo repository.SeatRepoInterface
+	var waitlistRepo repository.WaitlistRepoInterface
+	var queueRepo repository.QueueRepoInterface
+	var issuedTicketRepo repository.IssuedTicketRepoInterface
+	var paymentRepo repository.PaymentRepoInterface
+	var unitOfWork repository.UnitOfWorkInterface
+	// the queue commit apart from the purchase, so joining it never wait for the stock lock
+	var queueUnitOfWork repository.UnitOfWorkInterface
+	switch *dbBackend {
+	case "memory":
+		eventRepo = repository.NewEventRepo()
+		userRepo = repository.NewUserRepo()
+		orderRepo = repository.NewOrderRepo()
+		holdRepo = repository.NewHoldRepo()
+		walletRepo = repository.NewWalletRepo()
+		idempotencyRepo = repository.NewIdempotencyRepo()
+		promoRepo = repository.NewPromoRepo()
+		pricingRepo = repository.NewPricingRepo()
+		seatRepo = repository.NewSeatRepo()
+		waitlistRepo = repository.NewWaitlistRepo()
+		queueRepo = repository.NewQueueRepo()
+		issuedTicketRepo = repository.NewIssuedTicketRepo()
+		paymentRepo = repository.NewPaymentRepo()
+		unitOfWork = repository.NewUnitOfWork()
+		queueUnitOfWork = repository.NewUnitOfWork()
+	case "sqlite":
+		db, err := repository.OpenSqlite(*dbPath)
+		if err != nil {
+			log.Fatal("Error opening database: ", err)
+		}
+		defer db.Close()
+		eventRepo = repository.NewEventRepoSql(db)
+		userRepo = repository.NewUserRepoSql(db)
+		orderRepo = repository.NewOrderRepoSql(db)
+		holdRepo = repository.NewHoldRepoSql(db)
+		walletRepo = repository.NewWalletRepoSql(db)
+		idempotencyRepo = repository.NewIdempotencyRepoSql(db)
+		promoRepo = repository.NewPromoRepoSql(db)
+		pricingRepo = repository.NewPricingRepoSql(db)
+		seatRepo = repository.NewSeatRepoSql(db)
+		waitlistRepo = repository.NewWaitlistRepoSql(db)
+		queueRepo = repository.NewQueueRepoSql(db)
+		issuedTicketRepo = repository.NewIssuedTicketRepoSql(db)
+		paymentRepo = repository.NewPaymentRepoSql(db)
+		unitOfWork = repository.NewUnitOfWorkSql(db)
+		queueUnitOfWork = unitOfWork
+	default:
+		log.Fatal("Unknown -db backend: ", *dbBackend)
+	}
+
+	// waitlist connection, the offer is a reservation that last as long as a normal one
+	waitlistUsecase := usecase.NewWaitlistUsecase(waitlistRepo, eventRepo, holdRepo, orderRepo, unitOfWork, *holdWindow)
+	waitlistHandler := handler.NewWaitlistHandler(waitlistUsecase)
+
 	// event connection
-	eventRepo := repository.NewEventRepo()
-	eventUsecase := usecase.NewEventUsecase(eventRepo)
+	eventUsecase := usecase.NewEventUsecase(eventRepo, holdRepo, orderRepo, unitOfWork)
 	eventHandler := handler.NewEventHandler(eventUsecase)
+	ticketUsecase := usecase.NewTicketUsecase(eventRepo, waitlistUsecase)
+	ticketHandler := handler.NewTicketHandler(ticketUsecase)
+	promoUsecase := usecase.NewPromoUsecase(promoRepo, eventRepo)
+	promoHandler := handler.NewPromoHandler(promoUsecase)
+	pricingUsecase := usecase.NewPricingUsecase(pricingRepo)
+	pricingHandler := handler.NewPricingHandler(pricingUsecase)
+	seatUsecase := usecase.NewSeatUsecase(seatRepo, eventRepo, orderRepo, unitOfWork)
+	seatHandler := handler.NewSeatHandler(seatUsecase)
 
 	// user connection
-	userRepo := repository.NewUserRepo()
-	userUsecase := usecase.NewUserUsecase(userRepo)
+	userUsecase := usecase.NewUserUsecase(userRepo, walletRepo)
 	userHandler := handler.NewUserHandler(userUsecase)
 
+	// login connection
+	authUsecase := usecase.NewAuthUsecase(userRepo, signingKey, *tokenTTL)
+	authHandler := handler.NewAuthHandler(authUsecase)
+
+	// payment provider, wallet is paid at once and the simulator play QRIS, bank transfer and card gateway
+	simulator := payment.NewSimulator(paymentKey, paymentRepo)
+	payments := payment.Providers{
+		domain.PaymentWallet:       payment.NewWalletProvider(walletRepo),
+		domain.PaymentQRIS:         simulator,
+		domain.PaymentBankTransfer: simulator,
+		domain.PaymentCard:         simulator,
+	}
+	for _, method := range []string{domain.PaymentQRIS, domain.PaymentBankTransfer, domain.PaymentCard} {
+		simulator.Script(domain.SimulatorScript{Method: method, Outcome: *paymentOutcome, DelayMs: int(paymentDelay.Milliseconds())})
+	}
+	paymentUsecase := usecase.NewPaymentUsecase(orderRepo, eventRepo, promoRepo, seatRepo, issuedTicketRepo, unitOfWork, payments, simulator, waitlistUsecase)
+	paymentHandler := handler.NewPaymentHandler(paymentUsecase)
+
+	// the simulator run in the process, so its callback go straight to the usecase
+	simulator.OnCallback(func(callback domain.PaymentCallback) {
+		if _, err := paymentUsecase.PaymentCallback(callback, system); err != nil {
+			fmt.Println("Payment callback failed:", err)
+		}
+	})
+
 	// order connection
-	orderRepo := repository.NewOrderRepo()
-	orderUsecase := usecase.NewOrderUsecase(orderRepo, eventRepo, userRepo)
-	orderHandler := handler.NewOrderHandler(orderUsecase)
+	orderUsecase := usecase.NewOrderUsecase(orderRepo, eventRepo, userRepo, promoRepo, pricingRepo, seatRepo, waitlistRepo, queueRepo, issuedTicketRepo, payments, unitOfWork, waitlistUsecase, *paymentWindow)
+	idempotencyUsecase := usecase.NewIdempotencyUsecase(idempotencyRepo, *idempotencyTTL)
+	orderHandler := handler.NewOrderHandler(orderUsecase, idempotencyUsecase)
+
+	// wallet connection
+	walletUsecase := usecase.NewWalletUsecase(walletRepo, userRepo, orderRepo)
+	walletHandler := handler.NewWalletHandler(walletUsecase)
+
+	// reservation connection
+	holdUsecase := usecase.NewHoldUsecase(holdRepo, orderRepo, eventRepo, userRepo, promoRepo, pricingRepo, seatRepo, waitlistRepo, queueRepo, issuedTicketRepo, payments, unitOfWork, waitlistUsecase, *holdWindow)
+	holdHandler := handler.NewHoldHandler(holdUsecase)
+
+	// waiting room connection
+	queueUsecase := usecase.NewQueueUsecase(queueRepo, eventRepo, queueUnitOfWork)
+	queueHandler := handler.NewQueueHandler(queueUsecase)
+
+	// issued ticket connection
+	issuedTicketUsecase := usecase.NewIssuedTicketUsecase(issuedTicketRepo, eventRepo, userRepo, unitOfWork, passKey)
+	issuedTicketHandler := handler.NewIssuedTicketHandler(issuedTicketUsecase)
 
 	// create event
 
+	// every event get its own copy of the ticket types, the ID is given when the event is saved
 	tickets := []domain.Ticket{
-		{ID: 1, Type: "VIP", Price: 5000.0, Quantity: 10},
-		{ID: 2, Type: "CAT 1", Price: 250.0, Quantity: 100},
+		{Type: "VIP", Price: domain.NewMoney(500000, domain.DefaultCurrency), Quantity: 10},
+		{Type: "CAT 1", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 100},
 	}
 
+	// no refund in the last 24 hours before the event
+	refund := &domain.RefundPolicy{Refundable: true, CutoffHours: 24}
+
+	// every concert is 3 hours long in the default time zone
+	jakarta, err := time.LoadLocation(domain.DefaultTimeZone)
+	if err != nil {
+		log.Fatal("Error loading time zone: ", err)
+	}
+	concert := func(day int) (time.Time, time.Time) {
+		start := time.Date(2027, time.January, day, 15, 4, 5, 0, jakarta)
+		return start, start.Add(3 * time.Hour)
+	}
+	start1, end1 := concert(2)
+	start2, end2 := concert(3)
+	start3, end3 := concert(4)
+
 	events := []domain.Event{
-		{ID: 1, Name: "Concert1", Date: "02-Jan-2006 15:04:05", Description: "Awokwok1", Location: "Location1", Ticket: tickets},
-		{ID: 2, Name: "Concert2", Date: "03-Jan-2006 15:04:05", Description: "Awokwok2", Location: "Location2", Ticket: tickets},
-		{ID: 3, Name: "Concert3", Date: "04-Jan-2006 15:04:05", Description: "Awokwok3", Location: "Location3", Ticket: tickets},
-		{ID: 4, Name: "Concert4", Date: "03-Jan-2006 15:04:05", Description: "Awokwok4", Location: "Location4", Ticket: tickets},
-		{ID: 5, Name: "Concert5", Date: "03-Jan-2006 15:04:05", Description: "Awokwok5", Location: "Location5", Ticket: tickets},
+		{ID: 1, Name: "Concert1", Start: start1, End: end1, Description: "Awokwok1", Location: "Location1", Ticket: tickets, Refund: refund},
+		{ID: 2, Name: "Concert2", Start: start2, End: end2, Description: "Awokwok2", Location: "Location2", Ticket: tickets, Refund: refund},
+		{ID: 3, Name: "Concert3", Start: start3, End: end3, Description: "Awokwok3", Location: "Location3", Ticket: tickets, Refund: refund},
+		{ID: 4, Name: "Concert4", Start: start2, End: end2, Description: "Awokwok4", Location: "Location4", Ticket: tickets},
+		{ID: 5, Name: "Concert5", Start: start2, End: end2, Description: "Awokwok5", Location: "Location5", Ticket: tickets},
 	}
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		for _, value := range events {
-			eventUsecase.CreateEvent(value, context.Background())
+			eventUsecase.CreateEvent(value, system)
 		}
 	}()
 
+	// the first admin can't be made from the api, so make it here
+	if *adminPassword != "" {
+		if err := makeAdmin(userUsecase, *adminName, *adminPassword); err != nil {
+			log.Fatal("Error making admin: ", err)
+		}
+	}
+
+	// give the ticket of expired reservation back to the stock
+	go holdUsecase.RunHoldReaper(system, *reaperInterval)
+	go paymentUsecase.RunPaymentReaper(system, *reaperInterval)
+	go idempotencyUsecase.RunKeyReaper(system, *reaperInterval)
+	// offer the stock that came back to the waiting user
+	go waitlistUsecase.RunWaitlistOffers(system, *waitlistInterval)
+	// let the next user of every waiting room in
+	go queueUsecase.RunQueueAdmission(system, *queueInterval)
+
 	routes := http.NewServeMux()
 	routes.HandleFunc("/event", eventHandler.CreateEvent)
 	routes.HandleFunc("/eventGet", eventHandler.GetAllEvents) //check all ticket and event
 	routes.HandleFunc("/eventGetById", eventHandler.GetEventByID)
 	routes.HandleFunc("/eventGetByName", eventHandler.GetEventByName)
+	routes.HandleFunc("/eventGetByDate", eventHandler.GetEventsByDate)         // ?from=&to= in RFC 3339
+	routes.HandleFunc("/eventAvailability", eventHandler.GetEventAvailability) // which ticket type is on sale now
 	routes.HandleFunc("/eventUpdate", eventHandler.UpdateEvent)
 	routes.HandleFunc("/eventDelete", eventHandler.DeleteEvent)
 
-	routes.HandleFunc("/userPost", userHandler.CreateUser)
+	routes.HandleFunc("/ticketType", ticketHandler.CreateTicket) // add a ticket type to an event
+	routes.HandleFunc("/ticketTypeGetById", ticketHandler.GetTicketByID)
+	routes.HandleFunc("/ticketTypeUpdate", ticketHandler.UpdateTicket)
+	routes.HandleFunc("/ticketTypeClose", ticketHandler.CloseTicket) // stop selling a ticket type
+
+	routes.HandleFunc("/seatMap", seatHandler.CreateSeatMap) // add sections, rows and seats to an event
+	routes.HandleFunc("/seatMapGet", seatHandler.GetSeatMap) // live status of every seat, ?eventid=
+
+	routes.HandleFunc("/promo", promoHandler.CreatePromo) // percent or fixed discount code
+	routes.HandleFunc("/promoGetAll", promoHandler.GetAllPromos)
+	routes.HandleFunc("/promoGetByCode", promoHandler.GetPromoByCode)
+	routes.HandleFunc("/promoUpdate", promoHandler.UpdatePromo)
+
+	routes.HandleFunc("/pricing", pricingHandler.SetPricing) // platform fee and tax of one currency
+	routes.HandleFunc("/pricingGetAll", pricingHandler.GetAllPricings)
+
+	routes.HandleFunc("/userPost", userHandler.CreateUser) // register with name and password
+	routes.HandleFunc("/login", authHandler.Login)
 	routes.HandleFunc("/userGetAll", userHandler.GetAllUsers)
 	routes.HandleFunc("/userGetById", userHandler.GetUserByID)
 	routes.HandleFunc("/userGetByName", userHandler.GetUserByName)
 	routes.HandleFunc("/userUpdate", userHandler.UpdateUser)
 	routes.HandleFunc("/userDelete", userHandler.DeleteUser)
+	routes.HandleFunc("/userRole", userHandler.SetUserRole) // admin give admin, organizer or customer role
 
-	routes.HandleFunc("/buyTicket", orderHandler.CreateOrder) // buy the ticket
+	routes.HandleFunc("/buyTicket", orderHandler.CreateOrder) // buy the ticket as the logged in user
 	routes.HandleFunc("/orderGetAll", orderHandler.GetAllOrders)
 	routes.HandleFunc("/orderGetByUserId", orderHandler.GetOrderByID) // list all orders from that one user
+	routes.HandleFunc("/orderGetByNumber", orderHandler.GetOrderByNumber)
+	routes.HandleFunc("/orderCancel", orderHandler.CancelOrder)
+	routes.HandleFunc("/orderRefund", orderHandler.RefundOrder)
+	routes.HandleFunc("/organizerSales", orderHandler.GetOrganizerSales) // sales of the event owned by the organizer
+
+	routes.HandleFunc("/myTickets", issuedTicketHandler.GetMyTickets) // one ticket per person of the paid order
+	routes.HandleFunc("/ticketTransfer", issuedTicketHandler.TransferTicket)
+	routes.HandleFunc("/ticketCheckIn", issuedTicketHandler.CheckInTicket) // use the ticket at the gate, ?code= take the code or the scanned QR
+	routes.HandleFunc("/ticketQR", issuedTicketHandler.GetTicketQR)        // signed QR of the ticket, ?code=&format=png|svg&size=
+	routes.HandleFunc("/ticketPassKey", issuedTicketHandler.GetPassKey)    // public key to check the QR offline
+
+	routes.HandleFunc("/paymentCallback", paymentHandler.PaymentCallback) // webhook of the payment provider
+	routes.HandleFunc("/paymentConfirm", paymentHandler.ConfirmPayment)   // ask the provider instead of waiting for the callback
+	routes.HandleFunc("/paymentSimulatorScript", paymentHandler.ScriptSimulator)
+	routes.HandleFunc("/paymentSimulatorSettle", paymentHandler.SettleSimulated)
+
+	routes.HandleFunc("/walletAdjust", walletHandler.AdjustBalance) // admin correction or counter top up, can be negative
+	routes.HandleFunc("/walletStatement", walletHandler.GetStatement)
+	routes.HandleFunc("/walletReconcile", walletHandler.Reconcile) // check the ledger against the orders
+
+	routes.HandleFunc("/reserveTicket", holdHandler.ReserveTicket) // hold the ticket before paying
+	routes.HandleFunc("/reservationConfirm", holdHandler.ConfirmHold)
+	routes.HandleFunc("/reservationRelease", holdHandler.ReleaseHold)
+	routes.HandleFunc("/reservationGetById", holdHandler.GetHoldByID)
+
+	routes.HandleFunc("/waitlist", waitlistHandler.JoinWaitlist) // wait for a sold out ticket type
+	routes.HandleFunc("/waitlistLeave", waitlistHandler.LeaveWaitlist)
+	routes.HandleFunc("/waitlistGetByUserId", waitlistHandler.GetWaitlistByUserID) // the offer show up here as a reservation
+
+	routes.HandleFunc("/queueRoom", queueHandler.SetWaitingRoom) // open, tune or close the waiting room of an event
+	routes.HandleFunc("/queueRoomGet", queueHandler.GetWaitingRoom)
+	routes.HandleFunc("/queueJoin", queueHandler.JoinQueue)
+	routes.HandleFunc("/queueStatus", queueHandler.GetQueueStatus) // poll the place, ?token=
+	routes.HandleFunc("/queueStream", queueHandler.StreamQueue)    // server sent event of the place until admitted
+
+	// who can call every route, the usecase also check that customer and organizer only touch their own data
+	staff := handler.Roles(domain.RoleAdmin, domain.RoleOrganizer)
+	admin := handler.Roles(domain.RoleAdmin)
+	policy := handler.Policy{
+		"/event":             staff,
+		"/eventGet":          handler.Public(),
+		"/eventGetById":      handler.Public(),
+		"/eventGetByName":    handler.Public(),
+		"/eventGetByDate":    handler.Public(),
+		"/eventAvailability": handler.Public(),
+		"/eventUpdate":       staff,
+		"/eventDelete":       staff,
+
+		"/ticketType":        staff,
+		"/ticketTypeGetById": handler.Public(),
+		"/ticketTypeUpdate":  staff,
+		"/ticketTypeClose":   staff,
+
+		"/seatMap":    staff,
+		"/seatMapGet": handler.Public(),
+
+		"/promo":          staff,
+		"/promoGetAll":    staff,
+		"/promoGetByCode": staff,
+		"/promoUpdate":    staff,
+
+		"/pricing":       admin,
+		"/pricingGetAll": handler.Public(),
+
+		"/userPost":      handler.Public(),
+		"/login":         handler.Public(),
+		"/userGetAll":    admin,
+		"/userGetById":   handler.LoggedIn(),
+		"/userGetByName": handler.LoggedIn(),
+		"/userUpdate":    handler.LoggedIn(),
+		"/userDelete":    handler.LoggedIn(),
+		"/userRole":      admin,
+
+		"/buyTicket":        handler.LoggedIn(),
+		"/orderGetAll":      admin,
+		"/orderGetByUserId": handler.LoggedIn(),
+		"/orderGetByNumber": handler.LoggedIn(),
+		"/orderCancel":      handler.LoggedIn(),
+		"/orderRefund":      handler.LoggedIn(),
+		"/organizerSales":   staff,
+
+		"/myTickets":      handler.LoggedIn(),
+		"/ticketTransfer": handler.LoggedIn(),
+		"/ticketCheckIn":  staff,
+		"/ticketQR":       handler.LoggedIn(),
+		"/ticketPassKey":  handler.Public(),
+
+		"/paymentCallback":        handler.Public(),
+		"/paymentConfirm":         handler.LoggedIn(),
+		"/paymentSimulatorScript": admin,
+		"/paymentSimulatorSettle": admin,
+
+		"/walletAdjust":    admin,
+		"/walletStatement": handler.LoggedIn(),
+		"/walletReconcile": admin,
+
+		"/reserveTicket":      handler.LoggedIn(),
+		"/reservationConfirm": handler.LoggedIn(),
+		"/reservationRelease": handler.LoggedIn(),
+		"/reservationGetById": handler.LoggedIn(),
+
+		"/waitlist":            handler.LoggedIn(),
+		"/waitlistLeave":       handler.LoggedIn(),
+		"/waitlistGetByUserId": handler.LoggedIn(),
+
+		"/queueRoom":    staff,
+		"/queueRoomGet": handler.Public(),
+		"/queueJoin":    handler.LoggedIn(),
+		// the token is the secret of the place, so a browser EventSource that can't send a header can follow it
+		"/queueStatus": handler.Public(),
+		"/queueStream": handler.Public(),
+	}
 
 	server := http.Server{}
-	server.Handler = routes
+	server.Handler = handler.NewPolicyHandler(authUsecase, policy, routes)
 	server.Addr = ":8080"
 
 	wg.Add(1)
@@ -88,3 +427,17 @@ func main() {
 
 	wg.Wait()
 }
+
+// register the admin if it doesn't exist yet and give it the admin role
+func makeAdmin(userUsecase usecase.UserUsecaseInterface, name string, password string) error {
+	kontek := domain.WithSystem(context.Background())
+	user, err := userUsecase.GetUserByName(name, kontek)
+	if errors.Is(err, domain.ErrNotFound) {
+		user, err = userUsecase.CreateUser(domain.User{Name: name, Password: password}, kontek)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = userUsecase.SetUserRole(domain.RoleRequest{UserID: user.ID, Role: domain.RoleAdmin}, kontek)
+	return err
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// name and password sent to login
+type Credential struct {
+	Name     string `json:"name" validate:"noblank"`
+	Password string `json:"password" validate:"required"`
+}
+
+// signed bearer token given after login
+type Token struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// the part of the token that is signed
+type Claims struct {
+	UserID    int    `json:"sub"`
+	Name      string `json:"name"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// put the logged in user in the context
+func WithUser(kontek context.Context, user User) context.Context {
+	return context.WithValue(kontek, Key("user"), user)
+}
+
+// get the logged in user from the context, false when nobody is logged in
+func CurrentUser(kontek context.Context) (User, bool) {
+	user, ok := kontek.Value(Key("user")).(User)
+	return user, ok
+}
+
+// mark the context as the server itself (seeding, reapers, workers), it may touch every account
+func WithSystem(kontek context.Context) context.Context {
+	return context.WithValue(kontek, Key("system"), true)
+}
+
+// true when the context was made by the server itself and not by a request
+func IsSystem(kontek context.Context) bool {
+	system, _ := kontek.Value(Key("system")).(bool)
+	return system
+}
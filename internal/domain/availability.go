@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// what can be bought from an event right now and when the next sale phase open
+type EventAvailability struct {
+	EventID     int                  `json:"eventid"`
+	At          time.Time            `json:"at"`
+	Ticket      []TicketAvailability `json:"ticket"`
+	NextPhaseAt *time.Time           `json:"next_phase_at,omitempty"`
+	NextPhase   []int                `json:"next_phase,omitempty"` // ID of the ticket type that open at next_phase_at
+}
+
+type TicketAvailability struct {
+	Ticket
+	Status      string `json:"status"`
+	Purchasable bool   `json:"purchasable"`
+}
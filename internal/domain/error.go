@@ -0,0 +1,134 @@
+package domain
+
+import "errors"
+
+// kind of error, the handler pick the http status from it
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrOutOfStock          = errors.New("out of stock")
+	ErrConflict            = errors.New("conflict")
+	ErrInvalidInput        = errors.New("invalid input")
+	ErrForbidden           = errors.New("forbidden")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrGatewayTimeout      = errors.New("gateway timeout")
+)
+
+// error with a machine readable code, errors.Is match it with its kind
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+}
+
+func NewError(kind error, code string, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// error returned by the repo and usecase
+var (
+	ErrEventNotFound     = NewError(ErrNotFound, "EVENT_NOT_FOUND", "THERE'S NO EVENT WITH THAT ID")
+	ErrEventNameNotFound = NewError(ErrNotFound, "EVENT_NOT_FOUND", "THERE'S NO EVENT WITH THAT NAME")
+	ErrEventNameExist    = NewError(ErrConflict, "EVENT_NAME_EXIST", "EVENT WITH THAT NAME ALREADY EXIST")
+	ErrEventStarted      = NewError(ErrConflict, "EVENT_ALREADY_STARTED", "THE EVENT HAS ALREADY STARTED")
+	ErrEventHasOpenSale  = NewError(ErrConflict, "EVENT_HAS_OPEN_SALE", "THE EVENT STILL HAS AN ACTIVE RESERVATION OR AN UNPAID ORDER")
+	ErrInvalidTimeZone   = NewError(ErrInvalidInput, "INVALID_TIME_ZONE", "THAT TIME ZONE IS NOT A VALID IANA TIME ZONE")
+	ErrInvalidDateRange  = NewError(ErrInvalidInput, "INVALID_DATE_RANGE", "THE START OF THE RANGE MUST BE BEFORE ITS END")
+	ErrUserNotFound      = NewError(ErrNotFound, "USER_NOT_FOUND", "THERE'S NO USER WITH THAT ID")
+	ErrUserNameNotFound  = NewError(ErrNotFound, "USER_NOT_FOUND", "THERE'S NO USER WITH THAT NAME")
+	ErrUserNameExist     = NewError(ErrConflict, "USER_NAME_EXIST", "USER WITH THAT NAME ALREADY EXIST")
+	ErrPasswordRequired  = NewError(ErrInvalidInput, "PASSWORD_REQUIRED", "PASSWORD IS REQUIRED")
+	ErrWrongCredential   = NewError(ErrUnauthorized, "INVALID_CREDENTIAL", "WRONG NAME OR PASSWORD")
+	ErrMissingToken      = NewError(ErrUnauthorized, "MISSING_TOKEN", "AUTHORIZATION BEARER TOKEN IS REQUIRED")
+	ErrInvalidToken      = NewError(ErrUnauthorized, "INVALID_TOKEN", "TOKEN IS NOT VALID")
+	ErrTokenExpired      = NewError(ErrUnauthorized, "TOKEN_EXPIRED", "TOKEN HAS EXPIRED")
+	ErrNotYourAccount    = NewError(ErrForbidden, "NOT_YOUR_ACCOUNT", "YOU CAN ONLY ACCESS YOUR OWN ACCOUNT")
+	ErrNotYourEvent      = NewError(ErrForbidden, "NOT_YOUR_EVENT", "YOU CAN ONLY MANAGE YOUR OWN EVENT")
+	ErrNotYourOrder      = NewError(ErrForbidden, "NOT_YOUR_ORDER", "YOU CAN ONLY ACCESS YOUR OWN ORDER")
+	ErrNotYourHold       = NewError(ErrForbidden, "NOT_YOUR_RESERVATION", "YOU CAN ONLY ACCESS YOUR OWN RESERVATION")
+	ErrRoleNotAllowed    = NewError(ErrForbidden, "ROLE_NOT_ALLOWED", "YOUR ROLE IS NOT ALLOWED TO DO THIS")
+	ErrRouteNotFound     = NewError(ErrNotFound, "ROUTE_NOT_FOUND", "THERE'S NO ENDPOINT WITH THAT PATH")
+	ErrOrderNotFound     = NewError(ErrNotFound, "ORDER_NOT_FOUND", "THERE'S NO ORDER WITH THAT ID")
+	ErrUserHasNoOrder    = NewError(ErrNotFound, "ORDER_NOT_FOUND", "THIS USER HAVENT BUY A TICKET")
+	ErrHoldNotFound      = NewError(ErrNotFound, "RESERVATION_NOT_FOUND", "THERE'S NO RESERVATION WITH THAT ID")
+	ErrNotEnoughStock    = NewError(ErrOutOfStock, "OUT_OF_STOCK", "NOT ENOUGH TICKET STOCK")
+	ErrNotEnoughHeld     = NewError(ErrConflict, "NOT_ENOUGH_HELD_TICKET", "NOT ENOUGH HELD TICKET")
+	ErrTicketNotFound    = NewError(ErrNotFound, "TICKET_TYPE_NOT_FOUND", "THERE'S NO TICKET TYPE WITH THAT ID")
+	ErrTicketNotInEvent  = NewError(ErrNotFound, "TICKET_TYPE_NOT_FOUND", "THAT TICKET TYPE IS NOT SOLD BY THIS EVENT")
+	ErrTicketClosed      = NewError(ErrConflict, "TICKET_TYPE_CLOSED", "THAT TICKET TYPE IS CLOSED")
+	ErrTicketTypeExist   = NewError(ErrConflict, "TICKET_TYPE_EXIST", "THE EVENT ALREADY SELL A TICKET TYPE WITH THAT NAME")
+	ErrSaleNotStarted    = NewError(ErrConflict, "TICKET_SALE_NOT_STARTED", "THE SALE OF THAT TICKET TYPE HAS NOT STARTED YET")
+	ErrSaleEnded         = NewError(ErrConflict, "TICKET_SALE_ENDED", "THE SALE OF THAT TICKET TYPE HAS ENDED")
+	ErrInvalidSaleWindow = NewError(ErrInvalidInput, "INVALID_SALE_WINDOW", "THE SALE END MUST BE AFTER THE SALE START")
+	ErrPromoNotFound     = NewError(ErrNotFound, "PROMO_NOT_FOUND", "THERE'S NO PROMO WITH THAT CODE")
+	ErrPromoExist        = NewError(ErrConflict, "PROMO_CODE_EXIST", "PROMO WITH THAT CODE ALREADY EXIST")
+	ErrInvalidPromo      = NewError(ErrInvalidInput, "INVALID_PROMO", "PERCENT PROMO NEED A PERCENT, FIXED PROMO NEED AN AMOUNT, TICKET SCOPE NEED AN EVENT AND THE END MUST BE AFTER THE START")
+	ErrPromoNotActive    = NewError(ErrConflict, "PROMO_NOT_ACTIVE", "THAT PROMO CAN'T BE USED AT THIS TIME")
+	ErrPromoUsedUp       = NewError(ErrConflict, "PROMO_USED_UP", "THAT PROMO HAS BEEN USED UP")
+	ErrPromoUserLimit    = NewError(ErrConflict, "PROMO_USER_LIMIT_EXCEEDED", "YOU HAVE USED THAT PROMO AS MANY TIMES AS ALLOWED")
+	ErrPromoNotForOrder  = NewError(ErrConflict, "PROMO_NOT_APPLICABLE", "THAT PROMO DOESN'T APPLY TO ANY TICKET IN THE ORDER")
+	ErrPricingNotFound   = NewError(ErrNotFound, "PRICING_NOT_FOUND", "THERE'S NO FEE AND TAX FOR THAT CURRENCY")
+	ErrInvalidPricing    = NewError(ErrInvalidInput, "INVALID_PRICING", "THE CURRENCY MUST BE SUPPORTED AND EVERY FEE MUST BE IN IT")
+	ErrSeatNotFound      = NewError(ErrNotFound, "SEAT_NOT_FOUND", "THERE'S NO SEAT WITH THAT ID IN THE EVENT")
+	ErrSeatTaken         = NewError(ErrConflict, "SEAT_TAKEN", "THAT SEAT IS ALREADY HELD OR SOLD")
+	ErrSeatExist         = NewError(ErrConflict, "SEAT_EXIST", "THAT SEAT ALREADY EXIST IN THE EVENT")
+	ErrNoTicket          = NewError(ErrInvalidInput, "NO_TICKET", "PICK A TICKET OR A SEAT")
+	ErrSeatRequired      = NewError(ErrInvalidInput, "SEAT_REQUIRED", "THAT TICKET TYPE IS ONLY SOLD BY PICKING ITS SEAT")
+	ErrSectionTicket     = NewError(ErrConflict, "SECTION_HAS_OTHER_TICKET", "EVERY SEAT OF A SECTION IS SOLD AS ONE TICKET TYPE")
+	ErrUnseatedSold      = NewError(ErrConflict, "TICKET_SOLD_WITHOUT_SEAT", "THAT TICKET TYPE IS ALREADY SOLD OR HELD WITHOUT A SEAT")
+	ErrWaitlistNotFound  = NewError(ErrNotFound, "WAITLIST_NOT_FOUND", "THERE'S NO WAITLIST ENTRY WITH THAT ID")
+	ErrNotYourWaitlist   = NewError(ErrForbidden, "NOT_YOUR_WAITLIST", "YOU CAN ONLY ACCESS YOUR OWN WAITLIST ENTRY")
+	ErrWaitlistJoined    = NewError(ErrConflict, "ALREADY_ON_WAITLIST", "YOU ARE ALREADY WAITING FOR THAT TICKET TYPE")
+	ErrNotSoldOut        = NewError(ErrConflict, "TICKET_ON_SALE", "THAT TICKET IS STILL ON SALE, BUY IT INSTEAD")
+	ErrRoomNotFound      = NewError(ErrNotFound, "WAITING_ROOM_NOT_FOUND", "THAT EVENT HAS NO WAITING ROOM")
+	ErrInvalidRoom       = NewError(ErrInvalidInput, "INVALID_WAITING_ROOM", "AN ACTIVE WAITING ROOM NEED AN ADMIT PER MINUTE AND AN ADMIT WINDOW")
+	ErrRoomNotActive     = NewError(ErrConflict, "WAITING_ROOM_NOT_ACTIVE", "THAT EVENT HAS NO ACTIVE WAITING ROOM, BUY THE TICKET DIRECTLY")
+	ErrQueueNotFound     = NewError(ErrNotFound, "QUEUE_TOKEN_NOT_FOUND", "THERE'S NO PLACE IN THE QUEUE WITH THAT TOKEN")
+	ErrQueueRequired     = NewError(ErrForbidden, "QUEUE_TOKEN_REQUIRED", "THE SALE IS BEHIND A WAITING ROOM, JOIN THE QUEUE AND SEND ITS TOKEN")
+	ErrNotYourQueue      = NewError(ErrForbidden, "QUEUE_TOKEN_INVALID", "THAT QUEUE TOKEN IS NOT YOURS OR NOT FOR THIS EVENT")
+	ErrNotAdmitted       = NewError(ErrForbidden, "NOT_ADMITTED_YET", "YOUR TURN IN THE QUEUE HAS NOT COME YET")
+	ErrAdmissionExpired  = NewError(ErrForbidden, "ADMISSION_EXPIRED", "YOUR ADMISSION HAS EXPIRED, JOIN THE QUEUE AGAIN")
+	ErrIssuedNotFound    = NewError(ErrNotFound, "TICKET_NOT_FOUND", "THERE'S NO TICKET WITH THAT CODE")
+	ErrNotYourTicket     = NewError(ErrForbidden, "NOT_YOUR_TICKET", "YOU CAN ONLY ACCESS YOUR OWN TICKET")
+	ErrTicketUsed        = NewError(ErrConflict, "TICKET_ALREADY_USED", "THAT TICKET HAS ALREADY BEEN USED")
+	ErrTicketNotValid    = NewError(ErrConflict, "TICKET_NOT_VALID", "THAT TICKET IS VOID OR HAS BEEN TRANSFERRED")
+	ErrTransferToSelf    = NewError(ErrInvalidInput, "TRANSFER_TO_SELF", "YOU ALREADY HOLD THAT TICKET")
+	ErrTicketNotRefund   = NewError(ErrConflict, "TICKET_NOT_REFUNDABLE", "ONLY A VALID TICKET YOU STILL HOLD CAN BE REFUNDED")
+	ErrInvalidPass       = NewError(ErrInvalidInput, "INVALID_TICKET_QR", "THE TICKET QR IS NOT SIGNED BY US OR HAS BEEN CHANGED")
+	ErrPassExpired       = NewError(ErrConflict, "TICKET_QR_EXPIRED", "THE EVENT OF THAT TICKET HAS ENDED")
+	ErrNotEnoughBalance  = NewError(ErrInsufficientBalance, "INSUFFICIENT_BALANCE", "INSUFFICIENT BALANCE")
+	ErrHoldExpired       = NewError(ErrConflict, "RESERVATION_EXPIRED", "RESERVATION HAS EXPIRED")
+	ErrRefundNotAllowed  = NewError(ErrForbidden, "REFUND_NOT_ALLOWED", "THIS EVENT DOESN'T ALLOW REFUND")
+	ErrRefundTooMuch     = NewError(ErrInvalidInput, "REFUND_QUANTITY_EXCEEDED", "REFUND QUANTITY IS MORE THAN THE BOUGHT TICKET")
+	ErrNothingToRefund   = NewError(ErrConflict, "NOTHING_TO_REFUND", "NOTHING LEFT TO REFUND IN THAT ORDER")
+	ErrUnknownCurrency   = NewError(ErrInvalidInput, "UNKNOWN_CURRENCY", "THAT CURRENCY IS NOT SUPPORTED")
+	ErrInvalidAmount     = NewError(ErrInvalidInput, "INVALID_AMOUNT", "AMOUNT IS NOT A VALID MONEY AMOUNT")
+	ErrCurrencyMismatch  = NewError(ErrInvalidInput, "CURRENCY_MISMATCH", "CAN'T MIX MONEY OF DIFFERENT CURRENCY")
+	ErrAmountOverflow    = NewError(ErrInvalidInput, "AMOUNT_OVERFLOW", "AMOUNT IS TOO LARGE")
+	ErrUnknownPayment    = NewError(ErrInvalidInput, "UNKNOWN_PAYMENT_METHOD", "THAT PAYMENT METHOD IS NOT SUPPORTED")
+	ErrPaymentNotFound   = NewError(ErrNotFound, "PAYMENT_NOT_FOUND", "THERE'S NO PAYMENT WITH THAT REFERENCE")
+	ErrPaymentFailed     = NewError(ErrConflict, "PAYMENT_FAILED", "PAYMENT IS DECLINED BY THE PROVIDER")
+	ErrPaymentTimeout    = NewError(ErrGatewayTimeout, "PAYMENT_TIMEOUT", "PAYMENT PROVIDER DIDN'T ANSWER IN TIME")
+	ErrPaymentSettled    = NewError(ErrConflict, "PAYMENT_SETTLED", "PAYMENT IS ALREADY PAID OR FAILED")
+	ErrPaymentNotPaid    = NewError(ErrConflict, "PAYMENT_NOT_PAID", "PAYMENT IS NOT PAID, NOTHING TO REFUND")
+	ErrInvalidSignature  = NewError(ErrUnauthorized, "INVALID_SIGNATURE", "PAYMENT CALLBACK SIGNATURE IS NOT VALID")
+	ErrNoCallback        = NewError(ErrInvalidInput, "NO_CALLBACK", "THAT PAYMENT METHOD DOESN'T SEND CALLBACK")
+	ErrOrderNotPending   = NewError(ErrConflict, "ORDER_NOT_PENDING", "ORDER IS NOT WAITING FOR PAYMENT")
+
+	ErrInvalidIdempotencyKey = NewError(ErrInvalidInput, "INVALID_IDEMPOTENCY_KEY", "IDEMPOTENCY KEY MUST BE 1 TO 255 CHARACTER")
+	ErrIdempotencyInUse      = NewError(ErrConflict, "IDEMPOTENCY_KEY_IN_USE", "A REQUEST WITH THIS IDEMPOTENCY KEY IS STILL RUNNING")
+	ErrIdempotencyReused     = NewError(ErrConflict, "IDEMPOTENCY_KEY_REUSED", "IDEMPOTENCY KEY IS ALREADY USED FOR A DIFFERENT REQUEST")
+	ErrIdempotencyNotFound   = NewError(ErrNotFound, "IDEMPOTENCY_KEY_NOT_FOUND", "IDEMPOTENCY KEY NOT FOUND")
+)
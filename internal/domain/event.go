@@ -1,10 +1,54 @@
 package domain
 
+import "time"
+
+// time zone of an event that doesn't send one, the old date without zone is read in it too
+const DefaultTimeZone = "Asia/Jakarta"
+
 type Event struct {
-	ID          int      `json:"id,omitempty"`
-	Name        string   `json:"name" validate:"required,noblank,min=2"`
-	Date        string   `json:"date" validate:"required,Datetime"`
-	Description string   `json:"description" validate:"required,noblank"`
-	Location    string   `json:"location" validate:"required,noblank"`
-	Ticket      []Ticket `json:"ticket,omitempty" validate:"required,dive"`
+	ID          int           `json:"id,omitempty"`
+	Name        string        `json:"name" validate:"required,noblank,min=2"`
+	Start       time.Time     `json:"start" validate:"required"` // RFC 3339, like 2027-01-02T19:00:00+07:00
+	End         time.Time     `json:"end" validate:"required,gtfield=Start"`
+	TimeZone    string        `json:"timezone,omitempty" validate:"omitempty,timezone"` // IANA name, like Asia/Jakarta
+	Description string        `json:"description" validate:"required,noblank"`
+	Location    string        `json:"location" validate:"required,noblank"`
+	Ticket      []Ticket      `json:"ticket,omitempty" validate:"dive"` // only read on create, after that use the ticket type endpoint
+	Refund      *RefundPolicy `json:"refund_policy,omitempty"`
+	OrganizerID int           `json:"organizerid,omitempty"`
+}
+
+// when the ticket of an event can still be refunded, no policy mean no refund
+type RefundPolicy struct {
+	Refundable  bool `json:"refundable"`
+	CutoffHours int  `json:"cutoff_hours" validate:"gte=0"`
+}
+
+// show the start, the end and the ticket sale window in the time zone of the event
+func (e *Event) InTimeZone() error {
+	if e.TimeZone == "" {
+		e.TimeZone = DefaultTimeZone
+	}
+	location, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		return ErrInvalidTimeZone
+	}
+	e.Start = e.Start.In(location)
+	e.End = e.End.In(location)
+	for i, ticket := range e.Ticket {
+		if ticket.SaleStart != nil {
+			saleStart := ticket.SaleStart.In(location)
+			e.Ticket[i].SaleStart = &saleStart
+		}
+		if ticket.SaleEnd != nil {
+			saleEnd := ticket.SaleEnd.In(location)
+			e.Ticket[i].SaleEnd = &saleEnd
+		}
+	}
+	return nil
+}
+
+// ticket can't be bought or reserved anymore once the event has started
+func (e Event) HasStarted(now time.Time) bool {
+	return !now.Before(e.Start)
 }
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// reservation of tickets for a user before the payment
+type Hold struct {
+	ID        int          `json:"id,omitempty"`
+	UserID    int          `json:"userid"`
+	EventID   int          `json:"eventid"`
+	Ticket    []TicketLine `json:"ticket"`
+	Status    string       `json:"status"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	OrderID   int          `json:"orderid,omitempty"`
+	// applied when the hold is confirmed
+	PromoCode string `json:"promo_code,omitempty"`
+	Seats     []int  `json:"seats,omitempty"`
+}
+
+// status of a hold
+const (
+	HoldHeld      = "HELD"
+	HoldConfirmed = "CONFIRMED"
+	HoldReleased  = "RELEASED"
+	HoldExpired   = "EXPIRED"
+)
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// the saved response of a request sent with an Idempotency-Key header,
+// a retry with the same key and body get the same response back
+type Idempotency struct {
+	Key          string
+	UserID       int
+	RequestHash  string
+	Status       string
+	ResponseCode int
+	ResponseBody []byte
+	// the order the request saved before it ended with a server error
+	OrderNumber string
+	ExpiresAt   time.Time
+}
+
+// status of an idempotency key
+const (
+	IdempotencyInProgress = "IN_PROGRESS"
+	IdempotencyDone       = "DONE"
+	// the request saved its order and then failed, a retry get the order as it is now
+	IdempotencyOrderSaved = "ORDER_SAVED"
+)
+
+// longest Idempotency-Key header that is accepted
+const MaxIdempotencyKey = 255
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// one ticket given to one person when the order is paid, the code is what the gate check.
+// a seated ticket is for its seat
+type IssuedTicket struct {
+	ID         int        `json:"id,omitempty"`
+	Code       string     `json:"code"`
+	OrderID    int        `json:"orderid"`
+	EventID    int        `json:"eventid"`
+	EventName  string     `json:"event_name"`
+	TicketID   int        `json:"ticket_id"`
+	Type       string     `json:"type"`
+	SeatID     int        `json:"seat_id,omitempty"`
+	Seat       string     `json:"seat,omitempty"` // section, row and number
+	UserID     int        `json:"userid"`
+	HolderName string     `json:"holder_name"`
+	Status     string     `json:"status"`
+	IssuedAt   time.Time  `json:"issued_at"`
+	UsedAt     *time.Time `json:"used_at,omitempty"`
+	// the ticket that replaced this one when it was given to another user
+	TransferredTo int `json:"transferred_to,omitempty"`
+}
+
+// status of an issued ticket
+const (
+	IssuedValid       = "VALID"
+	IssuedUsed        = "USED" // checked in at the gate
+	IssuedVoid        = "VOID" // refunded or cancelled
+	IssuedTransferred = "TRANSFERRED"
+)
+
+// give the ticket to another user, the old code stop working
+type TransferRequest struct {
+	Code string `json:"code" validate:"required,max=64"`
+	To   string `json:"to" validate:"required,noblank,max=64"` // name of the new holder
+}
+
+// what the QR code of a ticket carry. it is signed, so the gate can trust it without asking the server
+type TicketPass struct {
+	Code       string `json:"code"`
+	EventID    int    `json:"eventid"`
+	Type       string `json:"type"`
+	Seat       string `json:"seat,omitempty"`
+	HolderName string `json:"holder"`
+	ValidUntil int64  `json:"exp"` // unix second of the end of the event
+}
+
+// the key a gate need to check a ticket QR offline
+type PassKey struct {
+	Algorithm string `json:"alg"`
+	PublicKey string `json:"public_key"` // base64url without padding
+}
@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// money in the smallest unit of the currency, IDR 250.00 is {Amount: 25000, Currency: "IDR"}.
+// the zero value has no currency and take the currency of the money it is added to
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// currency used when the json only send a number
+const DefaultCurrency = "IDR"
+
+// digit after the decimal point of every supported ISO 4217 currency
+var currencyExponent = map[string]int{
+	"IDR": 2,
+	"USD": 2,
+	"SGD": 2,
+	"MYR": 2,
+	"EUR": 2,
+	"JPY": 0,
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// parse a decimal like "250.50" without going through float
+func ParseMoney(decimal string, currency string) (Money, error) {
+	exponent, known := currencyExponent[currency]
+	if !known {
+		return Money{}, ErrUnknownCurrency
+	}
+
+	negative := strings.HasPrefix(decimal, "-")
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(decimal, "-"), ".")
+	if whole == "" || len(fraction) > exponent {
+		return Money{}, ErrInvalidAmount
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil || amount < 0 {
+		return Money{}, ErrInvalidAmount
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency == "" && m.Amount == 0 {
+		return other, nil
+	}
+	if other.Currency == "" && other.Amount == 0 {
+		return m, nil
+	}
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	// a sum that wrap around has the wrong sign
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	// the smallest int64 has no positive
+	if other.Amount == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return m.Add(other.Neg())
+}
+
+// price of quantity item
+func (m Money) Mul(quantity int) (Money, error) {
+	amount := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(int64(quantity)))
+	if !amount.IsInt64() {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: amount.Int64(), Currency: m.Currency}, nil
+}
+
+// part of the money, numerator/denominator of it rounded down to the minor unit.
+// the product can pass int64 so it is counted with big.Int
+func (m Money) Scale(numerator int64, denominator int64) Money {
+	amount := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(numerator))
+	amount.Quo(amount, big.NewInt(denominator))
+	return Money{Amount: amount.Int64(), Currency: m.Currency}
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// zero is the same in every currency
+func (m Money) Equal(other Money) bool {
+	return m.Amount == other.Amount && (m.Currency == other.Currency || m.Amount == 0)
+}
+
+// the amount as decimal, like "250.00"
+func (m Money) Decimal() string {
+	exponent := currencyExponent[m.currency()]
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) String() string {
+	return m.currency() + " " + m.Decimal()
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+type moneyJSON struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// money is written as {"amount": "250.00", "currency": "IDR"} so no precision is lost
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.currency()})
+}
+
+// money can be read from the object form, or from a plain number or string in the default currency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	value := moneyJSON{Currency: DefaultCurrency}
+	if len(data) > 0 && data[0] == '{' {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var raw struct {
+			Amount   any    `json:"amount"`
+			Currency string `json:"currency"`
+		}
+		if err := decoder.Decode(&raw); err != nil {
+			return err
+		}
+		switch amount := raw.Amount.(type) {
+		case json.Number:
+			value.Amount = amount
+		case string:
+			value.Amount = json.Number(amount)
+		default:
+			return ErrInvalidAmount
+		}
+		if raw.Currency != "" {
+			value.Currency = strings.ToUpper(raw.Currency)
+		}
+	} else {
+		var amount string
+		if err := json.Unmarshal(data, &amount); err != nil {
+			amount = string(data)
+		}
+		value.Amount = json.Number(amount)
+	}
+
+	money, err := ParseMoney(value.Amount.String(), value.Currency)
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
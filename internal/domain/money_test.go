@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+// a decimal is read exactly in the minor unit of its currency
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		decimal  string
+		currency string
+		amount   int64
+		err      error
+	}{
+		{decimal: "250.50", currency: "IDR", amount: 25050},
+		{decimal: "250.5", currency: "IDR", amount: 25050},
+		{decimal: "250", currency: "IDR", amount: 25000},
+		{decimal: "0.01", currency: "USD", amount: 1},
+		{decimal: "-77", currency: "IDR", amount: -7700},
+		{decimal: "1500", currency: "JPY", amount: 1500},
+		{decimal: "92233720368547758.07", currency: "IDR", amount: math.MaxInt64},
+		{decimal: "92233720368547758.08", currency: "IDR", err: ErrInvalidAmount},
+		{decimal: "1.5", currency: "JPY", err: ErrInvalidAmount},
+		{decimal: "1.234", currency: "IDR", err: ErrInvalidAmount},
+		{decimal: ".5", currency: "IDR", err: ErrInvalidAmount},
+		{decimal: "--5", currency: "IDR", err: ErrInvalidAmount},
+		{decimal: "1e3", currency: "IDR", err: ErrInvalidAmount},
+		{decimal: "", currency: "IDR", err: ErrInvalidAmount},
+		{decimal: "10", currency: "XXX", err: ErrUnknownCurrency},
+	}
+	for _, tc := range cases {
+		t.Run(tc.decimal+" "+tc.currency, func(t *testing.T) {
+			money, err := ParseMoney(tc.decimal, tc.currency)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got %v, want %v", err, tc.err)
+			}
+			if tc.err == nil && (money.Amount != tc.amount || money.Currency != tc.currency) {
+				t.Errorf("got %d %s, want %d %s", money.Amount, money.Currency, tc.amount, tc.currency)
+			}
+		})
+	}
+}
+
+// money is written as a decimal string and read back the same, a plain number is the default currency
+func TestMoneyJSON(t *testing.T) {
+	for _, money := range []Money{NewMoney(25050, "IDR"), NewMoney(-7, "USD"), NewMoney(1500, "JPY"), NewMoney(math.MaxInt64, "IDR"), NewMoney(5, "")} {
+		data, err := json.Marshal(money)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var read Money
+		if err := json.Unmarshal(data, &read); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		if read.Amount != money.Amount || read.Currency != money.currency() {
+			t.Errorf("%s read back as %+v, want %+v", data, read, money)
+		}
+	}
+
+	cases := []struct {
+		json  string
+		money Money
+		err   error
+	}{
+		{json: `{"amount": "250.00", "currency": "IDR"}`, money: NewMoney(25000, "IDR")},
+		{json: `{"amount": 12.5, "currency": "usd"}`, money: NewMoney(1250, "USD")},
+		{json: `{"amount": "7"}`, money: NewMoney(700, DefaultCurrency)},
+		{json: `250.5`, money: NewMoney(25050, DefaultCurrency)},
+		{json: `"250.5"`, money: NewMoney(25050, DefaultCurrency)},
+		{json: `{"amount": true}`, err: ErrInvalidAmount},
+		{json: `{"amount": "1.5", "currency": "JPY"}`, err: ErrInvalidAmount},
+		{json: `{"amount": "1", "currency": "XXX"}`, err: ErrUnknownCurrency},
+	}
+	for _, tc := range cases {
+		t.Run(tc.json, func(t *testing.T) {
+			var money Money
+			err := json.Unmarshal([]byte(tc.json), &money)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got %v, want %v", err, tc.err)
+			}
+			if tc.err == nil && money != tc.money {
+				t.Errorf("got %+v, want %+v", money, tc.money)
+			}
+		})
+	}
+}
+
+// the arithmetic refuse to mix currency and to wrap around int64
+func TestMoneyArithmetic(t *testing.T) {
+	idr := func(amount int64) Money { return NewMoney(amount, "IDR") }
+	cases := []struct {
+		name  string
+		do    func() (Money, error)
+		money Money
+		err   error
+	}{
+		{name: "add", do: func() (Money, error) { return idr(100).Add(idr(50)) }, money: idr(150)},
+		{name: "add to zero value", do: func() (Money, error) { return Money{}.Add(NewMoney(5, "USD")) }, money: NewMoney(5, "USD")},
+		{name: "add zero value", do: func() (Money, error) { return idr(5).Add(Money{}) }, money: idr(5)},
+		{name: "add other currency", do: func() (Money, error) { return idr(100).Add(NewMoney(50, "USD")) }, err: ErrCurrencyMismatch},
+		{name: "add up to the max", do: func() (Money, error) { return idr(math.MaxInt64 - 1).Add(idr(1)) }, money: idr(math.MaxInt64)},
+		{name: "add past the max", do: func() (Money, error) { return idr(math.MaxInt64).Add(idr(1)) }, err: ErrAmountOverflow},
+		{name: "add past the min", do: func() (Money, error) { return idr(math.MinInt64).Add(idr(-1)) }, err: ErrAmountOverflow},
+		{name: "sub", do: func() (Money, error) { return idr(100).Sub(idr(150)) }, money: idr(-50)},
+		{name: "sub other currency", do: func() (Money, error) { return idr(100).Sub(NewMoney(50, "JPY")) }, err: ErrCurrencyMismatch},
+		{name: "sub past the min", do: func() (Money, error) { return idr(math.MinInt64).Sub(idr(1)) }, err: ErrAmountOverflow},
+		{name: "sub past the max", do: func() (Money, error) { return idr(math.MaxInt64).Sub(idr(-1)) }, err: ErrAmountOverflow},
+		{name: "sub the min", do: func() (Money, error) { return idr(0).Sub(idr(math.MinInt64)) }, err: ErrAmountOverflow},
+		{name: "mul", do: func() (Money, error) { return idr(25000).Mul(3) }, money: idr(75000)},
+		{name: "mul by zero", do: func() (Money, error) { return idr(math.MaxInt64).Mul(0) }, money: idr(0)},
+		{name: "mul negative", do: func() (Money, error) { return idr(-77).Mul(2) }, money: idr(-154)},
+		{name: "mul past the max", do: func() (Money, error) { return idr(math.MaxInt64/2 + 1).Mul(2) }, err: ErrAmountOverflow},
+		{name: "mul past the min", do: func() (Money, error) { return idr(math.MinInt64).Mul(-1) }, err: ErrAmountOverflow},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			money, err := tc.do()
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got %v, want %v", err, tc.err)
+			}
+			if tc.err == nil && money != tc.money {
+				t.Errorf("got %+v, want %+v", money, tc.money)
+			}
+		})
+	}
+}
+
+// a part of the money is cut toward zero, even when the product pass int64
+func TestMoneyScale(t *testing.T) {
+	cases := []struct {
+		name                   string
+		amount                 int64
+		numerator, denominator int64
+		want                   int64
+	}{
+		{name: "exact", amount: 10000, numerator: 1100, denominator: 10000, want: 1100},
+		{name: "cut the fraction", amount: 999, numerator: 1, denominator: 10, want: 99},
+		{name: "cut below one", amount: 9, numerator: 1, denominator: 10, want: 0},
+		{name: "cut toward zero", amount: -999, numerator: 1, denominator: 10, want: -99},
+		{name: "product past int64", amount: math.MaxInt64, numerator: 10000, denominator: 10000, want: math.MaxInt64},
+		{name: "whole", amount: 25050, numerator: 1, denominator: 1, want: 25050},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			money := NewMoney(tc.amount, "IDR").Scale(tc.numerator, tc.denominator)
+			if money.Amount != tc.want || money.Currency != "IDR" {
+				t.Errorf("got %+v, want %d IDR", money, tc.want)
+			}
+		})
+	}
+}
+
+// the decimal has every digit of the minor unit
+func TestMoneyDecimal(t *testing.T) {
+	cases := map[Money]string{
+		NewMoney(25050, "IDR"): "250.50",
+		NewMoney(5, "USD"):     "0.05",
+		NewMoney(-5, "USD"):    "-0.05",
+		NewMoney(0, ""):        "0.00",
+		NewMoney(1500, "JPY"):  "1500",
+	}
+	for money, want := range cases {
+		if got := money.Decimal(); got != want {
+			t.Errorf("%+v is %q, want %q", money, got, want)
+		}
+	}
+}
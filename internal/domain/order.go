@@ -1,12 +1,87 @@
 package domain
 
+import (
+	"slices"
+	"time"
+)
+
 type Order struct {
-	ID            int      `json:"id,omitempty"`
-	OrderDate     string   `json:"order_date" validate:"Datetime"`
-	Status        string   `json:"status"`
-	PaymentMethod string   `json:"payment_method,omitempty" validate:"noblank"`
-	User          User     `json:"user,omitempty" validate:"dive,min=2"`
-	Event         Event    `json:"event,omitempty" validate:"dive"`
-	EventTicket   []Ticket `json:"event_ticket,omitempty" validate:"dive"`
-	TotalPrice    float64  `json:"total_price,omitempty" validate:"noblank"`
+	ID            int                 `json:"id,omitempty"`
+	Number        string              `json:"number,omitempty"` // public order number, it can't be guessed from another order
+	OrderDate     string              `json:"order_date" validate:"Datetime"`
+	Status        string              `json:"status"`
+	FailureReason string              `json:"failure_reason,omitempty"`
+	PaymentMethod string              `json:"payment_method,omitempty" validate:"noblank"`
+	PaymentRef    string              `json:"payment_reference,omitempty"`
+	PaymentDue    *time.Time          `json:"payment_due,omitempty"` // order still unpaid after this is expired
+	Payment       *Payment            `json:"payment,omitempty"`     // only in the response of a new order
+	User          User                `json:"user,omitempty" validate:"dive,min=2"`
+	Event         Event               `json:"event,omitempty" validate:"dive"`
+	EventTicket   []Ticket            `json:"event_ticket,omitempty" validate:"dive"`
+	Seats         []Seat              `json:"seats,omitempty"`
+	Subtotal      Money               `json:"subtotal,omitempty"` // price of the ticket before the discount
+	Discount      Money               `json:"discount,omitempty"`
+	PromoCode     string              `json:"promo_code,omitempty"`
+	Fee           Money               `json:"fee,omitempty"` // platform fee of the ticket and the order
+	Tax           Money               `json:"tax,omitempty"`
+	TaxRate       int                 `json:"tax_rate_bp,omitempty"` // in basis point
+	TaxIncluded   bool                `json:"tax_included,omitempty"`
+	TotalPrice    Money               `json:"total_price,omitempty" validate:"noblank"` // what the buyer pay
+	Refunded      []Ticket            `json:"refunded_ticket,omitempty"`
+	RefundAmount  Money               `json:"refund_amount,omitempty"`
+	History       []OrderStatusChange `json:"history,omitempty"`
+}
+
+// one step of the order in the state machine, the first step come from an empty status
+type OrderStatusChange struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// status of an order
+const (
+	OrderPending         = "PENDING"          // saved, the payment is not started yet
+	OrderAwaitingPayment = "AWAITING_PAYMENT" // waiting for the provider callback
+	OrderPaid            = "PAID"
+	OrderFulfilled       = "FULFILLED" // the ticket is given to the buyer
+	OrderCancelled       = "CANCELLED"
+	OrderRefunded        = "REFUNDED" // part or all of the ticket is refunded
+	OrderExpired         = "EXPIRED"  // not paid before the payment due
+	OrderFailed          = "FAILED"
+)
+
+// every status an order can move to from its current status
+var orderTransitions = map[string][]string{
+	"":                   {OrderPending, OrderFailed},
+	OrderPending:         {OrderAwaitingPayment, OrderPaid, OrderFailed},
+	OrderAwaitingPayment: {OrderPaid, OrderFailed, OrderExpired, OrderCancelled},
+	OrderPaid:            {OrderFulfilled, OrderRefunded, OrderCancelled},
+	OrderFulfilled:       {OrderRefunded, OrderCancelled},
+	OrderRefunded:        {OrderRefunded, OrderCancelled},
+}
+
+func CanTransition(from string, to string) bool {
+	return slices.Contains(orderTransitions[from], to)
+}
+
+// the order got past the purchase and was saved, even if its payment failed after it
+func (o Order) WasPlaced() bool {
+	for _, change := range o.History {
+		if change.To == OrderPending {
+			return true
+		}
+	}
+	return false
+}
+
+// the buyer money reached us at some point, even if it is refunded later
+func (o Order) WasPaid() bool {
+	for _, change := range o.History {
+		if change.To == OrderPaid {
+			return true
+		}
+	}
+	return false
 }
@@ -1,7 +1,14 @@
 package domain
 
 type OrderRequest struct {
-	UserID  int      `json:"userid" validate:"required,numeric"`
-	EventID int      `json:"eventid" validate:"required,numeric"`
-	Ticket  []Ticket `json:"ticket" validate:"required,min=1,dive"`
+	UserID  int          `json:"userid" validate:"required,numeric"`
+	EventID int          `json:"eventid" validate:"required,numeric"`
+	Ticket  []TicketLine `json:"ticket" validate:"required_without=Seats,dive"`
+	// picked seat, every seat is one ticket of its ticket type
+	Seats []int `json:"seats,omitempty" validate:"omitempty,max=50,unique,dive,gt=0"`
+	// WALLET when empty
+	PaymentMethod string `json:"payment_method" validate:"omitempty,oneof=WALLET QRIS BANK_TRANSFER CARD"`
+	PromoCode     string `json:"promo_code,omitempty" validate:"max=64"`
+	// admission token of the waiting room, needed while the event has an active one
+	QueueToken string `json:"queue_token,omitempty" validate:"max=64"`
 }
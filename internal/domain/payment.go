@@ -0,0 +1,65 @@
+package domain
+
+// how an order is paid, wallet is paid at once and the other wait for the provider callback
+const (
+	PaymentWallet       = "WALLET"
+	PaymentQRIS         = "QRIS"
+	PaymentBankTransfer = "BANK_TRANSFER"
+	PaymentCard         = "CARD"
+)
+
+// status of a payment at the provider
+const (
+	PaymentPending = "PENDING"
+	PaymentPaid    = "PAID"
+	PaymentFailed  = "FAILED"
+)
+
+// payment of one order at a provider, instruction is what the buyer need to pay,
+// like the QRIS string, the virtual account number or the card page
+type Payment struct {
+	Method      string `json:"method"`
+	Reference   string `json:"reference"`
+	OrderID     int    `json:"orderid"`
+	Amount      Money  `json:"amount"`
+	Status      string `json:"status"`
+	Instruction string `json:"instruction,omitempty"`
+}
+
+// a payment as the simulated gateway keep it. refunded is what is given back so far
+// and silent mean the gateway never answer about it
+type SimulatedPayment struct {
+	Payment
+	Refunded Money
+	Silent   bool
+}
+
+// notification from the provider that a payment is paid or failed, signed by the provider
+type PaymentCallback struct {
+	Method    string `json:"method" validate:"required"`
+	Reference string `json:"reference" validate:"required"`
+	OrderID   int    `json:"orderid" validate:"required"`
+	Status    string `json:"status" validate:"required,oneof=PAID FAILED"`
+	Signature string `json:"signature" validate:"required"`
+}
+
+// what the payment simulator do with the next payment of a method
+const (
+	SimulateSuccess = "success" // callback PAID after the delay
+	SimulateFail    = "fail"    // callback FAILED after the delay
+	SimulateTimeout = "timeout" // the provider never answer, the order expire after its payment window
+	SimulateManual  = "manual"  // no callback until it is settled by hand
+)
+
+// script of the payment simulator for one method
+type SimulatorScript struct {
+	Method  string `json:"method" validate:"required,oneof=QRIS BANK_TRANSFER CARD"`
+	Outcome string `json:"outcome" validate:"required,oneof=success fail timeout manual"`
+	DelayMs int    `json:"delay_ms" validate:"gte=0"`
+}
+
+// settle a simulated payment by hand
+type SimulatorSettlement struct {
+	Reference string `json:"reference" validate:"required"`
+	Status    string `json:"status" validate:"required,oneof=PAID FAILED"`
+}
@@ -0,0 +1,85 @@
+package domain
+
+import "math/big"
+
+// how the tax is rounded to the minor unit
+const (
+	RoundHalfUp = "HALF_UP" // the default
+	RoundUp     = "UP"
+	RoundDown   = "DOWN"
+)
+
+// platform fee and tax charged on every order in one currency, the fee and the tax rate
+// that was used is kept on the order so a later change never touch a paid order
+type Pricing struct {
+	Currency  string `json:"currency" validate:"required,len=3"`
+	TicketFee Money  `json:"ticket_fee" validate:"gte=0"` // for every ticket
+	OrderFee  Money  `json:"order_fee" validate:"gte=0"`  // once for every order
+	// in basis point, 1100 is 11% PPN
+	TaxRate int `json:"tax_rate_bp" validate:"gte=0,lte=10000"`
+	// the ticket price and the fee already include the tax, so the tax is only shown and not added
+	TaxIncluded bool `json:"tax_included"`
+	// the fee is taxed like the ticket, otherwise only the ticket after the discount is taxed
+	TaxOnFee bool   `json:"tax_on_fee"`
+	Rounding string `json:"rounding,omitempty" validate:"omitempty,oneof=HALF_UP UP DOWN"`
+	// the tax is rounded to a multiple of this many minor unit, 0 and 1 is the minor unit itself
+	RoundTo int64 `json:"round_to,omitempty" validate:"gte=0"`
+}
+
+// the fee and the money of the pricing are in its currency
+func (p Pricing) Valid() bool {
+	if _, known := currencyExponent[p.Currency]; !known {
+		return false
+	}
+	for _, fee := range []Money{p.TicketFee, p.OrderFee} {
+		if !fee.IsZero() && fee.Currency != p.Currency {
+			return false
+		}
+	}
+	return true
+}
+
+// the fee and the tax of an order with quantity ticket that cost net after the discount
+func (p Pricing) Charge(quantity int, net Money) (Money, Money, error) {
+	// the fee is in the currency of the pricing, a zero fee can be in any currency
+	fee := Money{Amount: p.TicketFee.Amount*int64(quantity) + p.OrderFee.Amount, Currency: p.Currency}
+
+	taxable := net
+	if p.TaxOnFee {
+		var err error
+		if taxable, err = taxable.Add(fee); err != nil {
+			return Money{}, Money{}, err
+		}
+	}
+	if taxable.currency() != p.Currency {
+		return Money{}, Money{}, ErrCurrencyMismatch
+	}
+	denominator := int64(10000)
+	if p.TaxIncluded {
+		// the tax part of a price that include it is rate/(1+rate) of it
+		denominator += int64(p.TaxRate)
+	}
+	return fee, p.round(taxable, int64(p.TaxRate), denominator), nil
+}
+
+// numerator/denominator of the money, rounded by the rounding rule of the pricing
+func (p Pricing) round(m Money, numerator int64, denominator int64) Money {
+	step := p.RoundTo
+	if step <= 0 {
+		step = 1
+	}
+	divisor := new(big.Int).Mul(big.NewInt(denominator), big.NewInt(step))
+	quotient, remainder := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(numerator)), divisor, new(big.Int))
+	switch p.Rounding {
+	case RoundDown:
+	case RoundUp:
+		if remainder.Sign() > 0 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	default:
+		if remainder.Lsh(remainder, 1).Cmp(divisor) >= 0 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return Money{Amount: quotient.Int64() * step, Currency: p.Currency}
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// kind of discount a promo code give
+const (
+	PromoPercent = "PERCENT" // percent of the eligible ticket price
+	PromoFixed   = "FIXED"   // fixed amount off, never more than the eligible ticket price
+)
+
+// a promo code, the code is saved in upper case so it can be typed in any case
+type Promo struct {
+	ID      int    `json:"id,omitempty"`
+	Code    string `json:"code" validate:"required,noblank,max=64"`
+	Kind    string `json:"kind" validate:"required,oneof=PERCENT FIXED"`
+	Percent int    `json:"percent,omitempty" validate:"gte=0,lte=100"`
+	Amount  Money  `json:"amount,omitempty" validate:"gte=0"`
+	// scope, 0 event mean every event and no ticket type mean every ticket type of the event
+	EventID   int   `json:"eventid,omitempty"`
+	TicketIDs []int `json:"ticket_ids,omitempty"`
+	// 0 mean no limit
+	MaxUses    int        `json:"max_uses,omitempty" validate:"gte=0"`
+	MaxPerUser int        `json:"max_per_user,omitempty" validate:"gte=0"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	Used       int        `json:"used"` // redemption of order that is not failed, expired or cancelled
+	CreatedBy  int        `json:"created_by,omitempty"`
+}
+
+// one use of a promo code by an order, it is released when the order never get paid or is cancelled
+type PromoRedemption struct {
+	PromoID  int
+	UserID   int
+	OrderID  int
+	Discount Money
+	Released bool
+}
+
+// the promo has the value its kind need and a window that end after it start
+func (p Promo) Valid() bool {
+	switch {
+	case p.Kind == PromoPercent && p.Percent == 0:
+		return false
+	case p.Kind == PromoFixed && p.Amount.IsZero():
+		return false
+	case len(p.TicketIDs) > 0 && p.EventID == 0:
+		return false
+	}
+	return p.ValidFrom == nil || p.ValidUntil == nil || p.ValidUntil.After(*p.ValidFrom)
+}
+
+// the promo can be used at that time
+func (p Promo) CheckActive(now time.Time) error {
+	if p.ValidFrom != nil && now.Before(*p.ValidFrom) {
+		return ErrPromoNotActive
+	}
+	if p.ValidUntil != nil && !now.Before(*p.ValidUntil) {
+		return ErrPromoNotActive
+	}
+	if p.MaxUses > 0 && p.Used >= p.MaxUses {
+		return ErrPromoUsedUp
+	}
+	return nil
+}
+
+// the discount of the promo on the bought ticket of an event, only the ticket in its scope count
+func (p Promo) Discount(eventID int, tickets []Ticket) (Money, error) {
+	if p.EventID != 0 && p.EventID != eventID {
+		return Money{}, ErrPromoNotForOrder
+	}
+	var eligible Money
+	for _, ticket := range tickets {
+		if len(p.TicketIDs) > 0 && !slices.Contains(p.TicketIDs, ticket.ID) {
+			continue
+		}
+		price, err := ticket.Price.Mul(ticket.Quantity)
+		if err != nil {
+			return Money{}, err
+		}
+		if eligible, err = eligible.Add(price); err != nil {
+			return Money{}, err
+		}
+	}
+	if eligible.IsZero() {
+		return Money{}, ErrPromoNotForOrder
+	}
+
+	if p.Kind == PromoPercent {
+		return eligible.Scale(int64(p.Percent), 100), nil
+	}
+	if p.Amount.currency() != eligible.currency() {
+		return Money{}, ErrCurrencyMismatch
+	}
+	if p.Amount.Amount > eligible.Amount {
+		return eligible, nil
+	}
+	return Money{Amount: p.Amount.Amount, Currency: eligible.Currency}, nil
+}
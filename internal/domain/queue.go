@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// a waiting room in front of the sale of a high demand event, while it is active a buyer
+// need an admission from the queue before buying
+type WaitingRoom struct {
+	EventID int  `json:"eventid"`
+	Active  bool `json:"active"`
+	// how many user in the line are let in every minute
+	AdmitPerMinute int `json:"admit_per_minute"`
+	// how long an admitted user can buy before the admission expire
+	AdmitWindowSeconds int `json:"admit_window_seconds"`
+	// the admission rate is counted from this time, it move forward every time users are let in
+	LastAdmitAt time.Time `json:"-"`
+	// counted when the room is read
+	Waiting  int `json:"waiting"`
+	Admitted int `json:"admitted"`
+}
+
+type WaitingRoomRequest struct {
+	EventID            int  `json:"eventid" validate:"required,gt=0"`
+	Active             bool `json:"active"`
+	AdmitPerMinute     int  `json:"admit_per_minute" validate:"gte=0,lte=100000"`
+	AdmitWindowSeconds int  `json:"admit_window_seconds" validate:"gte=0,lte=86400"`
+}
+
+// a place of a user in the queue of an event
+type QueueEntry struct {
+	ID      int `json:"id,omitempty"`
+	EventID int `json:"eventid"`
+	UserID  int `json:"userid"`
+	// the secret of the place, it is sent as queue_token to /buyTicket and /reserveTicket once admitted
+	Token    string    `json:"token"`
+	Status   string    `json:"status"`
+	JoinedAt time.Time `json:"joined_at"`
+	// place in the line while waiting, 1 is the next to be let in
+	Position   int        `json:"position,omitempty"`
+	AdmittedAt *time.Time `json:"admitted_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// status of a queue entry
+const (
+	QueueWaiting  = "WAITING"
+	QueueAdmitted = "ADMITTED"
+	QueueExpired  = "EXPIRED" // the admission window passed, the user join again at the back
+)
+
+// the admission is still good at that time
+func (entry QueueEntry) IsAdmitted(now time.Time) bool {
+	return entry.Status == QueueAdmitted && (entry.ExpiresAt == nil || now.Before(*entry.ExpiresAt))
+}
@@ -0,0 +1,7 @@
+package domain
+
+type RefundRequest struct {
+	OrderID int          `json:"orderid" validate:"required,numeric"`
+	Ticket  []TicketLine `json:"ticket" validate:"required_without=Seats,dive"`
+	Seats   []int        `json:"seats,omitempty" validate:"omitempty,unique,dive,gt=0"` // seat of a seated ticket type
+}
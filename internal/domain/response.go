@@ -3,6 +3,6 @@ package domain
 type Response struct {
 	Message string `json:"message"`
 	Status  any    `json:"status,omitempty"`
+	Code    string `json:"code,omitempty"`
 	Data    any    `json:"data,omitempty"`
 }
-
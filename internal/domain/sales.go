@@ -0,0 +1,18 @@
+package domain
+
+// sales of every event owned by an organizer
+type Sales struct {
+	OrganizerID int          `json:"organizerid"`
+	TicketSold  int          `json:"ticket_sold"`
+	Revenue     Money        `json:"revenue"`
+	Events      []EventSales `json:"events"`
+}
+
+// sales of one event, refunded ticket and money are not counted
+type EventSales struct {
+	EventID    int     `json:"eventid"`
+	Name       string  `json:"name"`
+	TicketSold int     `json:"ticket_sold"`
+	Revenue    Money   `json:"revenue"`
+	Orders     []Order `json:"orders"`
+}
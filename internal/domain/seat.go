@@ -0,0 +1,54 @@
+package domain
+
+// status of a seat
+const (
+	SeatAvailable = "AVAILABLE"
+	SeatHeld      = "HELD" // by a reservation
+	SeatSold      = "SOLD"
+	SeatReleased  = "RELEASED" // only on the seat of an order, given back by a refund, a cancel or an unpaid order
+)
+
+// one seat of an event, it is sold as one ticket of its ticket type. the ID is unique over every event
+type Seat struct {
+	ID       int    `json:"id"`
+	EventID  int    `json:"eventid,omitempty"`
+	TicketID int    `json:"ticket_id,omitempty"`
+	Section  string `json:"section,omitempty"`
+	Row      string `json:"row,omitempty"`
+	Number   int    `json:"number"`
+	Status   string `json:"status"`
+	HoldID   int    `json:"-"`
+	OrderID  int    `json:"-"`
+}
+
+// seats to add to an event, every seat of a section is sold as its ticket type
+type SeatMapRequest struct {
+	EventID  int                  `json:"eventid" validate:"required,gt=0"`
+	Sections []SeatSectionRequest `json:"sections" validate:"required,min=1,dive"`
+}
+type SeatSectionRequest struct {
+	Name     string           `json:"name" validate:"required,noblank,max=32"`
+	TicketID int              `json:"ticket_id" validate:"required,gt=0"`
+	Rows     []SeatRowRequest `json:"rows" validate:"required,min=1,dive"`
+}
+type SeatRowRequest struct {
+	Name  string `json:"name" validate:"required,noblank,max=16"`
+	Seats int    `json:"seats" validate:"required,gt=0,lte=500"` // numbered from 1
+}
+
+// the live status of every seat of an event, by section and row
+type SeatMap struct {
+	EventID   int           `json:"eventid"`
+	Available int           `json:"available"`
+	Sections  []SeatSection `json:"sections"`
+}
+type SeatSection struct {
+	Name      string    `json:"name"`
+	TicketID  int       `json:"ticket_id"`
+	Available int       `json:"available"`
+	Rows      []SeatRow `json:"rows"`
+}
+type SeatRow struct {
+	Name  string `json:"name"`
+	Seats []Seat `json:"seats"`
+}
@@ -1,8 +1,70 @@
 package domain
 
+import "time"
+
+// sale status of a ticket type, it move by itself as the time pass
+const (
+	SaleUpcoming = "UPCOMING"
+	SaleOnSale   = "ON_SALE"
+	SaleEnded    = "ENDED"
+	SaleSoldOut  = "SOLD_OUT"
+	SaleClosed   = "CLOSED"
+)
+
+// a ticket type sold by one event, the ID is unique over every event
 type Ticket struct {
-	ID       int     `json:"id,omitempty" validate:"required,numeric"`
-	Type     string  `json:"type" validate:"noblank"`
-	Quantity int     `json:"quantity" validate:"required,gt=0,numeric"`
-	Price    float64 `json:"price,omitempty" validate:"omitempty,gt=0,numeric"`
+	ID       int    `json:"id,omitempty"`
+	EventID  int    `json:"eventid,omitempty"`
+	Type     string `json:"type" validate:"noblank"`
+	Quantity int    `json:"quantity" validate:"required,gt=0,numeric"`
+	Price    Money  `json:"price,omitempty" validate:"omitempty,gt=0"`
+	Held     int    `json:"held,omitempty"`
+	// a closed ticket type is still shown but can't be bought or reserved anymore
+	Closed bool `json:"closed,omitempty"`
+	// the sale window, like an early bird that end or a general sale that open later. nil mean no limit
+	SaleStart *time.Time `json:"sale_start,omitempty"`
+	SaleEnd   *time.Time `json:"sale_end,omitempty"`
+	// cap against scalper, 0 mean no limit. the user cap count every order of the user for the event
+	MaxPerOrder int `json:"max_per_order,omitempty" validate:"gte=0"`
+	MaxPerUser  int `json:"max_per_user,omitempty" validate:"gte=0"`
+	// a seated ticket type is only sold by picking its seats, its quantity follow the seat map
+	Seated bool `json:"seated,omitempty"`
+}
+
+// one line of an order, a refund or a reservation, it point to the ticket type by its ID only
+type TicketLine struct {
+	ID       int `json:"id" validate:"required,numeric"`
+	Quantity int `json:"quantity" validate:"required,gt=0,numeric"`
+}
+
+// the sale window must end after it start
+func (t Ticket) ValidSaleWindow() bool {
+	return t.SaleStart == nil || t.SaleEnd == nil || t.SaleEnd.After(*t.SaleStart)
+}
+
+// the ticket type can be bought or reserved at that time, the stock is checked on its own
+func (t Ticket) CheckSale(now time.Time) error {
+	switch {
+	case t.Closed:
+		return ErrTicketClosed
+	case t.SaleStart != nil && now.Before(*t.SaleStart):
+		return ErrSaleNotStarted
+	case t.SaleEnd != nil && !now.Before(*t.SaleEnd):
+		return ErrSaleEnded
+	}
+	return nil
+}
+
+func (t Ticket) SaleStatus(now time.Time) string {
+	switch err := t.CheckSale(now); {
+	case err == ErrTicketClosed:
+		return SaleClosed
+	case err == ErrSaleNotStarted:
+		return SaleUpcoming
+	case err == ErrSaleEnded:
+		return SaleEnded
+	case t.Quantity == 0:
+		return SaleSoldOut
+	}
+	return SaleOnSale
 }
@@ -1,7 +1,23 @@
 package domain
 
 type User struct {
-	ID      int     `json:"id,omitempty"`
-	Name    string  `json:"name" validate:"noblank,min=2"`
-	Balance float64 `json:"balance,omitempty" validate:"gt=0,numeric"`
+	ID           int    `json:"id,omitempty"`
+	Name         string `json:"name" validate:"noblank,min=2"`
+	Password     string `json:"password,omitempty" validate:"omitempty,min=8"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role,omitempty"`
+	Balance      Money  `json:"balance"` // sum of the wallet ledger, can't be set
+}
+
+// role of a user, every registered user start as customer
+const (
+	RoleAdmin     = "admin"
+	RoleOrganizer = "organizer"
+	RoleCustomer  = "customer"
+)
+
+// request from admin to change the role of a user
+type RoleRequest struct {
+	UserID int    `json:"userid" validate:"required"`
+	Role   string `json:"role" validate:"required,oneof=admin organizer customer"`
 }
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// a user waiting for a sold out ticket type, served first come first served
+type WaitlistEntry struct {
+	ID       int       `json:"id,omitempty"`
+	UserID   int       `json:"userid"`
+	EventID  int       `json:"eventid"`
+	TicketID int       `json:"ticket_id"`
+	Quantity int       `json:"quantity"`
+	Status   string    `json:"status"`
+	JoinedAt time.Time `json:"joined_at"`
+	// place in the line of the ticket type while waiting, 1 is the next to get an offer
+	Position int `json:"position,omitempty"`
+	// the reservation offered when the stock come back, it is paid with /reservationConfirm before it expire
+	HoldID         int        `json:"holdid,omitempty"`
+	OfferExpiresAt *time.Time `json:"offer_expires_at,omitempty"`
+}
+
+// status of a waitlist entry
+const (
+	WaitlistWaiting = "WAITING"
+	WaitlistOffered = "OFFERED"
+	WaitlistClaimed = "CLAIMED" // the offer is paid
+	WaitlistExpired = "EXPIRED" // the offer is released or not paid in time, or the event started
+	WaitlistLeft    = "LEFT"
+)
+
+type WaitlistRequest struct {
+	UserID   int `json:"userid" validate:"required,numeric"`
+	EventID  int `json:"eventid" validate:"required,gt=0"`
+	TicketID int `json:"ticket_id" validate:"required,gt=0"`
+	Quantity int `json:"quantity" validate:"required,gt=0,lte=50"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// one line of the wallet ledger, positive amount is money in and negative is money out.
+// the ledger is never changed, the balance of a user is the sum of their entries
+type WalletEntry struct {
+	ID        int       `json:"id,omitempty"`
+	UserID    int       `json:"userid"`
+	Kind      string    `json:"kind"`
+	Amount    Money     `json:"amount"`
+	OrderID   int       `json:"orderid,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// kind of a wallet entry
+const (
+	WalletTopUp      = "TOPUP" // entry of an older ledger, a counter top up is now an adjustment
+	WalletPurchase   = "PURCHASE"
+	WalletRefund     = "REFUND"
+	WalletAdjustment = "ADJUSTMENT"
+)
+
+// correction of a balance by admin, the amount can be negative
+type AdjustmentRequest struct {
+	UserID int    `json:"userid" validate:"required"`
+	Amount Money  `json:"amount" validate:"required"`
+	Note   string `json:"note" validate:"required,noblank"`
+}
+
+// balance of a user together with every entry that make it
+type Statement struct {
+	UserID  int           `json:"userid"`
+	Balance Money         `json:"balance"`
+	Entries []WalletEntry `json:"entries"`
+}
+
+// result of checking the ledger against the orders
+type Reconciliation struct {
+	Balanced       bool       `json:"balanced"`
+	OrdersChecked  int        `json:"orders_checked"`
+	EntriesChecked int        `json:"entries_checked"`
+	Mismatches     []Mismatch `json:"mismatches"`
+}
+
+// one thing in the ledger that doesn't match the orders
+type Mismatch struct {
+	OrderID  int    `json:"orderid,omitempty"`
+	UserID   int    `json:"userid,omitempty"`
+	Reason   string `json:"reason"`
+	Expected Money  `json:"expected"`
+	Actual   Money  `json:"actual"`
+}
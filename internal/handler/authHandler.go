@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"time"
+)
+
+// make a connection to usecase
+type AuthHandler struct {
+	AuthUsecase usecase.AuthUsecaseInterface
+}
+
+func NewAuthHandler(authUsecase usecase.AuthUsecaseInterface) AuthHandlerInterface {
+	return AuthHandler{
+		AuthUsecase: authUsecase,
+	}
+}
+
+type AuthHandlerInterface interface {
+	Login
+}
+type Login interface {
+	Login(w http.ResponseWriter, r *http.Request)
+}
+
+// function for login, give back a bearer token
+func (h AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Login API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var credential domain.Credential
+	if err := json.NewDecoder(r.Body).Decode(&credential); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Login API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(credential); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Login API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	token, err := h.AuthUsecase.Login(credential, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Login API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Login success", Status: http.StatusOK, Data: token})
+	LogMethod("Login API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
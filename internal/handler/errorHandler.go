@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"time"
+)
+
+// pick the http status from the kind of the error
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, domain.ErrGatewayTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrInsufficientBalance), errors.Is(err, domain.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrOutOfStock), errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrUnauthorized):
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// machine readable code of the error
+func errorCode(err error) string {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "TIMEOUT"
+	}
+	return "INTERNAL_ERROR"
+}
+
+// write the usecase error to the response body and log it
+func writeError(w http.ResponseWriter, r *http.Request, kontek context.Context, logMsg string, err error) {
+	status := errorStatus(err)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: status, Code: errorCode(err)})
+	LogMethod(logMsg+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), status)
+}
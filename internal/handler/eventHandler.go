@@ -29,6 +29,8 @@ type EventHandlerInterface interface {
 	UpdateEvent
 	DeleteEvent
 	GetAllEvents
+	GetEventsByDate
+	GetEventAvailability
 }
 type CreateEvent interface {
 	CreateEvent(w http.ResponseWriter, r *http.Request)
@@ -48,6 +50,12 @@ type DeleteEvent interface {
 type GetAllEvents interface {
 	GetAllEvents(w http.ResponseWriter, r *http.Request)
 }
+type GetEventsByDate interface {
+	GetEventsByDate(w http.ResponseWriter, r *http.Request)
+}
+type GetEventAvailability interface {
+	GetEventAvailability(w http.ResponseWriter, r *http.Request)
+}
 
 // function for creating event
 func (h EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
@@ -61,7 +69,7 @@ func (h EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	// check if the method is post
 	if r.Method != "POST" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Create Event API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -70,7 +78,7 @@ func (h EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Create Event API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -78,7 +86,7 @@ func (h EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	// validate the input
 	if err := validate.Struct(event); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Create Event API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -86,15 +94,7 @@ func (h EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	// send the data to usecase
 	events, err := h.EventUsecase.CreateEvent(event, kontek)
 	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Create Event API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusInternalServerError})
-		LogMethod("Create Event API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusInternalServerError)
+		writeError(w, r, kontek, "Create Event API Failed ", err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -113,7 +113,7 @@ func (h EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
 	// check if the method is post
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Get Event By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -122,7 +122,7 @@ func (h EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
 	eventIdStr := r.URL.Query().Get("id")
 	if eventIdStr == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Missing event ID in uri param", Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing event ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Get Event By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -131,7 +131,7 @@ func (h EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
 	eventId, err := strconv.Atoi(eventIdStr)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid event ID", Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid event ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Get Event By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -139,15 +139,7 @@ func (h EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
 	// send the data to usecase
 	events, err := h.EventUsecase.GetEventByID(eventId, kontek)
 	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Get Event By ID API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Event ID not found", Status: http.StatusNotFound})
-		LogMethod("Get Event By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		writeError(w, r, kontek, "Get Event By ID API Failed ", err)
 		return
 	}
 
@@ -168,7 +160,7 @@ func (h EventHandler) GetEventByName(w http.ResponseWriter, r *http.Request) {
 	// check if the method is get
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Get Event By Name API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -177,7 +169,7 @@ func (h EventHandler) GetEventByName(w http.ResponseWriter, r *http.Request) {
 	eventName := r.URL.Query().Get("name")
 	if strings.TrimSpace(eventName) == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Missing event Name", Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing event Name", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Get Event By Name API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -185,15 +177,7 @@ func (h EventHandler) GetEventByName(w http.ResponseWriter, r *http.Request) {
 	// send the data to usecase
 	events, err := h.EventUsecase.GetEventByName(eventName, kontek)
 	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Get Event By Name API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Can't find Event By Name", Status: http.StatusNotFound})
-		LogMethod("Get Event By Name API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		writeError(w, r, kontek, "Get Event By Name API Failed ", err)
 		return
 	}
 	// show it on response body
@@ -213,7 +197,7 @@ func (h EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 	// check if the method is put
 	if r.Method != "PUT" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Update Event API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -221,7 +205,7 @@ func (h EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 	var event domain.Event
 	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Update Event API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -229,22 +213,14 @@ func (h EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 	// validate the input
 	if err := validate.Struct(event); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Update Event API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
 
 	// send it to usecase
 	if err := h.EventUsecase.UpdateEvent(event, kontek); err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Update Event API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusNotFound})
-		LogMethod("Update Event API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		writeError(w, r, kontek, "Update Event API Failed ", err)
 		return
 	}
 	// show it on response body
@@ -264,7 +240,7 @@ func (h EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	// check if the method is delete
 	if r.Method != "DELETE" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Delete Event API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -273,7 +249,7 @@ func (h EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	eventIdStr := r.URL.Query().Get("id")
 	if eventIdStr == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: "ID param is required", Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: "ID param is required", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Delete Event API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -282,21 +258,13 @@ func (h EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	eventId, err := strconv.Atoi(eventIdStr)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Delete Event API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
 	// send to usecase
 	if err := h.EventUsecase.DeleteEvent(eventId, kontek); err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Delete Event API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusNotFound})
-		LogMethod("Delete Event API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		writeError(w, r, kontek, "Delete Event API Failed ", err)
 		return
 	}
 	// show it on response body
@@ -316,7 +284,7 @@ func (h EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
 	// check if the method is using get
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Get All Events API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -324,19 +292,101 @@ func (h EventHandler) GetAllEvents(w http.ResponseWriter, r *http.Request) {
 	// send to usecase
 	events, err := h.EventUsecase.GetAllEvents(kontek)
 	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Get All Events API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
+		writeError(w, r, kontek, "Get All Events API Failed ", err)
+		return
+	}
+	// show it on response body
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+	LogMethod("Get All Events API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for getting the event that start between from and to, both in RFC 3339 and both optional
+func (h EventHandler) GetEventsByDate(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is using get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Events By Date API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// an empty param mean no limit on that side
+	var dates [2]time.Time
+	for i, param := range []string{"from", "to"} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(domain.Response{Message: "Invalid " + param + " date, use RFC 3339", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+			LogMethod("Get Events By Date API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 			return
 		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusNotFound})
-		LogMethod("Get All Events API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		dates[i] = date
+	}
+
+	// send to usecase
+	events, err := h.EventUsecase.GetEventsByDate(dates[0], dates[1], kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Events By Date API Failed ", err)
 		return
 	}
 	// show it on response body
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(events)
-	LogMethod("Get All Events API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+	LogMethod("Get Events By Date API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for showing which ticket type can be bought now and when the next sale phase open
+func (h EventHandler) GetEventAvailability(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Event Availability API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// get query param from url
+	eventIdStr := r.URL.Query().Get("id")
+	if eventIdStr == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing event ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Get Event Availability API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// convert the query param id to int
+	eventId, err := strconv.Atoi(eventIdStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid event ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Get Event Availability API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	availability, err := h.EventUsecase.GetEventAvailability(eventId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Event Availability API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(availability)
+	LogMethod("Get Event Availability API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
 }
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"strconv"
+	"time"
+)
+
+// make a connection to usecase
+type HoldHandler struct {
+	HoldUsecase usecase.HoldUsecaseInterface
+}
+
+func NewHoldHandler(holdUsecase usecase.HoldUsecaseInterface) HoldHandlerInterface {
+	return HoldHandler{
+		HoldUsecase: holdUsecase,
+	}
+}
+
+type HoldHandlerInterface interface {
+	ReserveTicket
+	ConfirmHold
+	ReleaseHold
+	GetHoldByID
+}
+type ReserveTicket interface {
+	ReserveTicket(w http.ResponseWriter, r *http.Request)
+}
+type ConfirmHold interface {
+	ConfirmHold(w http.ResponseWriter, r *http.Request)
+}
+type ReleaseHold interface {
+	ReleaseHold(w http.ResponseWriter, r *http.Request)
+}
+type GetHoldByID interface {
+	GetHoldByID(w http.ResponseWriter, r *http.Request)
+}
+
+// function for reserving ticket before paying
+func (h HoldHandler) ReserveTicket(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Reserve Ticket API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var OrderReq domain.OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&OrderReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Reserve Ticket API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// the ticket is held for the logged in user, not the one in the body
+	caller, ok := domain.CurrentUser(kontek)
+	if !ok {
+		writeError(w, r, kontek, "Reserve Ticket API Failed ", domain.ErrMissingToken)
+		return
+	}
+	OrderReq.UserID = caller.ID
+
+	// validate the input
+	if err := validate.Struct(OrderReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Reserve Ticket API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	hold, err := h.HoldUsecase.ReserveTicket(OrderReq, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Reserve Ticket API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Ticket has been reserved", Status: http.StatusOK, Data: hold})
+	LogMethod("Reserve Ticket API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for paying a reservation
+func (h HoldHandler) ConfirmHold(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Confirm Reservation API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	holdId, ok := h.holdID(w, r, kontek, "Confirm Reservation API Failed")
+	if !ok {
+		return
+	}
+
+	// send the data to usecase
+	order, err := h.HoldUsecase.ConfirmHold(holdId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Confirm Reservation API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Reservation has been paid", Status: http.StatusOK, Data: order})
+	LogMethod("Confirm Reservation API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for cancelling a reservation
+func (h HoldHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Release Reservation API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	holdId, ok := h.holdID(w, r, kontek, "Release Reservation API Failed")
+	if !ok {
+		return
+	}
+
+	// send the data to usecase
+	hold, err := h.HoldUsecase.ReleaseHold(holdId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Release Reservation API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Reservation has been released", Status: http.StatusOK, Data: hold})
+	LogMethod("Release Reservation API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for get reservation by id
+func (h HoldHandler) GetHoldByID(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Reservation By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	holdId, ok := h.holdID(w, r, kontek, "Get Reservation By ID API Failed")
+	if !ok {
+		return
+	}
+
+	// send the data to usecase
+	hold, err := h.HoldUsecase.GetHoldByID(holdId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Reservation By ID API Failed ", err)
+		return
+	}
+
+	// get the data and show it on response body
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(hold)
+	LogMethod("Get Reservation By ID API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// get the reservation id from the uri param
+func (h HoldHandler) holdID(w http.ResponseWriter, r *http.Request, kontek context.Context, logMsg string) (int, bool) {
+	holdIdStr := r.URL.Query().Get("id")
+	if holdIdStr == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing reservation ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg, r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return 0, false
+	}
+
+	// convert the query param id to int
+	holdId, err := strconv.Atoi(holdIdStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid reservation ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg, r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return 0, false
+	}
+	return holdId, true
+}
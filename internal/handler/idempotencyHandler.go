@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// keep a copy of the response so it can be saved for the retry
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
+
+// run serve once for every Idempotency-Key header, a retry with the same key and request
+// get the saved response back. without the header serve always run. serve return the number of
+// the order it saved, a retry of a request that failed after it get that order from replay
+func withIdempotency(idempotency usecase.IdempotencyUsecaseInterface, w http.ResponseWriter, r *http.Request, kontek context.Context, logMsg string, request any, serve func(w http.ResponseWriter) string, replay func(w http.ResponseWriter, orderNumber string)) {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		serve(w)
+		return
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		writeError(w, r, kontek, logMsg+" Failed ", err)
+		return
+	}
+	hash := sha256.Sum256(requestJSON)
+
+	record, err := idempotency.BeginRequest(key, hex.EncodeToString(hash[:]), kontek)
+	if err != nil {
+		writeError(w, r, kontek, logMsg+" Failed ", err)
+		return
+	}
+	if record.Status == domain.IdempotencyOrderSaved {
+		w.Header().Set("Idempotent-Replayed", "true")
+		replay(w, record.OrderNumber)
+		return
+	}
+	if record.Status == domain.IdempotencyDone {
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(record.ResponseCode)
+		w.Write(record.ResponseBody)
+		LogMethod(logMsg+" Replayed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), record.ResponseCode)
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	orderNumber := serve(recorder)
+
+	// the request can end by its timeout, the key is still finished
+	if err := idempotency.FinishRequest(record, orderNumber, recorder.status, recorder.body.Bytes(), context.WithoutCancel(kontek)); err != nil {
+		log.Error().Err(err).Str("key", key).Msg(logMsg + " Idempotency Key Not Saved")
+	}
+}
@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"strconv"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// the QR image is square, its side in pixel when no size is asked and the allowed range
+const (
+	qrDefaultSize = 256
+	qrMinSize     = 64
+	qrMaxSize     = 1024
+)
+
+// make a connection to usecase
+type IssuedTicketHandler struct {
+	IssuedTicketUsecase usecase.IssuedTicketUsecaseInterface
+}
+
+func NewIssuedTicketHandler(issuedTicketUsecase usecase.IssuedTicketUsecaseInterface) IssuedTicketHandlerInterface {
+	return IssuedTicketHandler{
+		IssuedTicketUsecase: issuedTicketUsecase,
+	}
+}
+
+type IssuedTicketHandlerInterface interface {
+	GetMyTickets
+	TransferTicket
+	CheckInTicket
+	GetTicketQR
+	GetPassKey
+}
+type GetMyTickets interface {
+	GetMyTickets(w http.ResponseWriter, r *http.Request)
+}
+type TransferTicket interface {
+	TransferTicket(w http.ResponseWriter, r *http.Request)
+}
+type CheckInTicket interface {
+	CheckInTicket(w http.ResponseWriter, r *http.Request)
+}
+type GetTicketQR interface {
+	GetTicketQR(w http.ResponseWriter, r *http.Request)
+}
+type GetPassKey interface {
+	GetPassKey(w http.ResponseWriter, r *http.Request)
+}
+
+// func for get every ticket of the logged in user
+func (h IssuedTicketHandler) GetMyTickets(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get My Tickets API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := domain.CurrentUser(kontek)
+	if !ok {
+		writeError(w, r, kontek, "Get My Tickets API Failed ", domain.ErrMissingToken)
+		return
+	}
+
+	// send the data to usecase
+	tickets, err := h.IssuedTicketUsecase.GetMyTickets(caller.ID, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get My Tickets API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tickets)
+	LogMethod("Get My Tickets API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for giving a ticket to another user
+func (h IssuedTicketHandler) TransferTicket(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Transfer Ticket API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var transferReq domain.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&transferReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Transfer Ticket API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(transferReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Transfer Ticket API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send it to usecase
+	ticket, err := h.IssuedTicketUsecase.TransferTicket(transferReq, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Transfer Ticket API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Ticket has been transferred", Status: http.StatusOK, Data: ticket})
+	LogMethod("Transfer Ticket API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for letting the holder of a ticket in at the gate
+func (h IssuedTicketHandler) CheckInTicket(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Check In Ticket API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing ticket code in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Check In Ticket API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	ticket, err := h.IssuedTicketUsecase.CheckInTicket(code, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Check In Ticket API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Ticket has been checked in", Status: http.StatusOK, Data: ticket})
+	LogMethod("Check In Ticket API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for get the signed QR code of a ticket as a png or svg image, ?code=&format=png|svg&size=
+func (h IssuedTicketHandler) GetTicketQR(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	// the error is json, the content type of the image is set when it is written
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Ticket QR API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing ticket code in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Get Ticket QR API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "svg" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Format must be png or svg", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Get Ticket QR API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+	size := qrDefaultSize
+	if sizeStr := r.URL.Query().Get("size"); sizeStr != "" {
+		var err error
+		size, err = strconv.Atoi(sizeStr)
+		if err != nil || size < qrMinSize || size > qrMaxSize {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(domain.Response{Message: fmt.Sprintf("Size must be a number from %d to %d", qrMinSize, qrMaxSize), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+			LogMethod("Get Ticket QR API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// send the data to usecase
+	pass, err := h.IssuedTicketUsecase.GetTicketPass(code, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Ticket QR API Failed ", err)
+		return
+	}
+
+	// medium recovery still scan from a scratched print or a cracked phone screen
+	qr, err := qrcode.New(pass, qrcode.Medium)
+	if err != nil {
+		writeError(w, r, kontek, "Get Ticket QR API Failed ", err)
+		return
+	}
+	var image []byte
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		image = qrSVG(qr.Bitmap(), size)
+	} else {
+		image, err = qr.PNG(size)
+		if err != nil {
+			writeError(w, r, kontek, "Get Ticket QR API Failed ", err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+	}
+	// the pass inside the image is only valid while the ticket is, so it is never cached
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+	LogMethod("Get Ticket QR API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for get the public key a gate use to check the ticket QR offline
+func (h IssuedTicketHandler) GetPassKey(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Pass Key API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.IssuedTicketUsecase.GetPassKey())
+	LogMethod("Get Pass Key API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// draw every dark module of the QR as one path, the quiet zone is already in the bitmap
+func qrSVG(bitmap [][]bool, size int) []byte {
+	var path bytes.Buffer
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	var svg bytes.Buffer
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, len(bitmap), len(bitmap))
+	fmt.Fprintf(&svg, `<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="%s"/></svg>`, path.String())
+	return svg.Bytes()
+}
@@ -12,19 +12,25 @@ import (
 
 // make a connection to usecase
 type OrderHandler struct {
-	OrderUsecase usecase.OrderUsecaseInterface
+	OrderUsecase       usecase.OrderUsecaseInterface
+	IdempotencyUsecase usecase.IdempotencyUsecaseInterface
 }
 
-func NewOrderHandler(orderUsecase usecase.OrderUsecaseInterface) OrderHandlerInterface {
+func NewOrderHandler(orderUsecase usecase.OrderUsecaseInterface, idempotencyUsecase usecase.IdempotencyUsecaseInterface) OrderHandlerInterface {
 	return OrderHandler{
-		OrderUsecase: orderUsecase,
+		OrderUsecase:       orderUsecase,
+		IdempotencyUsecase: idempotencyUsecase,
 	}
 }
 
 type OrderHandlerInterface interface {
 	CreateOrder
 	GetOrderByID
+	GetOrderByNumber
 	GetAllOrders
+	CancelOrder
+	RefundOrder
+	GetOrganizerSales
 }
 type CreateOrder interface {
 	CreateOrder(w http.ResponseWriter, r *http.Request)
@@ -32,9 +38,21 @@ type CreateOrder interface {
 type GetOrderByID interface {
 	GetOrderByID(w http.ResponseWriter, r *http.Request)
 }
+type GetOrderByNumber interface {
+	GetOrderByNumber(w http.ResponseWriter, r *http.Request)
+}
 type GetAllOrders interface {
 	GetAllOrders(w http.ResponseWriter, r *http.Request)
 }
+type CancelOrder interface {
+	CancelOrder(w http.ResponseWriter, r *http.Request)
+}
+type RefundOrder interface {
+	RefundOrder(w http.ResponseWriter, r *http.Request)
+}
+type GetOrganizerSales interface {
+	GetOrganizerSales(w http.ResponseWriter, r *http.Request)
+}
 
 // function for creating Order
 func (h OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
@@ -48,7 +66,7 @@ func (h OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	// check if the method is post
 	if r.Method != "POST" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Create Order API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -59,55 +77,54 @@ func (h OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&OrderReq); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Create Order API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
 
+	// the buyer is the logged in user, not the one in the body
+	caller, ok := domain.CurrentUser(kontek)
+	if !ok {
+		writeError(w, r, kontek, "Create Order API Failed ", domain.ErrMissingToken)
+		return
+	}
+	OrderReq.UserID = caller.ID
+
 	// validate the input
 	if err := validate.Struct(OrderReq); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Create Order API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
 
-	// send the data to usecase
-	Orders, err := h.OrderUsecase.CreateOrder(OrderReq, kontek)
-	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Create Order API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		} else if err.Error() == "INSUFFICIENT BALANCE🤬🤬🤬🚨🚨" {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
-			LogMethod("Create Order API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
-			return
-		} else if err.Error() == "THERE'S NO USER WITH THAT ID🤬🚨🤬🚨" {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error() + ", please make an account before buy a ticket", Status: http.StatusNotFound})
-			LogMethod("Create Order API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
-			return
-		} else if err.Error() == "NOT ENOUGH TICKET STOCK🤬🚨🤬🚨" {
-			w.WriteHeader(http.StatusConflict)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusConflict})
-			LogMethod("Create Order API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusConflict)
-			return
-		} else if err.Error() == "THERE'S NO EVENT WITH THAT ID🤬🚨🤬🚨" {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusNotFound})
-			LogMethod("Create Order API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+	// a retry with the same Idempotency-Key get the first response instead of a new order
+	withIdempotency(h.IdempotencyUsecase, w, r, kontek, "Create Order API", OrderReq, func(w http.ResponseWriter) string {
+		// send the data to usecase
+		Orders, err := h.OrderUsecase.CreateOrder(OrderReq, kontek)
+		if err != nil {
+			writeError(w, r, kontek, "Create Order API Failed ", err)
+			// the order can be saved before the error, like a payment that time out after the debit
+			if Orders != nil && Orders.WasPlaced() {
+				return Orders.Number
+			}
+			return ""
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Order has been created", Status: http.StatusOK, Data: Orders})
+		LogMethod("Create Order API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+		return Orders.Number
+	}, func(w http.ResponseWriter, orderNumber string) {
+		// the order as it is now, the reaper or the callback can have paid or expired it since
+		Orders, err := h.OrderUsecase.GetOrderByNumber(orderNumber, kontek)
+		if err != nil {
+			writeError(w, r, kontek, "Create Order API Failed ", err)
 			return
 		}
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusInternalServerError})
-		LogMethod("Create Order API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusInternalServerError)
-		return
-	}
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(domain.Response{Message: "Order has been created", Status: http.StatusOK, Data: Orders})
-	LogMethod("Create Order API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Order has been created", Status: http.StatusOK, Data: Orders})
+		LogMethod("Create Order API Replayed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+	})
 }
 
 // func for get Order by id
@@ -121,7 +138,7 @@ func (h OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	// check if the method is post
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Get Order By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -130,7 +147,7 @@ func (h OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	OrderIdStr := r.URL.Query().Get("id")
 	if OrderIdStr == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Missing Order ID in uri param", Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing Order ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Get Order By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -139,7 +156,7 @@ func (h OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	OrderId, err := strconv.Atoi(OrderIdStr)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid Order ID", Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid Order ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Get Order By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -147,20 +164,7 @@ func (h OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	// send the data to usecase
 	Orders, err := h.OrderUsecase.GetOrderByID(OrderId, kontek)
 	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Get Order By ID API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		} else if err.Error() == "THIS USER HAVENT BUY A TICKET" {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusNotFound})
-			LogMethod("Get Order By ID API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Order ID not found", Status: http.StatusNotFound})
-		LogMethod("Get Order By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		writeError(w, r, kontek, "Get Order By ID API Failed ", err)
 		return
 	}
 
@@ -170,6 +174,40 @@ func (h OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	LogMethod("Get Order By ID API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
 }
 
+// function for get one order by its public number
+func (h OrderHandler) GetOrderByNumber(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Order By Number API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	number := r.URL.Query().Get("number")
+	if number == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing Order Number in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Get Order By Number API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.OrderUsecase.GetOrderByNumber(number, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Order By Number API Failed ", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+	LogMethod("Get Order By Number API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
 // function for get all Orders
 func (h OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
@@ -181,7 +219,7 @@ func (h OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	// check if the method is using get
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Get All Orders API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -189,15 +227,7 @@ func (h OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	// send to usecase
 	Orders, err := h.OrderUsecase.GetAllOrders(kontek)
 	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Get All Orders API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusNotFound})
-		LogMethod("Get All Orders API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		writeError(w, r, kontek, "Get All Orders API Failed ", err)
 		return
 	}
 	// show it on response body
@@ -205,3 +235,135 @@ func (h OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(Orders)
 	LogMethod("Get All Orders API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
 }
+
+// function for cancelling an Order and refund all of it
+func (h OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Cancel Order API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// get query param from url
+	OrderIdStr := r.URL.Query().Get("id")
+	if OrderIdStr == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing Order ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Cancel Order API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// convert the query param id to int
+	OrderId, err := strconv.Atoi(OrderIdStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid Order ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Cancel Order API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	Order, err := h.OrderUsecase.CancelOrder(OrderId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Cancel Order API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Order has been cancelled", Status: http.StatusOK, Data: Order})
+	LogMethod("Cancel Order API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for refunding part of an Order
+func (h OrderHandler) RefundOrder(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Refund Order API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var RefundReq domain.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&RefundReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Refund Order API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(RefundReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Refund Order API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	Order, err := h.OrderUsecase.RefundOrder(RefundReq, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Refund Order API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Order has been refunded", Status: http.StatusOK, Data: Order})
+	LogMethod("Refund Order API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for the sales of an organizer event, without id it is the logged in organizer
+func (h OrderHandler) GetOrganizerSales(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Organizer Sales API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := domain.CurrentUser(kontek)
+	if !ok {
+		writeError(w, r, kontek, "Get Organizer Sales API Failed ", domain.ErrMissingToken)
+		return
+	}
+	organizerId := caller.ID
+	if organizerIdStr := r.URL.Query().Get("id"); organizerIdStr != "" {
+		var err error
+		organizerId, err = strconv.Atoi(organizerIdStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(domain.Response{Message: "Invalid Organizer ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+			LogMethod("Get Organizer Sales API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// send the data to usecase
+	sales, err := h.OrderUsecase.GetOrganizerSales(organizerId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Organizer Sales API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Organizer sales", Status: http.StatusOK, Data: sales})
+	LogMethod("Get Organizer Sales API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/payment"
+	"pemesananTiketOnlineGo/internal/repository"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// the wallet take the money and then, once, the request run out of time before the payment is saved
+type slowWallet struct {
+	payment.PaymentProviderInterface
+	stall *atomic.Bool
+}
+
+func (p slowWallet) Initiate(order *domain.Order, kontek context.Context) (*domain.Payment, error) {
+	paid, err := p.PaymentProviderInterface.Initiate(order, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if p.stall.Swap(false) {
+		<-kontek.Done()
+	}
+	return paid, nil
+}
+
+// a retry of an order that timed out after the debit get the same order back and is never charged again
+func TestCreateOrderIdempotentTimeout(t *testing.T) {
+	cases := []struct {
+		name string
+		// how long the first request can run
+		timeout time.Duration
+		// the first request saved its order, so the retry replay it instead of buying again
+		saved bool
+	}{
+		{name: "timeout after the debit", timeout: 300 * time.Millisecond, saved: true},
+		{name: "timeout before the purchase", timeout: 0},
+	}
+	for _, backend := range []string{"memory", "sqlite"} {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				var eventRepo repository.EventRepoInterface
+				var userRepo repository.UserRepoInterface
+				var orderRepo repository.OrderRepoInterface
+				var walletRepo repository.WalletRepoInterface
+				var idempotencyRepo repository.IdempotencyRepoInterface
+				var unitOfWork repository.UnitOfWorkInterface
+				if backend == "memory" {
+					eventRepo, userRepo, orderRepo, walletRepo = repository.NewEventRepo(), repository.NewUserRepo(), repository.NewOrderRepo(), repository.NewWalletRepo()
+					idempotencyRepo, unitOfWork = repository.NewIdempotencyRepo(), repository.NewUnitOfWork()
+				} else {
+					db, err := repository.OpenSqlite(filepath.Join(t.TempDir(), "test.db"))
+					if err != nil {
+						t.Fatal(err)
+					}
+					t.Cleanup(func() { db.Close() })
+					eventRepo, userRepo, orderRepo, walletRepo = repository.NewEventRepoSql(db), repository.NewUserRepoSql(db), repository.NewOrderRepoSql(db), repository.NewWalletRepoSql(db)
+					idempotencyRepo, unitOfWork = repository.NewIdempotencyRepoSql(db), repository.NewUnitOfWorkSql(db)
+				}
+				var seatRepo repository.SeatRepoInterface = repository.NewSeatRepo()
+				var waitlistRepo repository.WaitlistRepoInterface = repository.NewWaitlistRepo()
+				var holdRepo repository.HoldRepoInterface = repository.NewHoldRepo()
+				stall := &atomic.Bool{}
+				stall.Store(tc.saved)
+				waitlist := usecase.NewWaitlistUsecase(waitlistRepo, eventRepo, holdRepo, orderRepo, unitOfWork, time.Minute).(usecase.WaitlistUsecase)
+				orderUsecase := usecase.NewOrderUsecase(orderRepo, eventRepo, userRepo, repository.NewPromoRepo(), repository.NewPricingRepo(), seatRepo, waitlistRepo,
+					repository.NewQueueRepo(), repository.NewIssuedTicketRepo(), payment.Providers{domain.PaymentWallet: slowWallet{payment.NewWalletProvider(walletRepo), stall}},
+					unitOfWork, waitlist, 15*time.Minute)
+				orderHandler := NewOrderHandler(orderUsecase, usecase.NewIdempotencyUsecase(idempotencyRepo, time.Hour))
+
+				system := domain.WithSystem(context.Background())
+				event, err := eventRepo.CreateEvent(&domain.Event{Name: "Retry", Start: time.Now().Add(24 * time.Hour), End: time.Now().Add(27 * time.Hour), Description: "Retry", Location: "Retry",
+					Ticket: []domain.Ticket{{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10}}}, system)
+				if err != nil {
+					t.Fatal(err)
+				}
+				user, err := userRepo.CreateUser(&domain.User{Name: "buyer", Role: domain.RoleCustomer}, system)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := walletRepo.AddEntry(&domain.WalletEntry{UserID: user.ID, Kind: domain.WalletTopUp, Amount: domain.NewMoney(100000, domain.DefaultCurrency)}, system); err != nil {
+					t.Fatal(err)
+				}
+				body := fmt.Sprintf(`{"eventid": %d, "ticket": [{"id": %d, "quantity": 2}]}`, event.ID, event.Ticket[0].ID)
+
+				order := func(timeout time.Duration) *httptest.ResponseRecorder {
+					kontek, cancel := context.WithTimeout(domain.WithUser(context.Background(), *user), timeout)
+					defer cancel()
+					request := httptest.NewRequest(http.MethodPost, "/buyTicket", bytes.NewBufferString(body)).WithContext(kontek)
+					request.Header.Set("Idempotency-Key", "retry-1")
+					response := httptest.NewRecorder()
+					orderHandler.CreateOrder(response, request)
+					return response
+				}
+
+				first := order(tc.timeout)
+				if first.Code != http.StatusGatewayTimeout {
+					t.Fatalf("first request got %d %s, want 504", first.Code, first.Body)
+				}
+				retry := order(5 * time.Second)
+				if retry.Code != http.StatusOK {
+					t.Fatalf("retry got %d %s, want 200", retry.Code, retry.Body)
+				}
+				if replayed := retry.Header().Get("Idempotent-Replayed") == "true"; replayed != tc.saved {
+					t.Errorf("retry replayed is %v, want %v", replayed, tc.saved)
+				}
+
+				var replay struct {
+					Data domain.Order `json:"data"`
+				}
+				if err := json.NewDecoder(retry.Body).Decode(&replay); err != nil {
+					t.Fatal(err)
+				}
+				orders, err := orderRepo.GetOrderByID(user.ID, system)
+				if err != nil {
+					t.Fatal(err)
+				}
+				placed := 0
+				for _, saved := range orders {
+					if saved.WasPlaced() {
+						placed++
+						if saved.Number != replay.Data.Number {
+							t.Errorf("retry answered order %s, the saved order is %s", replay.Data.Number, saved.Number)
+						}
+					}
+				}
+				if placed != 1 {
+					t.Errorf("%d order placed, want 1", placed)
+				}
+				entries, err := walletRepo.GetEntriesByUserID(user.ID, system)
+				if err != nil {
+					t.Fatal(err)
+				}
+				debits := 0
+				for _, entry := range entries {
+					if entry.Kind == domain.WalletPurchase {
+						debits++
+					}
+				}
+				if debits != 1 {
+					t.Errorf("wallet debited %d time, want 1", debits)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"strconv"
+	"time"
+)
+
+// make a connection to usecase
+type PaymentHandler struct {
+	PaymentUsecase usecase.PaymentUsecaseInterface
+}
+
+func NewPaymentHandler(paymentUsecase usecase.PaymentUsecaseInterface) PaymentHandlerInterface {
+	return PaymentHandler{
+		PaymentUsecase: paymentUsecase,
+	}
+}
+
+type PaymentHandlerInterface interface {
+	PaymentCallback
+	ConfirmPayment
+	ScriptSimulator
+	SettleSimulated
+}
+type PaymentCallback interface {
+	PaymentCallback(w http.ResponseWriter, r *http.Request)
+}
+type ConfirmPayment interface {
+	ConfirmPayment(w http.ResponseWriter, r *http.Request)
+}
+type ScriptSimulator interface {
+	ScriptSimulator(w http.ResponseWriter, r *http.Request)
+}
+type SettleSimulated interface {
+	SettleSimulated(w http.ResponseWriter, r *http.Request)
+}
+
+// function for the webhook of the payment provider, it is trusted by its signature not by login
+func (h PaymentHandler) PaymentCallback(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Payment Callback API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var callback domain.PaymentCallback
+	if err := json.NewDecoder(r.Body).Decode(&callback); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Payment Callback API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(callback); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Payment Callback API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	order, err := h.PaymentUsecase.PaymentCallback(callback, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Payment Callback API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Payment callback received", Status: http.StatusOK, Data: order})
+	LogMethod("Payment Callback API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for asking the provider the payment status of an order
+func (h PaymentHandler) ConfirmPayment(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Confirm Payment API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// get query param from url
+	OrderIdStr := r.URL.Query().Get("id")
+	if OrderIdStr == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing Order ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Confirm Payment API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// convert the query param id to int
+	OrderId, err := strconv.Atoi(OrderIdStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid Order ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Confirm Payment API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	order, err := h.PaymentUsecase.ConfirmPayment(OrderId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Confirm Payment API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Payment status", Status: http.StatusOK, Data: order})
+	LogMethod("Confirm Payment API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for admin to script what the payment simulator do next
+func (h PaymentHandler) ScriptSimulator(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Script Simulator API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var script domain.SimulatorScript
+	if err := json.NewDecoder(r.Body).Decode(&script); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Script Simulator API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(script); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Script Simulator API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	if err := h.PaymentUsecase.ScriptSimulator(script, kontek); err != nil {
+		writeError(w, r, kontek, "Script Simulator API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Simulator has been scripted", Status: http.StatusOK, Data: script})
+	LogMethod("Script Simulator API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for admin to pay or fail a simulated payment by hand
+func (h PaymentHandler) SettleSimulated(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Settle Simulated API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var settlement domain.SimulatorSettlement
+	if err := json.NewDecoder(r.Body).Decode(&settlement); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Settle Simulated API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(settlement); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Settle Simulated API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	if err := h.PaymentUsecase.SettleSimulated(settlement, kontek); err != nil {
+		writeError(w, r, kontek, "Settle Simulated API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Simulated payment has been settled", Status: http.StatusOK, Data: settlement})
+	LogMethod("Settle Simulated API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
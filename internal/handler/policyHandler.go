@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"slices"
+	"strings"
+	"time"
+)
+
+// who can call a route
+type Rule struct {
+	Public bool
+	Roles  []string
+}
+
+// anyone can call the route without login
+func Public() Rule {
+	return Rule{Public: true}
+}
+
+// every logged in user can call the route
+func LoggedIn() Rule {
+	return Rule{}
+}
+
+// only logged in user with one of the roles can call the route
+func Roles(roles ...string) Rule {
+	return Rule{Roles: roles}
+}
+
+// rule of every route, a route without rule is not served
+type Policy map[string]Rule
+
+// wrap the mux, check the token and the role before the request reach the route
+type PolicyHandler struct {
+	AuthUsecase usecase.AuthUsecaseInterface
+	Policy      Policy
+	Next        http.Handler
+}
+
+func NewPolicyHandler(authUsecase usecase.AuthUsecaseInterface, policy Policy, next http.Handler) http.Handler {
+	return PolicyHandler{
+		AuthUsecase: authUsecase,
+		Policy:      policy,
+		Next:        next,
+	}
+}
+
+func (h PolicyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	rule, exist := h.Policy[r.URL.Path]
+	if !exist {
+		w.Header().Set("Content-Type", "application/json")
+		writeError(w, r, kontek, "Policy Check Failed ", domain.ErrRouteNotFound)
+		return
+	}
+	if rule.Public {
+		h.Next.ServeHTTP(w, r)
+		return
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		w.Header().Set("Content-Type", "application/json")
+		writeError(w, r, kontek, "Policy Check Failed ", domain.ErrMissingToken)
+		return
+	}
+	user, err := h.AuthUsecase.Authenticate(token, kontek)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		writeError(w, r, kontek, "Policy Check Failed ", err)
+		return
+	}
+	if len(rule.Roles) > 0 && !slices.Contains(rule.Roles, user.Role) {
+		w.Header().Set("Content-Type", "application/json")
+		writeError(w, r, kontek, "Policy Check Failed ", domain.ErrRoleNotAllowed)
+		return
+	}
+
+	// the route and the usecase find the logged in user in the context
+	h.Next.ServeHTTP(w, r.WithContext(domain.WithUser(r.Context(), *user)))
+}
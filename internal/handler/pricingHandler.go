@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"time"
+)
+
+// make a connection to usecase
+type PricingHandler struct {
+	PricingUsecase usecase.PricingUsecaseInterface
+}
+
+func NewPricingHandler(pricingUsecase usecase.PricingUsecaseInterface) PricingHandlerInterface {
+	return PricingHandler{
+		PricingUsecase: pricingUsecase,
+	}
+}
+
+type PricingHandlerInterface interface {
+	GetAllPricings
+	SetPricing
+}
+type GetAllPricings interface {
+	GetAllPricings(w http.ResponseWriter, r *http.Request)
+}
+type SetPricing interface {
+	SetPricing(w http.ResponseWriter, r *http.Request)
+}
+
+// func for get the fee and tax of every currency
+func (h PricingHandler) GetAllPricings(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get All Pricings API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// send to usecase
+	pricings, err := h.PricingUsecase.GetAllPricings(kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get All Pricings API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(pricings)
+	LogMethod("Get All Pricings API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for setting the fee and tax of one currency
+func (h PricingHandler) SetPricing(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is put
+	if r.Method != "PUT" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Set Pricing API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var pricing domain.Pricing
+	if err := json.NewDecoder(r.Body).Decode(&pricing); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Set Pricing API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(pricing); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Set Pricing API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send it to usecase
+	saved, err := h.PricingUsecase.SetPricing(pricing, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Set Pricing API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Pricing has been set", Status: http.StatusOK, Data: saved})
+	LogMethod("Set Pricing API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"time"
+)
+
+// make a connection to usecase
+type PromoHandler struct {
+	PromoUsecase usecase.PromoUsecaseInterface
+}
+
+func NewPromoHandler(promoUsecase usecase.PromoUsecaseInterface) PromoHandlerInterface {
+	return PromoHandler{
+		PromoUsecase: promoUsecase,
+	}
+}
+
+type PromoHandlerInterface interface {
+	CreatePromo
+	GetPromoByCode
+	GetAllPromos
+	UpdatePromo
+}
+type CreatePromo interface {
+	CreatePromo(w http.ResponseWriter, r *http.Request)
+}
+type GetPromoByCode interface {
+	GetPromoByCode(w http.ResponseWriter, r *http.Request)
+}
+type GetAllPromos interface {
+	GetAllPromos(w http.ResponseWriter, r *http.Request)
+}
+type UpdatePromo interface {
+	UpdatePromo(w http.ResponseWriter, r *http.Request)
+}
+
+// function for making a promo code
+func (h PromoHandler) CreatePromo(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Create Promo API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	promo, ok := decodePromo(w, r, kontek, "Create Promo API Failed ")
+	if !ok {
+		return
+	}
+
+	// send the data to usecase
+	created, err := h.PromoUsecase.CreatePromo(promo, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Create Promo API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Promo has been created", Status: http.StatusOK, Data: created})
+	LogMethod("Create Promo API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for get promo by its code
+func (h PromoHandler) GetPromoByCode(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Promo By Code API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing promo code in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Get Promo By Code API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	promo, err := h.PromoUsecase.GetPromoByCode(code, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Promo By Code API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(promo)
+	LogMethod("Get Promo By Code API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for get every promo the caller can manage
+func (h PromoHandler) GetAllPromos(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get All Promos API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// send to usecase
+	promos, err := h.PromoUsecase.GetAllPromos(kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get All Promos API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(promos)
+	LogMethod("Get All Promos API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for changing a promo, the promo is found by its code
+func (h PromoHandler) UpdatePromo(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is put
+	if r.Method != "PUT" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Update Promo API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	promo, ok := decodePromo(w, r, kontek, "Update Promo API Failed ")
+	if !ok {
+		return
+	}
+
+	// send it to usecase
+	updated, err := h.PromoUsecase.UpdatePromo(promo, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Update Promo API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Promo has been updated", Status: http.StatusOK, Data: updated})
+	LogMethod("Update Promo API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// read and validate the promo from the request body
+func decodePromo(w http.ResponseWriter, r *http.Request, kontek context.Context, logMsg string) (domain.Promo, bool) {
+	var promo domain.Promo
+	if err := json.NewDecoder(r.Body).Decode(&promo); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return promo, false
+	}
+	if err := validate.Struct(promo); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return promo, false
+	}
+	return promo, true
+}
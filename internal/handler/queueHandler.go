@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"strconv"
+	"time"
+)
+
+// how often the stream look at the place again
+const queueStreamInterval = time.Second
+
+// make a connection to usecase
+type QueueHandler struct {
+	QueueUsecase usecase.QueueUsecaseInterface
+}
+
+func NewQueueHandler(queueUsecase usecase.QueueUsecaseInterface) QueueHandlerInterface {
+	return QueueHandler{
+		QueueUsecase: queueUsecase,
+	}
+}
+
+type QueueHandlerInterface interface {
+	SetWaitingRoom
+	GetWaitingRoom
+	JoinQueue
+	GetQueueStatus
+	StreamQueue
+}
+type SetWaitingRoom interface {
+	SetWaitingRoom(w http.ResponseWriter, r *http.Request)
+}
+type GetWaitingRoom interface {
+	GetWaitingRoom(w http.ResponseWriter, r *http.Request)
+}
+type JoinQueue interface {
+	JoinQueue(w http.ResponseWriter, r *http.Request)
+}
+type GetQueueStatus interface {
+	GetQueueStatus(w http.ResponseWriter, r *http.Request)
+}
+type StreamQueue interface {
+	StreamQueue(w http.ResponseWriter, r *http.Request)
+}
+
+// function for opening, tuning or closing the waiting room of an event
+func (h QueueHandler) SetWaitingRoom(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is put
+	if r.Method != "PUT" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Set Waiting Room API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var roomReq domain.WaitingRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&roomReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Set Waiting Room API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(roomReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Set Waiting Room API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send it to usecase
+	room, err := h.QueueUsecase.SetWaitingRoom(roomReq, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Set Waiting Room API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Waiting room has been saved", Status: http.StatusOK, Data: room})
+	LogMethod("Set Waiting Room API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for get the setting of the waiting room and how many user are in it
+func (h QueueHandler) GetWaitingRoom(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Waiting Room API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	eventId, ok := h.eventID(w, r, kontek, "Get Waiting Room API Failed")
+	if !ok {
+		return
+	}
+
+	// send the data to usecase
+	room, err := h.QueueUsecase.GetWaitingRoom(eventId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Waiting Room API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(room)
+	LogMethod("Get Waiting Room API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for taking a place in the queue of an event
+func (h QueueHandler) JoinQueue(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Join Queue API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	eventId, ok := h.eventID(w, r, kontek, "Join Queue API Failed")
+	if !ok {
+		return
+	}
+
+	// the logged in user take the place
+	caller, ok := domain.CurrentUser(kontek)
+	if !ok {
+		writeError(w, r, kontek, "Join Queue API Failed ", domain.ErrMissingToken)
+		return
+	}
+
+	// send the data to usecase
+	entry, err := h.QueueUsecase.JoinQueue(eventId, caller.ID, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Join Queue API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "You are in the queue", Status: http.StatusOK, Data: entry})
+	LogMethod("Join Queue API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for polling the place in the queue
+func (h QueueHandler) GetQueueStatus(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Queue Status API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	token, ok := h.token(w, r, kontek, "Get Queue Status API Failed")
+	if !ok {
+		return
+	}
+
+	// send the data to usecase
+	entry, err := h.QueueUsecase.GetQueueStatus(token, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Queue Status API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entry)
+	LogMethod("Get Queue Status API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for following the place in the queue as server sent event, a "position" event is sent every time
+// the place change and the stream end with the "admitted" or "expired" event
+func (h QueueHandler) StreamQueue(w http.ResponseWriter, r *http.Request) {
+	// the stream last as long as the client stay, only every look up has a timeout
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Stream Queue API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	token, ok := h.token(w, r, kontek, "Stream Queue API Failed")
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		writeError(w, r, kontek, "Stream Queue API Failed ", domain.NewError(domain.ErrInvalidInput, "STREAM_NOT_SUPPORTED", "THIS CONNECTION CAN'T STREAM, POLL /queueStatus INSTEAD"))
+		return
+	}
+
+	// the first look up answer an unknown token with a normal error
+	entry, err := h.lookUp(token, kontek)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		writeError(w, r, kontek, "Stream Queue API Failed ", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(queueStreamInterval)
+	defer ticker.Stop()
+	var last *domain.QueueEntry
+	for {
+		if last == nil || entry.Status != last.Status || entry.Position != last.Position {
+			name := "position"
+			switch entry.Status {
+			case domain.QueueAdmitted:
+				name = "admitted"
+			case domain.QueueExpired:
+				name = "expired"
+			}
+			data, _ := json.Marshal(entry)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+			flusher.Flush()
+			if entry.Status != domain.QueueWaiting {
+				break
+			}
+			last = entry
+		}
+
+		select {
+		case <-kontek.Done():
+			LogMethod("Stream Queue API Closed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+			return
+		case <-ticker.C:
+		}
+		next, err := h.lookUp(token, kontek)
+		if err != nil {
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", err.Error())
+			flusher.Flush()
+			LogMethod("Stream Queue API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+			return
+		}
+		entry = next
+	}
+	LogMethod("Stream Queue API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// one look up of the stream
+func (h QueueHandler) lookUp(token string, kontek context.Context) (*domain.QueueEntry, error) {
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+	return h.QueueUsecase.GetQueueStatus(token, kontek)
+}
+
+// read the eventid query param, a missing or bad id is answered with 400
+func (h QueueHandler) eventID(w http.ResponseWriter, r *http.Request, kontek context.Context, logMsg string) (int, bool) {
+	idStr := r.URL.Query().Get("eventid")
+	if idStr == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing event ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg, r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return 0, false
+	}
+
+	// convert the query param id to int
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid event ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg, r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// read the token query param, a missing one is answered with 400
+func (h QueueHandler) token(w http.ResponseWriter, r *http.Request, kontek context.Context, logMsg string) (string, bool) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing queue token in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg, r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return "", false
+	}
+	return token, true
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"strconv"
+	"time"
+)
+
+// make a connection to usecase
+type SeatHandler struct {
+	SeatUsecase usecase.SeatUsecaseInterface
+}
+
+func NewSeatHandler(seatUsecase usecase.SeatUsecaseInterface) SeatHandlerInterface {
+	return SeatHandler{
+		SeatUsecase: seatUsecase,
+	}
+}
+
+type SeatHandlerInterface interface {
+	CreateSeatMap
+	GetSeatMap
+}
+type CreateSeatMap interface {
+	CreateSeatMap(w http.ResponseWriter, r *http.Request)
+}
+type GetSeatMap interface {
+	GetSeatMap(w http.ResponseWriter, r *http.Request)
+}
+
+// function for adding sections, rows and seats to an event
+func (h SeatHandler) CreateSeatMap(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Create Seat Map API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var seatMap domain.SeatMapRequest
+	if err := json.NewDecoder(r.Body).Decode(&seatMap); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Create Seat Map API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(seatMap); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Create Seat Map API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send it to usecase
+	created, err := h.SeatUsecase.CreateSeatMap(seatMap, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Create Seat Map API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Seats have been added", Status: http.StatusCreated, Data: created})
+	LogMethod("Create Seat Map API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusCreated)
+}
+
+// func for get the live status of every seat of an event
+func (h SeatHandler) GetSeatMap(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Seat Map API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// get query param from url
+	eventIdStr := r.URL.Query().Get("eventid")
+	if eventIdStr == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing event ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Get Seat Map API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// convert the query param id to int
+	eventId, err := strconv.Atoi(eventIdStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid event ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Get Seat Map API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	seatMap, err := h.SeatUsecase.GetSeatMap(eventId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Seat Map API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(seatMap)
+	LogMethod("Get Seat Map API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
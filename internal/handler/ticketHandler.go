@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"strconv"
+	"time"
+)
+
+// make a connection to usecase
+type TicketHandler struct {
+	TicketUsecase usecase.TicketUsecaseInterface
+}
+
+func NewTicketHandler(ticketUsecase usecase.TicketUsecaseInterface) TicketHandlerInterface {
+	return TicketHandler{
+		TicketUsecase: ticketUsecase,
+	}
+}
+
+type TicketHandlerInterface interface {
+	CreateTicket
+	GetTicketByID
+	UpdateTicket
+	CloseTicket
+}
+type CreateTicket interface {
+	CreateTicket(w http.ResponseWriter, r *http.Request)
+}
+type GetTicketByID interface {
+	GetTicketByID(w http.ResponseWriter, r *http.Request)
+}
+type UpdateTicket interface {
+	UpdateTicket(w http.ResponseWriter, r *http.Request)
+}
+type CloseTicket interface {
+	CloseTicket(w http.ResponseWriter, r *http.Request)
+}
+
+// function for adding a ticket type to an event
+func (h TicketHandler) CreateTicket(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Create Ticket Type API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var ticket domain.Ticket
+	if err := json.NewDecoder(r.Body).Decode(&ticket); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Create Ticket Type API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input, the ticket type must belong to an event
+	if err := validate.Struct(ticket); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Create Ticket Type API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+	if ticket.EventID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing event ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Create Ticket Type API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	created, err := h.TicketUsecase.CreateTicket(ticket, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Create Ticket Type API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Ticket type has been created", Status: http.StatusOK, Data: created})
+	LogMethod("Create Ticket Type API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for get ticket type by id
+func (h TicketHandler) GetTicketByID(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Ticket Type By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ticketId, ok := h.ticketID(w, r, kontek, "Get Ticket Type By ID API Failed")
+	if !ok {
+		return
+	}
+
+	// send the data to usecase
+	ticket, err := h.TicketUsecase.GetTicketByID(ticketId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Ticket Type By ID API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ticket)
+	LogMethod("Get Ticket Type By ID API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for renaming, repricing or restocking a ticket type
+func (h TicketHandler) UpdateTicket(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is put
+	if r.Method != "PUT" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Update Ticket Type API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var ticket domain.Ticket
+	if err := json.NewDecoder(r.Body).Decode(&ticket); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Update Ticket Type API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(ticket); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Update Ticket Type API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+	if ticket.ID <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing ticket type ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Update Ticket Type API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send it to usecase
+	updated, err := h.TicketUsecase.UpdateTicket(ticket, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Update Ticket Type API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Ticket type has been updated", Status: http.StatusOK, Data: updated})
+	LogMethod("Update Ticket Type API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for closing the sale of a ticket type
+func (h TicketHandler) CloseTicket(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Close Ticket Type API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ticketId, ok := h.ticketID(w, r, kontek, "Close Ticket Type API Failed")
+	if !ok {
+		return
+	}
+
+	// send the data to usecase
+	ticket, err := h.TicketUsecase.CloseTicket(ticketId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Close Ticket Type API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Ticket type has been closed", Status: http.StatusOK, Data: ticket})
+	LogMethod("Close Ticket Type API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// get the ticket type id from the uri param
+func (h TicketHandler) ticketID(w http.ResponseWriter, r *http.Request, kontek context.Context, logMsg string) (int, bool) {
+	ticketIdStr := r.URL.Query().Get("id")
+	if ticketIdStr == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing ticket type ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg, r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return 0, false
+	}
+
+	// convert the query param id to int
+	ticketId, err := strconv.Atoi(ticketIdStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid ticket type ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg, r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return 0, false
+	}
+	return ticketId, true
+}
@@ -29,6 +29,7 @@ type UserHandlerInterface interface {
 	UpdateUser
 	DeleteUser
 	GetAllUsers
+	SetUserRole
 }
 type CreateUser interface {
 	CreateUser(w http.ResponseWriter, r *http.Request)
@@ -48,6 +49,9 @@ type DeleteUser interface {
 type GetAllUsers interface {
 	GetAllUsers(w http.ResponseWriter, r *http.Request)
 }
+type SetUserRole interface {
+	SetUserRole(w http.ResponseWriter, r *http.Request)
+}
 
 // function for creating User
 func (h UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -61,7 +65,7 @@ func (h UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	// check if the method is post
 	if r.Method != "POST" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Create User API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -70,7 +74,7 @@ func (h UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&User); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Create User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -78,7 +82,7 @@ func (h UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	// validate the input
 	if err := validate.Struct(User); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Create User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -86,15 +90,7 @@ func (h UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	// send the data to usecase
 	user, err := h.UserUsecase.CreateUser(User, kontek)
 	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Create User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusInternalServerError})
-		LogMethod("Create User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusInternalServerError)
+		writeError(w, r, kontek, "Create User API Failed ", err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -112,7 +108,7 @@ func (h UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	// check if the method is post
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Get User By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -121,7 +117,7 @@ func (h UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	UserIdStr := r.URL.Query().Get("id")
 	if UserIdStr == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Missing User ID in uri param", Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing User ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Get User By ID API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -130,7 +126,7 @@ func (h UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	UserId, err := strconv.Atoi(UserIdStr)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid User ID", Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid User ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Create User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -138,15 +134,7 @@ func (h UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	// send the data to usecase
 	user, err := h.UserUsecase.GetUserByID(UserId, kontek)
 	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Get User By ID API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: "User ID not found", Status: http.StatusNotFound})
-		LogMethod("Get User By ID API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		writeError(w, r, kontek, "Get User By ID API Failed ", err)
 		return
 	}
 
@@ -166,7 +154,7 @@ func (h UserHandler) GetUserByName(w http.ResponseWriter, r *http.Request) {
 	// check if the method is get
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Get User By Name API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -175,7 +163,7 @@ func (h UserHandler) GetUserByName(w http.ResponseWriter, r *http.Request) {
 	UserName := r.URL.Query().Get("name")
 	if strings.TrimSpace(UserName) == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Missing User name", Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing User name", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Get User By Name API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -183,15 +171,7 @@ func (h UserHandler) GetUserByName(w http.ResponseWriter, r *http.Request) {
 	// send the data to usecase
 	user, err := h.UserUsecase.GetUserByName(UserName, kontek)
 	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Get User By Name API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Can't find User By Name", Status: http.StatusNotFound})
-		LogMethod("Get User By Name API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		writeError(w, r, kontek, "Get User By Name API Failed ", err)
 		return
 	}
 	// show it on response body
@@ -211,7 +191,7 @@ func (h UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	// check if the method is put
 	if r.Method != "PUT" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Update User API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -219,35 +199,38 @@ func (h UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	var user domain.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Update User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
 
+	// without an id the logged in user update their own account
+	caller, ok := domain.CurrentUser(kontek)
+	if !ok {
+		writeError(w, r, kontek, "Update User API Failed ", domain.ErrMissingToken)
+		return
+	}
+	if user.ID == 0 {
+		user.ID = caller.ID
+	}
+
 	// validate the input
 	if err := validate.Struct(user); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Update User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
 
 	// send it to usecase
-	if err := h.UserUsecase.UpdateUser(user, kontek); err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Update User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusNotFound})
-		LogMethod("Update User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+	updated, err := h.UserUsecase.UpdateUser(user, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Update User API Failed ", err)
 		return
 	}
 	// show it on response body
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(domain.Response{Message: "User has been updated", Status: http.StatusOK, Data: user})
+	json.NewEncoder(w).Encode(domain.Response{Message: "User has been updated", Status: http.StatusOK, Data: updated})
 	LogMethod("Update User API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
 }
 
@@ -262,7 +245,7 @@ func (h UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	// check if the method is delete
 	if r.Method != "DELETE" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Delete User API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -271,7 +254,7 @@ func (h UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	UserIdStr := r.URL.Query().Get("id")
 	if UserIdStr == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: "ID param is required", Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: "ID param is required", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Delete User API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
@@ -280,21 +263,13 @@ func (h UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	UserId, err := strconv.Atoi(UserIdStr)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest})
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
 		LogMethod("Delete User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
 		return
 	}
 	// send to usecase
 	if err := h.UserUsecase.DeleteUser(UserId, kontek); err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Delete User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusNotFound})
-		LogMethod("Delete User API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		writeError(w, r, kontek, "Delete User API Failed ", err)
 		return
 	}
 	// show it on rsponse body
@@ -314,7 +289,7 @@ func (h UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	// check if the method is using get
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed})
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
 		LogMethod("Get All Users API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
 		return
 	}
@@ -322,15 +297,7 @@ func (h UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	// send to usecase
 	users, err := h.UserUsecase.GetAllUsers(kontek)
 	if err != nil {
-		if err.Error() == "context deadline exceeded" {
-			w.WriteHeader(http.StatusGatewayTimeout)
-			json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusGatewayTimeout})
-			LogMethod("Get All Users API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusGatewayTimeout)
-			return
-		}
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(domain.Response{Message: "Method Not Allowed", Status: http.StatusNotFound})
-		LogMethod("Get All Users API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusNotFound)
+		writeError(w, r, kontek, "Get All Users API Failed ", err)
 		return
 	}
 	// show it on response body
@@ -338,3 +305,46 @@ func (h UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(users)
 	LogMethod("Get All Users API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
 }
+
+// function for giving a role to a user
+func (h UserHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is put
+	if r.Method != "PUT" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Set User Role API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var roleReq domain.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&roleReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Set User Role API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(roleReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Set User Role API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send it to usecase
+	user, err := h.UserUsecase.SetUserRole(roleReq, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Set User Role API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "User role has been changed", Status: http.StatusOK, Data: user})
+	LogMethod("Set User Role API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"pemesananTiketOnlineGo/internal/domain"
+	"reflect"
 	"strings"
 	"time"
 
@@ -14,9 +16,17 @@ func init() {
 	validate.RegisterValidation("noblank", func(fl validator.FieldLevel) bool {
 		return strings.TrimSpace(fl.Field().String()) != ""
 	})
-	validate.RegisterValidation("Datetime", func(fl validator.FieldLevel) bool {
-		dateStr := fl.Field().String()
-		_, err := time.Parse("02-Jan-2006 15:04:05", dateStr)
-		return err == nil
+	// IANA time zone name, Local is the zone of the server so it is not accepted
+	validate.RegisterValidation("timezone", func(fl validator.FieldLevel) bool {
+		name := fl.Field().String()
+		_, err := time.LoadLocation(name)
+		return err == nil && name != "Local"
 	})
+	// money is validated by its amount in minor unit, so gt=0 mean more than zero
+	validate.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if money, ok := field.Interface().(domain.Money); ok {
+			return money.Amount
+		}
+		return nil
+	}, domain.Money{})
 }
\ No newline at end of file
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"strconv"
+	"time"
+)
+
+// make a connection to usecase
+type WaitlistHandler struct {
+	WaitlistUsecase usecase.WaitlistUsecaseInterface
+}
+
+func NewWaitlistHandler(waitlistUsecase usecase.WaitlistUsecaseInterface) WaitlistHandlerInterface {
+	return WaitlistHandler{
+		WaitlistUsecase: waitlistUsecase,
+	}
+}
+
+type WaitlistHandlerInterface interface {
+	JoinWaitlist
+	LeaveWaitlist
+	GetWaitlistByUserID
+}
+type JoinWaitlist interface {
+	JoinWaitlist(w http.ResponseWriter, r *http.Request)
+}
+type LeaveWaitlist interface {
+	LeaveWaitlist(w http.ResponseWriter, r *http.Request)
+}
+type GetWaitlistByUserID interface {
+	GetWaitlistByUserID(w http.ResponseWriter, r *http.Request)
+}
+
+// function for waiting for a sold out ticket type
+func (h WaitlistHandler) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Join Waitlist API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var waitlistReq domain.WaitlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&waitlistReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Join Waitlist API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// the logged in user join the line, not the one in the body
+	caller, ok := domain.CurrentUser(kontek)
+	if !ok {
+		writeError(w, r, kontek, "Join Waitlist API Failed ", domain.ErrMissingToken)
+		return
+	}
+	waitlistReq.UserID = caller.ID
+
+	// validate the input
+	if err := validate.Struct(waitlistReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Join Waitlist API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	entry, err := h.WaitlistUsecase.JoinWaitlist(waitlistReq, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Join Waitlist API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(domain.Response{Message: "You are on the waitlist", Status: http.StatusCreated, Data: entry})
+	LogMethod("Join Waitlist API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusCreated)
+}
+
+// function for leaving the waitlist before getting an offer
+func (h WaitlistHandler) LeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Leave Waitlist API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	entryId, ok := h.queryID(w, r, kontek, "waitlist", "Leave Waitlist API Failed")
+	if !ok {
+		return
+	}
+
+	// send the data to usecase
+	entry, err := h.WaitlistUsecase.LeaveWaitlist(entryId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Leave Waitlist API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "You left the waitlist", Status: http.StatusOK, Data: entry})
+	LogMethod("Leave Waitlist API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// func for get every waitlist entry of a user, with the offered reservation
+func (h WaitlistHandler) GetWaitlistByUserID(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Waitlist API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, ok := h.queryID(w, r, kontek, "user", "Get Waitlist API Failed")
+	if !ok {
+		return
+	}
+
+	// send the data to usecase
+	entries, err := h.WaitlistUsecase.GetWaitlistByUserID(userId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Waitlist API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+	LogMethod("Get Waitlist API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// read the id query param, a missing or bad id is answered with 400
+func (h WaitlistHandler) queryID(w http.ResponseWriter, r *http.Request, kontek context.Context, name string, logMsg string) (int, bool) {
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Missing " + name + " ID in uri param", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg, r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return 0, false
+	}
+
+	// convert the query param id to int
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Invalid " + name + " ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod(logMsg, r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
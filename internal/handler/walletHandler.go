@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/usecase"
+	"strconv"
+	"time"
+)
+
+// make a connection to usecase
+type WalletHandler struct {
+	WalletUsecase usecase.WalletUsecaseInterface
+}
+
+func NewWalletHandler(walletUsecase usecase.WalletUsecaseInterface) WalletHandlerInterface {
+	return WalletHandler{
+		WalletUsecase: walletUsecase,
+	}
+}
+
+type WalletHandlerInterface interface {
+	AdjustBalance
+	GetStatement
+	Reconcile
+}
+type AdjustBalance interface {
+	AdjustBalance(w http.ResponseWriter, r *http.Request)
+}
+type GetStatement interface {
+	GetStatement(w http.ResponseWriter, r *http.Request)
+}
+type Reconcile interface {
+	Reconcile(w http.ResponseWriter, r *http.Request)
+}
+
+// function for admin to correct a balance
+func (h WalletHandler) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is post
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Adjust Balance API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var adjustmentReq domain.AdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&adjustmentReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Adjust Balance API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// validate the input
+	if err := validate.Struct(adjustmentReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(domain.Response{Message: err.Error(), Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+		LogMethod("Adjust Balance API Failed "+err.Error(), r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+		return
+	}
+
+	// send the data to usecase
+	entry, err := h.WalletUsecase.AdjustBalance(adjustmentReq, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Adjust Balance API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Balance has been adjusted", Status: http.StatusOK, Data: entry})
+	LogMethod("Adjust Balance API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for the wallet statement, without id it is the logged in user
+func (h WalletHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Get Statement API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := domain.CurrentUser(kontek)
+	if !ok {
+		writeError(w, r, kontek, "Get Statement API Failed ", domain.ErrMissingToken)
+		return
+	}
+	userId := caller.ID
+	if userIdStr := r.URL.Query().Get("id"); userIdStr != "" {
+		var err error
+		userId, err = strconv.Atoi(userIdStr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(domain.Response{Message: "Invalid User ID", Status: http.StatusBadRequest, Code: "INVALID_INPUT"})
+			LogMethod("Get Statement API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// send the data to usecase
+	statement, err := h.WalletUsecase.GetStatement(userId, kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Get Statement API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Wallet statement", Status: http.StatusOK, Data: statement})
+	LogMethod("Get Statement API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
+
+// function for checking the ledger against the orders
+func (h WalletHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	kontek := context.WithValue(r.Context(), domain.Key("waktu"), time.Now())
+	kontek, cancel := context.WithTimeout(kontek, 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// check if the method is get
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(domain.Response{Message: "Method not allowed", Status: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED"})
+		LogMethod("Reconcile API Failed", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// send to usecase
+	report, err := h.WalletUsecase.Reconcile(kontek)
+	if err != nil {
+		writeError(w, r, kontek, "Reconcile API Failed ", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(domain.Response{Message: "Wallet reconciliation", Status: http.StatusOK, Data: report})
+	LogMethod("Reconcile API Success", r.Method, kontek.Value(domain.Key("waktu")).(time.Time), http.StatusOK)
+}
@@ -0,0 +1,45 @@
+package payment
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+)
+
+// a payment gateway, the usecase only talk to the gateway through this
+type PaymentProviderInterface interface {
+	Initiate
+	Confirm
+	Refund
+	Callback
+}
+
+// start paying the order, the payment is PAID at once or PENDING until the callback
+type Initiate interface {
+	Initiate(order *domain.Order, kontek context.Context) (*domain.Payment, error)
+}
+
+// ask the provider the current status of the payment of the order
+type Confirm interface {
+	Confirm(order *domain.Order, kontek context.Context) (*domain.Payment, error)
+}
+
+// give part of the paid money back to the buyer
+type Refund interface {
+	Refund(order *domain.Order, amount domain.Money, kontek context.Context) error
+}
+
+// check the callback really come from the provider and turn it into the payment status
+type Callback interface {
+	Callback(callback domain.PaymentCallback, kontek context.Context) (*domain.Payment, error)
+}
+
+// provider of every payment method
+type Providers map[string]PaymentProviderInterface
+
+func (providers Providers) Get(method string) (PaymentProviderInterface, error) {
+	provider, exist := providers[method]
+	if !exist {
+		return nil, domain.ErrUnknownPayment
+	}
+	return provider, nil
+}
@@ -0,0 +1,198 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// local fake QRIS, bank transfer and card gateway. every method can be scripted to succeed,
+// fail, time out or wait to be settled by hand, and the callback is signed like a real gateway.
+// the payment is kept in its repo so a restart still know it
+type Simulator struct {
+	Secret      []byte
+	PaymentRepo repository.PaymentRepoInterface
+	state       *simulatorState
+}
+
+// shared state of the simulator, the simulator itself is passed by value
+type simulatorState struct {
+	mutek   sync.Mutex
+	scripts map[string]domain.SimulatorScript
+	deliver func(callback domain.PaymentCallback)
+}
+
+func NewSimulator(secret []byte, paymentRepo repository.PaymentRepoInterface) SimulatorInterface {
+	return Simulator{
+		Secret:      secret,
+		PaymentRepo: paymentRepo,
+		state: &simulatorState{
+			scripts: map[string]domain.SimulatorScript{},
+		},
+	}
+}
+
+type SimulatorInterface interface {
+	PaymentProviderInterface
+	Script
+	Settle
+	OnCallback
+}
+
+// change what happen to the next payment of a method
+type Script interface {
+	Script(script domain.SimulatorScript)
+}
+
+// pay or fail a pending payment by hand, the callback is sent like the gateway do
+type Settle interface {
+	Settle(settlement domain.SimulatorSettlement) error
+}
+
+// where the signed callback is sent
+type OnCallback interface {
+	OnCallback(deliver func(callback domain.PaymentCallback))
+}
+
+func (s Simulator) Script(script domain.SimulatorScript) {
+	s.state.mutek.Lock()
+	defer s.state.mutek.Unlock()
+	s.state.scripts[script.Method] = script
+}
+
+func (s Simulator) OnCallback(deliver func(callback domain.PaymentCallback)) {
+	s.state.mutek.Lock()
+	defer s.state.mutek.Unlock()
+	s.state.deliver = deliver
+}
+
+// make a pending payment, without a script the payment succeed right away.
+// it never wait, even a gateway that time out only leave the payment pending.
+// the settlement is only scheduled after the payment is committed
+func (s Simulator) Initiate(order *domain.Order, kontek context.Context) (*domain.Payment, error) {
+	s.state.mutek.Lock()
+	script, exist := s.state.scripts[order.PaymentMethod]
+	s.state.mutek.Unlock()
+	if !exist {
+		script = domain.SimulatorScript{Method: order.PaymentMethod, Outcome: domain.SimulateSuccess}
+	}
+	delay := time.Duration(script.DelayMs) * time.Millisecond
+
+	// one order has one payment, so its ID make the reference unique even after a restart
+	reference := fmt.Sprintf("SIM-%s-%06d", order.PaymentMethod, order.ID)
+	payment := domain.SimulatedPayment{
+		Payment: domain.Payment{
+			Method:      order.PaymentMethod,
+			Reference:   reference,
+			OrderID:     order.ID,
+			Amount:      order.TotalPrice,
+			Status:      domain.PaymentPending,
+			Instruction: instruction(order.PaymentMethod, reference, order.ID),
+		},
+		Silent: script.Outcome == domain.SimulateTimeout,
+	}
+	if err := s.PaymentRepo.CreatePayment(&payment, kontek); err != nil {
+		return nil, err
+	}
+
+	status := ""
+	switch script.Outcome {
+	case domain.SimulateSuccess:
+		status = domain.PaymentPaid
+	case domain.SimulateFail:
+		status = domain.PaymentFailed
+	}
+	if status != "" {
+		repository.AfterCommit(kontek, func() {
+			time.AfterFunc(delay, func() { s.Settle(domain.SimulatorSettlement{Reference: reference, Status: status}) })
+		})
+	}
+	return &payment.Payment, nil
+}
+
+func (s Simulator) Confirm(order *domain.Order, kontek context.Context) (*domain.Payment, error) {
+	payment, err := s.PaymentRepo.GetPaymentByReference(order.PaymentRef, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if payment.OrderID != order.ID {
+		return nil, domain.ErrPaymentNotFound
+	}
+	if payment.Silent && payment.Status == domain.PaymentPending {
+		return nil, domain.ErrPaymentTimeout
+	}
+	return &payment.Payment, nil
+}
+
+// only paid money can be refunded and never more than what is paid
+func (s Simulator) Refund(order *domain.Order, amount domain.Money, kontek context.Context) error {
+	payment, err := s.PaymentRepo.GetPaymentByReference(order.PaymentRef, kontek)
+	if err != nil {
+		return err
+	}
+	if payment.OrderID != order.ID {
+		return domain.ErrPaymentNotFound
+	}
+	return s.PaymentRepo.RefundPayment(payment.Reference, amount, kontek)
+}
+
+// a callback is only trusted when it is signed with the secret of the simulator
+func (s Simulator) Callback(callback domain.PaymentCallback, kontek context.Context) (*domain.Payment, error) {
+	if !hmac.Equal([]byte(callback.Signature), []byte(s.sign(callback))) {
+		return nil, domain.ErrInvalidSignature
+	}
+	payment, err := s.PaymentRepo.GetPaymentByReference(callback.Reference, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if payment.OrderID != callback.OrderID || payment.Method != callback.Method {
+		return nil, domain.ErrPaymentNotFound
+	}
+	payment.Status = callback.Status
+	return &payment.Payment, nil
+}
+
+// a payment that was rolled back is not in the repo anymore, so it is never settled
+func (s Simulator) Settle(settlement domain.SimulatorSettlement) error {
+	payment, err := s.PaymentRepo.SettlePayment(settlement.Reference, settlement.Status, context.Background())
+	if err != nil {
+		return err
+	}
+	s.state.mutek.Lock()
+	deliver := s.state.deliver
+	s.state.mutek.Unlock()
+
+	callback := domain.PaymentCallback{Method: payment.Method, Reference: payment.Reference, OrderID: payment.OrderID, Status: payment.Status}
+	callback.Signature = s.sign(callback)
+	if deliver != nil {
+		deliver(callback)
+	}
+	return nil
+}
+
+// hex hmac of every field of the callback except the signature
+func (s Simulator) sign(callback domain.PaymentCallback) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(callback.Method + "|" + callback.Reference + "|" + strconv.Itoa(callback.OrderID) + "|" + callback.Status))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// what the buyer see to pay with the method
+func instruction(method string, reference string, number int) string {
+	switch method {
+	case domain.PaymentQRIS:
+		return "QRIS:" + reference
+	case domain.PaymentBankTransfer:
+		return fmt.Sprintf("VA 8808%010d", number)
+	case domain.PaymentCard:
+		return "https://pay.local/card/" + reference
+	}
+	return reference
+}
@@ -0,0 +1,73 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+)
+
+// pay from the wallet ledger of the buyer, it is paid or failed at once
+type WalletProvider struct {
+	WalletRepo repository.WalletRepoInterface
+}
+
+func NewWalletProvider(walletRepo repository.WalletRepoInterface) PaymentProviderInterface {
+	return WalletProvider{
+		WalletRepo: walletRepo,
+	}
+}
+
+// debit the total of the order, the entry point to the order
+func (p WalletProvider) Initiate(order *domain.Order, kontek context.Context) (*domain.Payment, error) {
+	entry, err := p.WalletRepo.AddEntry(&domain.WalletEntry{
+		UserID:  order.User.ID,
+		Kind:    domain.WalletPurchase,
+		Amount:  order.TotalPrice.Neg(),
+		OrderID: order.ID,
+	}, kontek)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Payment{
+		Method:    domain.PaymentWallet,
+		Reference: fmt.Sprintf("WALLET-%d", entry.ID),
+		OrderID:   order.ID,
+		Amount:    order.TotalPrice,
+		Status:    domain.PaymentPaid,
+	}, nil
+}
+
+// the order is paid when the ledger has its purchase entry
+func (p WalletProvider) Confirm(order *domain.Order, kontek context.Context) (*domain.Payment, error) {
+	entries, err := p.WalletRepo.GetEntriesByUserID(order.User.ID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Kind == domain.WalletPurchase && entry.OrderID == order.ID {
+			return &domain.Payment{
+				Method:    domain.PaymentWallet,
+				Reference: fmt.Sprintf("WALLET-%d", entry.ID),
+				OrderID:   order.ID,
+				Amount:    entry.Amount.Neg(),
+				Status:    domain.PaymentPaid,
+			}, nil
+		}
+	}
+	return nil, domain.ErrPaymentNotFound
+}
+
+func (p WalletProvider) Refund(order *domain.Order, amount domain.Money, kontek context.Context) error {
+	_, err := p.WalletRepo.AddEntry(&domain.WalletEntry{
+		UserID:  order.User.ID,
+		Kind:    domain.WalletRefund,
+		Amount:  amount,
+		OrderID: order.ID,
+	}, kontek)
+	return err
+}
+
+func (p WalletProvider) Callback(callback domain.PaymentCallback, kontek context.Context) (*domain.Payment, error) {
+	return nil, domain.ErrNoCallback
+}
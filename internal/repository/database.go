@@ -0,0 +1,488 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+
+	_ "modernc.org/sqlite"
+)
+
+// every change to the schema is a new migration, never edit the old one
+var migrations = []string{
+	// 1: first schema
+	`CREATE TABLE events (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		name        TEXT NOT NULL UNIQUE,
+		date        TEXT NOT NULL,
+		description TEXT NOT NULL,
+		location    TEXT NOT NULL
+	);
+	CREATE TABLE ticket_types (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id  INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+		ticket_id INTEGER NOT NULL,
+		type      TEXT NOT NULL,
+		price     REAL NOT NULL DEFAULT 0,
+		quantity  INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+		UNIQUE (event_id, ticket_id)
+	);
+	CREATE TABLE users (
+		id      INTEGER PRIMARY KEY AUTOINCREMENT,
+		name    TEXT NOT NULL,
+		balance REAL NOT NULL DEFAULT 0 CHECK (balance >= 0)
+	);
+	CREATE TABLE orders (
+		id                INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_date        TEXT NOT NULL,
+		status            TEXT NOT NULL,
+		payment_method    TEXT NOT NULL DEFAULT '',
+		user_id           INTEGER NOT NULL,
+		user_name         TEXT NOT NULL DEFAULT '',
+		event_id          INTEGER NOT NULL,
+		event_name        TEXT NOT NULL DEFAULT '',
+		event_date        TEXT NOT NULL DEFAULT '',
+		event_location    TEXT NOT NULL DEFAULT '',
+		event_description TEXT NOT NULL DEFAULT '',
+		total_price       REAL NOT NULL DEFAULT 0
+	);
+	CREATE INDEX orders_user_id ON orders(user_id);
+	CREATE TABLE order_lines (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id  INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		ticket_id INTEGER NOT NULL,
+		type      TEXT NOT NULL DEFAULT '',
+		price     REAL NOT NULL DEFAULT 0,
+		quantity  INTEGER NOT NULL
+	);
+	CREATE INDEX order_lines_order_id ON order_lines(order_id);`,
+
+	// 2: reservation hold, ticket stock is split into available and held
+	`ALTER TABLE ticket_types ADD COLUMN held INTEGER NOT NULL DEFAULT 0 CHECK (held >= 0);
+	CREATE TABLE holds (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id    INTEGER NOT NULL,
+		event_id   INTEGER NOT NULL,
+		status     TEXT NOT NULL,
+		expires_at INTEGER NOT NULL,
+		order_id   INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX holds_status_expires_at ON holds(status, expires_at);
+	CREATE TABLE hold_lines (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		hold_id   INTEGER NOT NULL REFERENCES holds(id) ON DELETE CASCADE,
+		ticket_id INTEGER NOT NULL,
+		type      TEXT NOT NULL DEFAULT '',
+		quantity  INTEGER NOT NULL
+	);
+	CREATE INDEX hold_lines_hold_id ON hold_lines(hold_id);`,
+
+	// 3: refund policy of an event and refunded part of an order
+	`ALTER TABLE events ADD COLUMN refundable INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE events ADD COLUMN refund_cutoff_hours INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN refund_amount REAL NOT NULL DEFAULT 0;
+	ALTER TABLE order_lines ADD COLUMN refunded INTEGER NOT NULL DEFAULT 0;`,
+
+	// 4: login, user without password can't login until the password is set
+	`ALTER TABLE users ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
+	CREATE INDEX users_name ON users(name);`,
+
+	// 5: role of a user and the organizer that own an event
+	`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'customer';
+	ALTER TABLE events ADD COLUMN organizer_id INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX orders_event_id ON orders(event_id);`,
+
+	// 6: wallet ledger, the balance is the sum of the entries. the old balance is moved in as
+	// an adjustment and users.balance is not used anymore
+	`CREATE TABLE wallet_entries (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id    INTEGER NOT NULL,
+		kind       TEXT NOT NULL,
+		amount     REAL NOT NULL,
+		order_id   INTEGER NOT NULL DEFAULT 0,
+		note       TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL
+	);
+	CREATE INDEX wallet_entries_user_id ON wallet_entries(user_id);
+	CREATE INDEX wallet_entries_order_id ON wallet_entries(order_id);
+	INSERT INTO wallet_entries (user_id, kind, amount, note, created_at)
+		SELECT id, 'ADJUSTMENT', balance, 'balance before the wallet ledger', CAST(strftime('%s', 'now') AS INTEGER) * 1000
+		FROM users WHERE balance != 0;
+	UPDATE users SET balance = 0;`,
+
+	// 7: money is stored as integer minor unit with its currency, every old amount is rupiah
+	// with two decimal. users.balance can't be dropped because of its check, it stay unused
+	`ALTER TABLE ticket_types ADD COLUMN price_minor INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE ticket_types ADD COLUMN currency TEXT NOT NULL DEFAULT 'IDR';
+	UPDATE ticket_types SET price_minor = CAST(ROUND(price * 100) AS INTEGER);
+	ALTER TABLE ticket_types DROP COLUMN price;
+	ALTER TABLE orders ADD COLUMN total_minor INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN refund_minor INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN currency TEXT NOT NULL DEFAULT 'IDR';
+	UPDATE orders SET total_minor = CAST(ROUND(total_price * 100) AS INTEGER), refund_minor = CAST(ROUND(refund_amount * 100) AS INTEGER);
+	ALTER TABLE orders DROP COLUMN total_price;
+	ALTER TABLE orders DROP COLUMN refund_amount;
+	ALTER TABLE order_lines ADD COLUMN price_minor INTEGER NOT NULL DEFAULT 0;
+	UPDATE order_lines SET price_minor = CAST(ROUND(price * 100) AS INTEGER);
+	ALTER TABLE order_lines DROP COLUMN price;
+	ALTER TABLE wallet_entries ADD COLUMN amount_minor INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE wallet_entries ADD COLUMN currency TEXT NOT NULL DEFAULT 'IDR';
+	UPDATE wallet_entries SET amount_minor = CAST(ROUND(amount * 100) AS INTEGER);
+	ALTER TABLE wallet_entries DROP COLUMN amount;`,
+
+	// 8: payment provider of an order, every old order was paid from the wallet
+	`ALTER TABLE orders ADD COLUMN payment_reference TEXT NOT NULL DEFAULT '';
+	UPDATE orders SET payment_method = 'WALLET';`,
+
+	// 9: order state machine. the old free text status is split into a status and a failure reason,
+	// SUCCESS is FULFILLED and PENDING is AWAITING_PAYMENT. every old paid order get a PAID step
+	// in its history so it still count as paid
+	`ALTER TABLE orders ADD COLUMN failure_reason TEXT NOT NULL DEFAULT '';
+	ALTER TABLE orders ADD COLUMN payment_due INTEGER NOT NULL DEFAULT 0;
+	UPDATE orders SET failure_reason = TRIM(SUBSTR(status, 7)), status = 'FAILED' WHERE status LIKE 'FAILED%';
+	UPDATE orders SET status = 'FULFILLED' WHERE status = 'SUCCESS';
+	UPDATE orders SET status = 'AWAITING_PAYMENT' WHERE status = 'PENDING';
+	CREATE INDEX orders_status_payment_due ON orders(status, payment_due);
+	CREATE TABLE order_status_history (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		order_id    INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		from_status TEXT NOT NULL,
+		to_status   TEXT NOT NULL,
+		reason      TEXT NOT NULL DEFAULT '',
+		changed_at  INTEGER NOT NULL
+	);
+	CREATE INDEX order_status_history_order_id ON order_status_history(order_id);
+	INSERT INTO order_status_history (order_id, from_status, to_status, reason, changed_at)
+		SELECT id, '', 'PAID', 'before the status history', CAST(strftime('%s', 'now') AS INTEGER) * 1000
+		FROM orders WHERE status IN ('FULFILLED', 'REFUNDED', 'CANCELLED') ORDER BY id;
+	INSERT INTO order_status_history (order_id, from_status, to_status, changed_at)
+		SELECT id, 'PAID', status, CAST(strftime('%s', 'now') AS INTEGER) * 1000
+		FROM orders WHERE status IN ('FULFILLED', 'REFUNDED', 'CANCELLED') ORDER BY id;
+	INSERT INTO order_status_history (order_id, from_status, to_status, reason, changed_at)
+		SELECT id, '', status, failure_reason, CAST(strftime('%s', 'now') AS INTEGER) * 1000
+		FROM orders WHERE status NOT IN ('FULFILLED', 'REFUNDED', 'CANCELLED') ORDER BY id;`,
+
+	// 10: saved response of the request sent with an Idempotency-Key header
+	`CREATE TABLE idempotency_keys (
+		user_id       INTEGER NOT NULL,
+		key           TEXT NOT NULL,
+		request_hash  TEXT NOT NULL,
+		status        TEXT NOT NULL,
+		response_code INTEGER NOT NULL DEFAULT 0,
+		response_body BLOB,
+		expires_at    INTEGER NOT NULL,
+		PRIMARY KEY (user_id, key)
+	);
+	CREATE INDEX idempotency_keys_expires_at ON idempotency_keys(expires_at);`,
+
+	// 11: ticket type is its own entity, its row id is the ticket ID over every event. the old
+	// ticket_id was only unique inside the event, so the order and hold line are moved to the row id
+	`CREATE TABLE ticket_types_new (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id    INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+		type        TEXT NOT NULL,
+		price_minor INTEGER NOT NULL DEFAULT 0,
+		currency    TEXT NOT NULL DEFAULT 'IDR',
+		quantity    INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+		held        INTEGER NOT NULL DEFAULT 0 CHECK (held >= 0),
+		closed      INTEGER NOT NULL DEFAULT 0
+	);
+	INSERT INTO ticket_types_new (id, event_id, type, price_minor, currency, quantity, held)
+		SELECT id, event_id, type, price_minor, currency, quantity, held FROM ticket_types;
+	UPDATE order_lines SET ticket_id = COALESCE((SELECT t.id FROM ticket_types t JOIN orders o ON o.event_id = t.event_id
+		WHERE o.id = order_lines.order_id AND t.ticket_id = order_lines.ticket_id), ticket_id);
+	UPDATE hold_lines SET ticket_id = COALESCE((SELECT t.id FROM ticket_types t JOIN holds h ON h.event_id = t.event_id
+		WHERE h.id = hold_lines.hold_id AND t.ticket_id = hold_lines.ticket_id), ticket_id);
+	DROP TABLE ticket_types;
+	ALTER TABLE ticket_types_new RENAME TO ticket_types;
+	CREATE INDEX ticket_types_event_id ON ticket_types(event_id);`,
+
+	// 12: the event start and end are unix millisecond with an IANA time zone. the old date had no
+	// zone, it is read in the default zone and the end is set to the start because it was never saved
+	`ALTER TABLE events ADD COLUMN start_at INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE events ADD COLUMN end_at INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE events ADD COLUMN timezone TEXT NOT NULL DEFAULT '` + domain.DefaultTimeZone + `';
+	UPDATE events SET start_at = ` + oldDateMillis("date") + `, end_at = ` + oldDateMillis("date") + `;
+	ALTER TABLE events DROP COLUMN date;
+	CREATE INDEX events_start_at ON events(start_at);
+	ALTER TABLE orders ADD COLUMN event_start INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN event_end INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN event_timezone TEXT NOT NULL DEFAULT '` + domain.DefaultTimeZone + `';
+	UPDATE orders SET event_start = ` + oldDateMillis("event_date") + `, event_end = ` + oldDateMillis("event_date") + `;
+	ALTER TABLE orders DROP COLUMN event_date;`,
+
+	// 13: sale window of the ticket type in unix millisecond, 0 mean no limit
+	`ALTER TABLE ticket_types ADD COLUMN sale_start INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE ticket_types ADD COLUMN sale_end INTEGER NOT NULL DEFAULT 0;`,
+
+	// 14: purchase cap of the ticket type, 0 mean no limit
+	`ALTER TABLE ticket_types ADD COLUMN max_per_order INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE ticket_types ADD COLUMN max_per_user INTEGER NOT NULL DEFAULT 0;`,
+
+	// 15: promo code and its redemption, the order keep its price before and after the discount
+	`CREATE TABLE promos (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		code         TEXT NOT NULL UNIQUE,
+		kind         TEXT NOT NULL,
+		percent      INTEGER NOT NULL DEFAULT 0,
+		amount_minor INTEGER NOT NULL DEFAULT 0,
+		currency     TEXT NOT NULL DEFAULT 'IDR',
+		event_id     INTEGER NOT NULL DEFAULT 0,
+		max_uses     INTEGER NOT NULL DEFAULT 0,
+		max_per_user INTEGER NOT NULL DEFAULT 0,
+		valid_from   INTEGER NOT NULL DEFAULT 0,
+		valid_until  INTEGER NOT NULL DEFAULT 0,
+		created_by   INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE promo_tickets (
+		promo_id  INTEGER NOT NULL REFERENCES promos(id) ON DELETE CASCADE,
+		ticket_id INTEGER NOT NULL,
+		PRIMARY KEY (promo_id, ticket_id)
+	);
+	CREATE TABLE promo_redemptions (
+		order_id       INTEGER PRIMARY KEY,
+		promo_id       INTEGER NOT NULL REFERENCES promos(id),
+		user_id        INTEGER NOT NULL,
+		discount_minor INTEGER NOT NULL DEFAULT 0,
+		currency       TEXT NOT NULL DEFAULT 'IDR',
+		released       INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX promo_redemptions_promo_id ON promo_redemptions(promo_id, user_id);
+	ALTER TABLE orders ADD COLUMN subtotal_minor INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN discount_minor INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN promo_code TEXT NOT NULL DEFAULT '';
+	ALTER TABLE holds ADD COLUMN promo_code TEXT NOT NULL DEFAULT '';
+	UPDATE orders SET subtotal_minor = total_minor;`,
+
+	// 16: platform fee and tax of every currency, the order keep the fee and tax it was charged
+	`CREATE TABLE pricings (
+		currency         TEXT PRIMARY KEY,
+		ticket_fee_minor INTEGER NOT NULL DEFAULT 0,
+		order_fee_minor  INTEGER NOT NULL DEFAULT 0,
+		tax_rate_bp      INTEGER NOT NULL DEFAULT 0,
+		tax_included     INTEGER NOT NULL DEFAULT 0,
+		tax_on_fee       INTEGER NOT NULL DEFAULT 0,
+		rounding         TEXT NOT NULL DEFAULT '',
+		round_to         INTEGER NOT NULL DEFAULT 0
+	);
+	ALTER TABLE orders ADD COLUMN fee_minor INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN tax_minor INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN tax_rate_bp INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN tax_included INTEGER NOT NULL DEFAULT 0;`,
+
+	// 17: seat map, the order and the hold keep the seat they took
+	`ALTER TABLE ticket_types ADD COLUMN seated INTEGER NOT NULL DEFAULT 0;
+	CREATE TABLE seats (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id  INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+		ticket_id INTEGER NOT NULL,
+		section   TEXT NOT NULL,
+		row_label TEXT NOT NULL,
+		number    INTEGER NOT NULL,
+		status    TEXT NOT NULL,
+		hold_id   INTEGER NOT NULL DEFAULT 0,
+		order_id  INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (event_id, section, row_label, number)
+	);
+	CREATE TABLE order_seats (
+		order_id  INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		seat_id   INTEGER NOT NULL,
+		ticket_id INTEGER NOT NULL,
+		section   TEXT NOT NULL,
+		row_label TEXT NOT NULL,
+		number    INTEGER NOT NULL,
+		status    TEXT NOT NULL,
+		PRIMARY KEY (order_id, seat_id)
+	);
+	CREATE TABLE hold_seats (
+		hold_id INTEGER NOT NULL REFERENCES holds(id) ON DELETE CASCADE,
+		seat_id INTEGER NOT NULL,
+		PRIMARY KEY (hold_id, seat_id)
+	);`,
+
+	// 18: waitlist of sold out ticket type
+	`CREATE TABLE waitlist (
+		id               INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id          INTEGER NOT NULL,
+		event_id         INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+		ticket_id        INTEGER NOT NULL,
+		quantity         INTEGER NOT NULL,
+		status           TEXT NOT NULL,
+		joined_at        INTEGER NOT NULL,
+		hold_id          INTEGER NOT NULL DEFAULT 0,
+		offer_expires_at INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX waitlist_ticket ON waitlist (ticket_id, status);`,
+
+	// 19: waiting room in front of a high demand sale and the queue of its users
+	`CREATE TABLE waiting_rooms (
+		event_id             INTEGER PRIMARY KEY REFERENCES events(id) ON DELETE CASCADE,
+		active               INTEGER NOT NULL,
+		admit_per_minute     INTEGER NOT NULL,
+		admit_window_seconds INTEGER NOT NULL,
+		last_admit_at        INTEGER NOT NULL
+	);
+	CREATE TABLE queue_entries (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		event_id    INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+		user_id     INTEGER NOT NULL,
+		token       TEXT NOT NULL UNIQUE,
+		status      TEXT NOT NULL,
+		joined_at   INTEGER NOT NULL,
+		admitted_at INTEGER NOT NULL DEFAULT 0,
+		expires_at  INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX queue_event_status ON queue_entries (event_id, status, id);
+	CREATE INDEX queue_user ON queue_entries (event_id, user_id);`,
+
+	// 20: public number of the order, the old order get a random one
+	`ALTER TABLE orders ADD COLUMN number TEXT NOT NULL DEFAULT '';
+	UPDATE orders SET number = 'ORD-' || upper(hex(randomblob(8)));
+	CREATE UNIQUE INDEX orders_number ON orders (number);`,
+
+	// 21: one ticket per person of a paid order. the paid order get its ticket now, a seat that is
+	// still sold get one ticket and the rest of the line one ticket per quantity that isn't refunded
+	`CREATE TABLE issued_tickets (
+		id             INTEGER PRIMARY KEY AUTOINCREMENT,
+		code           TEXT NOT NULL UNIQUE,
+		order_id       INTEGER NOT NULL,
+		event_id       INTEGER NOT NULL,
+		event_name     TEXT NOT NULL,
+		ticket_id      INTEGER NOT NULL,
+		type           TEXT NOT NULL,
+		seat_id        INTEGER NOT NULL DEFAULT 0,
+		seat           TEXT NOT NULL DEFAULT '',
+		user_id        INTEGER NOT NULL,
+		holder_name    TEXT NOT NULL,
+		status         TEXT NOT NULL,
+		issued_at      INTEGER NOT NULL,
+		used_at        INTEGER NOT NULL DEFAULT 0,
+		transferred_to INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX issued_tickets_user ON issued_tickets (user_id);
+	CREATE INDEX issued_tickets_order ON issued_tickets (order_id);
+	INSERT INTO issued_tickets (code, order_id, event_id, event_name, ticket_id, type, seat_id, seat, user_id, holder_name, status, issued_at)
+		SELECT upper(hex(randomblob(10))), o.id, o.event_id, o.event_name, s.ticket_id, l.type, s.seat_id, s.section || '-' || s.row_label || '-' || s.number,
+			o.user_id, o.user_name, 'VALID', CAST(strftime('%s', 'now') AS INTEGER) * 1000
+		FROM orders o JOIN order_seats s ON s.order_id = o.id AND s.status = 'SOLD'
+		JOIN order_lines l ON l.order_id = o.id AND l.ticket_id = s.ticket_id
+		WHERE o.status IN ('FULFILLED', 'REFUNDED');
+	WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < (SELECT COALESCE(MAX(quantity), 0) FROM order_lines))
+	INSERT INTO issued_tickets (code, order_id, event_id, event_name, ticket_id, type, user_id, holder_name, status, issued_at)
+		SELECT upper(hex(randomblob(10))), o.id, o.event_id, o.event_name, l.ticket_id, l.type, o.user_id, o.user_name, 'VALID', CAST(strftime('%s', 'now') AS INTEGER) * 1000
+		FROM orders o JOIN order_lines l ON l.order_id = o.id JOIN n ON n.i <= l.quantity - l.refunded
+		WHERE o.status IN ('FULFILLED', 'REFUNDED')
+		AND NOT EXISTS (SELECT 1 FROM order_seats s WHERE s.order_id = o.id AND s.ticket_id = l.ticket_id)
+		ORDER BY o.id, l.id, n.i;`,
+
+	// 22: the payment of the simulated gateway, so a restart doesn't forget it
+	`CREATE TABLE simulated_payments (
+		reference       TEXT PRIMARY KEY,
+		method          TEXT NOT NULL,
+		order_id        INTEGER NOT NULL,
+		amount_minor    INTEGER NOT NULL,
+		currency        TEXT NOT NULL,
+		status          TEXT NOT NULL,
+		instruction     TEXT NOT NULL DEFAULT '',
+		refunded_minor  INTEGER NOT NULL DEFAULT 0,
+		silent          INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX simulated_payments_order ON simulated_payments (order_id);`,
+
+	// 23: the order a request saved before it ended with a server error, the retry get that order
+	`ALTER TABLE idempotency_keys ADD COLUMN order_number TEXT NOT NULL DEFAULT '';`,
+}
+
+// sql that turn an old "02-Jan-2006 15:04:05" date column into unix millisecond in Asia/Jakarta (UTC+7
+// without daylight saving), a date that can't be read become 0
+func oldDateMillis(column string) string {
+	return `COALESCE(CAST(strftime('%s', substr(` + column + `, 8, 4) || '-' ||
+		CASE substr(` + column + `, 4, 3) WHEN 'Jan' THEN '01' WHEN 'Feb' THEN '02' WHEN 'Mar' THEN '03' WHEN 'Apr' THEN '04'
+		WHEN 'May' THEN '05' WHEN 'Jun' THEN '06' WHEN 'Jul' THEN '07' WHEN 'Aug' THEN '08' WHEN 'Sep' THEN '09'
+		WHEN 'Oct' THEN '10' WHEN 'Nov' THEN '11' WHEN 'Dec' THEN '12' END || '-' ||
+		substr(` + column + `, 1, 2) || ' ' || substr(` + column + `, 13, 8), '-7 hours') AS INTEGER) * 1000, 0)`
+}
+
+// open the sqlite file and bring the schema up to date
+func OpenSqlite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// sqlite only allow one writer, so keep one connection and let the transaction queue on it
+	db.SetMaxOpenConns(1)
+
+	if err := Migrate(db, context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// run every migration that is not applied yet
+func Migrate(db *sql.DB, kontek context.Context) error {
+	if _, err := db.ExecContext(kontek, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return err
+	}
+
+	var version int
+	if err := db.QueryRowContext(kontek, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		err := inSqlTx(db, kontek, func(kontek context.Context) error {
+			tx := executor(db, kontek)
+			if _, err := tx.ExecContext(kontek, migrations[i]); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(kontek, `INSERT INTO schema_migrations (version) VALUES (?)`, i+1)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// the part of *sql.DB and *sql.Tx that the repo use
+type sqlExecutor interface {
+	ExecContext(kontek context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(kontek context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(kontek context.Context, query string, args ...any) *sql.Row
+}
+
+// use the transaction from kontek if there is one, if not use the db
+func executor(db *sql.DB, kontek context.Context) sqlExecutor {
+	if tx, ok := kontek.Value(domain.Key("sqltx")).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// run fn inside a transaction, join the one in kontek when it already exist
+func inSqlTx(db *sql.DB, kontek context.Context, fn func(kontek context.Context) error) error {
+	if _, ok := kontek.Value(domain.Key("sqltx")).(*sql.Tx); ok {
+		return fn(kontek)
+	}
+
+	tx, err := db.BeginTx(kontek, nil)
+	if err != nil {
+		return err
+	}
+	var done []func()
+	kontek = context.WithValue(kontek, domain.Key("sqltxdone"), &done)
+	if err := fn(context.WithValue(kontek, domain.Key("sqltx"), tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, fn := range done {
+		fn()
+	}
+	return nil
+}
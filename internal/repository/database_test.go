@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"pemesananTiketOnlineGo/internal/domain"
+	"testing"
+)
+
+// every migration apply to an empty file once, opening the file again or migrating again change nothing
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "migrate.db")
+	kontek := context.Background()
+
+	db, err := OpenSqlite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMigrations(t, db)
+	user, err := NewUserRepoSql(db).CreateUser(&domain.User{Name: "kept"}, kontek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = OpenSqlite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	checkMigrations(t, db)
+	if err := Migrate(db, kontek); err != nil {
+		t.Fatal(err)
+	}
+	checkMigrations(t, db)
+	if _, err := NewUserRepoSql(db).GetUserByID(user.ID, kontek); err != nil {
+		t.Errorf("user saved before the reopen is gone %v", err)
+	}
+}
+
+// every migration is recorded exactly once
+func checkMigrations(t *testing.T, db *sql.DB) {
+	t.Helper()
+	var count, version int
+	if err := db.QueryRow(`SELECT COUNT(*), COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&count, &version); err != nil {
+		t.Fatal(err)
+	}
+	if count != len(migrations) || version != len(migrations) {
+		t.Errorf("%d migration recorded up to version %d, want %d", count, version, len(migrations))
+	}
+}
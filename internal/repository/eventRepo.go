@@ -2,21 +2,37 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"pemesananTiketOnlineGo/internal/domain"
+	"slices"
 	"sync"
+	"time"
 )
 
-// make event db with map
+// make event db with map, the ticket type is kept inside its event. every event has its own lock
+// so buying the ticket of one event never wait for another event, the map lock is only taken for
+// writing when an event or a ticket type is added or removed
 type EventRepo struct {
-	Events map[int]domain.Event
-	mutek  *sync.Mutex
+	Events map[int]*eventSlot
+	// the event of every ticket type
+	tickets      map[int]int
+	lastEventID  *int
+	lastTicketID *int
+	mutek        *sync.RWMutex
+}
+
+// one event with its own lock, it is always taken after the map lock
+type eventSlot struct {
+	event domain.Event
+	mutek sync.RWMutex
 }
 
 func NewEventRepo() EventRepoInterface {
 	return EventRepo{
-		Events: map[int]domain.Event{},
-		mutek:  &sync.Mutex{},
+		Events:       map[int]*eventSlot{},
+		tickets:      map[int]int{},
+		lastEventID:  new(int),
+		lastTicketID: new(int),
+		mutek:        &sync.RWMutex{},
 	}
 }
 
@@ -27,8 +43,16 @@ type EventRepoInterface interface {
 	UpdateEvent
 	DeleteEvent
 	GetAllEvents
+	GetEventsByDate
 	DecrementTicketStock
+	IncrementTicketStock
 	CheckTotalValue
+	HoldTicketStock
+	ReleaseTicketStock
+	ConfirmTicketStock
+	CreateTicket
+	GetTicketByID
+	UpdateTicket
 }
 type CreateEvent interface {
 	CreateEvent(event *domain.Event, kontek context.Context) (*domain.Event, error)
@@ -48,11 +72,35 @@ type DeleteEvent interface {
 type GetAllEvents interface {
 	GetAllEvents(kontek context.Context) ([]domain.Event, error)
 }
+type GetEventsByDate interface {
+	GetEventsByDate(from, to time.Time, kontek context.Context) ([]domain.Event, error)
+}
 type DecrementTicketStock interface {
-	DecrementTicketStock(eventID int, tickets []domain.Ticket, ctx context.Context) error
+	DecrementTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error
+}
+type IncrementTicketStock interface {
+	IncrementTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error
 }
 type CheckTotalValue interface {
-	CheckTotalValue(eventID int, tickets []domain.Ticket, ctx context.Context) (float64, error)
+	CheckTotalValue(eventID int, tickets []domain.TicketLine, ctx context.Context) (domain.Money, error)
+}
+type HoldTicketStock interface {
+	HoldTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error
+}
+type ReleaseTicketStock interface {
+	ReleaseTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error
+}
+type ConfirmTicketStock interface {
+	ConfirmTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error
+}
+type CreateTicket interface {
+	CreateTicket(ticket *domain.Ticket, kontek context.Context) (*domain.Ticket, error)
+}
+type GetTicketByID interface {
+	GetTicketByID(id int, kontek context.Context) (*domain.Ticket, error)
+}
+type UpdateTicket interface {
+	UpdateTicket(ticket *domain.Ticket, kontek context.Context) error
 }
 
 func (repo EventRepo) CreateEvent(event *domain.Event, kontek context.Context) (*domain.Event, error) {
@@ -62,65 +110,80 @@ func (repo EventRepo) CreateEvent(event *domain.Event, kontek context.Context) (
 	case <-kontek.Done():
 		return nil, kontek.Err()
 	default:
-		for _, value := range repo.Events {
-			if value.Name == event.Name {
-				return nil, errors.New("EVENT WITH THAT NAME ALREADY EXIST")
+		// nobody hold an event lock while the map is locked for writing
+		for _, slot := range repo.Events {
+			if slot.event.Name == event.Name {
+				return nil, domain.ErrEventNameExist
 			}
 		}
 
-		if repo.Events == nil || len(repo.Events) == 0 {
-			event.ID = 1
-		} else {
-			event.ID = repo.Events[len(repo.Events)].ID + 1
+		// a deleted event never give its ID to a new one
+		*repo.lastEventID++
+		event.ID = *repo.lastEventID
+		// new event doesn't have any held ticket yet, every ticket type get its own ID
+		tickets := make([]domain.Ticket, 0, len(event.Ticket))
+		for _, ticket := range event.Ticket {
+			*repo.lastTicketID++
+			ticket.ID = *repo.lastTicketID
+			ticket.EventID = event.ID
+			ticket.Held = 0
+			tickets = append(tickets, ticket)
+			repo.tickets[ticket.ID] = event.ID
 		}
-		repo.Events[event.ID] = *event
+		event.Ticket = tickets
+		repo.Events[event.ID] = &eventSlot{event: *event}
 		return event, nil
 	}
 }
 
 func (repo EventRepo) GetEventByID(id int, kontek context.Context) (*domain.Event, error) {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
 	select {
 	case <-kontek.Done():
 		return nil, kontek.Err()
 	default:
-		for _, event := range repo.Events {
-			if event.ID == id {
-				return &event, nil
-			}
+		slot, exist := repo.Events[id]
+		if !exist {
+			return nil, domain.ErrEventNotFound
 		}
-		return nil, errors.New("THERE'S NO EVENT WITH THAT ID🤬🚨🤬🚨")
+		event := slot.read()
+		return &event, nil
 	}
 }
 
 func (repo EventRepo) GetEventByName(name string, kontek context.Context) (*domain.Event, error) {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
 	select {
 	case <-kontek.Done():
 		return nil, kontek.Err()
 	default:
-		for _, event := range repo.Events {
-			if event.Name == name {
+		for _, slot := range repo.Events {
+			if event := slot.read(); event.Name == name {
 				return &event, nil
 			}
 		}
-		return nil, errors.New("THERE'S NO EVENT WITH THAT NAME🤬🚨🤬🚨")
+		return nil, domain.ErrEventNameNotFound
 	}
 }
 
 func (repo EventRepo) UpdateEvent(event *domain.Event, kontek context.Context) error {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
 	select {
 	case <-kontek.Done():
 		return kontek.Err()
 	default:
-		if _, exist := repo.Events[event.ID]; !exist {
-			return errors.New("THERE'S NO EVENT WITH THAT ID🤬🤬🤬🚨🚨")
+		slot, exist := repo.Events[event.ID]
+		if !exist {
+			return domain.ErrEventNotFound
 		}
-		repo.Events[event.ID] = *event
+		slot.mutek.Lock()
+		defer slot.mutek.Unlock()
+		// the ticket type is changed with its own func, not with the event
+		event.Ticket = slot.event.Ticket
+		slot.event = *event
 		return nil
 	}
 }
@@ -132,76 +195,339 @@ func (repo EventRepo) DeleteEvent(id int, kontek context.Context) error {
 	case <-kontek.Done():
 		return kontek.Err()
 	default:
-		if _, exist := repo.Events[id]; !exist {
-			return errors.New("THERE'S NO EVENT WITH THAT ID🤬🚨🤬🚨")
+		slot, exist := repo.Events[id]
+		if !exist {
+			return domain.ErrEventNotFound
+		}
+		for _, ticket := range slot.event.Ticket {
+			delete(repo.tickets, ticket.ID)
 		}
 		delete(repo.Events, id)
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			repo.Events[id] = slot
+			for _, ticket := range slot.event.Ticket {
+				repo.tickets[ticket.ID] = id
+			}
+		})
 		return nil
 	}
 }
 
 func (repo EventRepo) GetAllEvents(kontek context.Context) ([]domain.Event, error) {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
 	select {
 	case <-kontek.Done():
 		return nil, kontek.Err()
 	default:
 		events := make([]domain.Event, 0, len(repo.Events))
-		for _, event := range repo.Events {
+		for _, slot := range repo.Events {
+			events = append(events, slot.read())
+		}
+		return events, nil
+	}
+}
+
+// event that start from the from time until before the to time, a zero time mean no limit
+func (repo EventRepo) GetEventsByDate(from, to time.Time, kontek context.Context) ([]domain.Event, error) {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		events := []domain.Event{}
+		for _, slot := range repo.Events {
+			event := slot.read()
+			if !from.IsZero() && event.Start.Before(from) {
+				continue
+			}
+			if !to.IsZero() && !event.Start.Before(to) {
+				continue
+			}
 			events = append(events, event)
 		}
 		return events, nil
 	}
 }
 
-func (repo EventRepo) DecrementTicketStock(eventID int, tickets []domain.Ticket, ctx context.Context) error {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
-	event, exists := repo.Events[eventID]
+func (repo EventRepo) DecrementTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error {
+	err := repo.changeTicketStock(eventID, tickets, ctx, func(eventTicket *domain.Ticket, quantity int) error {
+		if err := eventTicket.CheckSale(time.Now()); err != nil {
+			return err
+		}
+		if eventTicket.Quantity < quantity {
+			return domain.ErrNotEnoughStock
+		}
+		eventTicket.Quantity -= quantity
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	onRollback(ctx, func() {
+		repo.changeTicketStock(eventID, tickets, context.Background(), func(eventTicket *domain.Ticket, quantity int) error {
+			eventTicket.Quantity += quantity
+			return nil
+		})
+	})
+	return nil
+}
+
+// put the ticket back to the stock when an order is cancelled or refunded
+func (repo EventRepo) IncrementTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error {
+	err := repo.changeTicketStock(eventID, tickets, ctx, func(eventTicket *domain.Ticket, quantity int) error {
+		eventTicket.Quantity += quantity
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	onRollback(ctx, func() {
+		repo.changeTicketStock(eventID, tickets, context.Background(), func(eventTicket *domain.Ticket, quantity int) error {
+			eventTicket.Quantity -= quantity
+			return nil
+		})
+	})
+	return nil
+}
+
+func (repo EventRepo) CheckTotalValue(eventID int, tickets []domain.TicketLine, ctx context.Context) (domain.Money, error) {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	slot, exists := repo.Events[eventID]
 	if !exists {
-		return errors.New("event not found")
+		return domain.Money{}, domain.ErrEventNotFound
 	}
 
-	var updatedTickets []domain.Ticket
-	// var total float64
-	for _, eventTicket := range event.Ticket {
-		for _, ticket := range tickets {
-			if eventTicket.ID == ticket.ID || eventTicket.Type == ticket.Type {
-				if eventTicket.Quantity < ticket.Quantity {
-					return errors.New("not enough ticket stock")
-				}
-				eventTicket.Quantity -= ticket.Quantity
-				// total += eventTicket.Price * float64(ticket.Quantity)
+	event := slot.read()
+	return ticketsValue(&event, tickets)
+}
+
+// move ticket from available to held for a reservation
+func (repo EventRepo) HoldTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error {
+	err := repo.changeTicketStock(eventID, tickets, ctx, func(eventTicket *domain.Ticket, quantity int) error {
+		if err := eventTicket.CheckSale(time.Now()); err != nil {
+			return err
+		}
+		if eventTicket.Quantity < quantity {
+			return domain.ErrNotEnoughStock
+		}
+		eventTicket.Quantity -= quantity
+		eventTicket.Held += quantity
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	onRollback(ctx, func() {
+		repo.changeTicketStock(eventID, tickets, context.Background(), func(eventTicket *domain.Ticket, quantity int) error {
+			eventTicket.Quantity += quantity
+			eventTicket.Held -= quantity
+			return nil
+		})
+	})
+	return nil
+}
+
+// move held ticket back to available when the reservation is released or expired
+func (repo EventRepo) ReleaseTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error {
+	err := repo.changeTicketStock(eventID, tickets, ctx, func(eventTicket *domain.Ticket, quantity int) error {
+		if eventTicket.Held < quantity {
+			return domain.ErrNotEnoughHeld
+		}
+		eventTicket.Held -= quantity
+		eventTicket.Quantity += quantity
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	onRollback(ctx, func() {
+		repo.changeTicketStock(eventID, tickets, context.Background(), func(eventTicket *domain.Ticket, quantity int) error {
+			eventTicket.Held += quantity
+			eventTicket.Quantity -= quantity
+			return nil
+		})
+	})
+	return nil
+}
+
+// held ticket is sold when the reservation is paid
+func (repo EventRepo) ConfirmTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error {
+	err := repo.changeTicketStock(eventID, tickets, ctx, func(eventTicket *domain.Ticket, quantity int) error {
+		if eventTicket.Held < quantity {
+			return domain.ErrNotEnoughHeld
+		}
+		eventTicket.Held -= quantity
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	onRollback(ctx, func() {
+		repo.changeTicketStock(eventID, tickets, context.Background(), func(eventTicket *domain.Ticket, quantity int) error {
+			eventTicket.Held += quantity
+			return nil
+		})
+	})
+	return nil
+}
+
+// apply change to every event ticket that is requested, nothing is saved when one of them fail.
+// only the lock of that event is taken for writing
+func (repo EventRepo) changeTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context, change func(eventTicket *domain.Ticket, quantity int) error) error {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	slot, exists := repo.Events[eventID]
+	if !exists {
+		return domain.ErrEventNotFound
+	}
+	slot.mutek.Lock()
+	defer slot.mutek.Unlock()
+
+	requested, err := requestedQuantity(&slot.event, tickets)
+	if err != nil {
+		return err
+	}
+	// the event given out before still point to the old list, so make a new one
+	updatedTickets := make([]domain.Ticket, 0, len(slot.event.Ticket))
+	for _, eventTicket := range slot.event.Ticket {
+		if quantity, exist := requested[eventTicket.ID]; exist {
+			if err := change(&eventTicket, quantity); err != nil {
+				return err
 			}
 		}
 		updatedTickets = append(updatedTickets, eventTicket)
 	}
 
-	event.Ticket = updatedTickets
-	repo.Events[event.ID] = event
+	slot.event.Ticket = updatedTickets
 	return nil
 }
 
-func (repo EventRepo) CheckTotalValue(eventID int, tickets []domain.Ticket, ctx context.Context) (float64, error) {
+// add a ticket type to an existing event
+func (repo EventRepo) CreateTicket(ticket *domain.Ticket, kontek context.Context) (*domain.Ticket, error) {
 	repo.mutek.Lock()
 	defer repo.mutek.Unlock()
-	event, exists := repo.Events[eventID]
-	if !exists {
-		return 0, errors.New("event not found")
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		slot, exist := repo.Events[ticket.EventID]
+		if !exist {
+			return nil, domain.ErrEventNotFound
+		}
+		*repo.lastTicketID++
+		ticket.ID = *repo.lastTicketID
+		ticket.Held = 0
+		// the event given out before still point to the old list, so make a new one
+		slot.event.Ticket = append(slices.Clone(slot.event.Ticket), *ticket)
+		repo.tickets[ticket.ID] = slot.event.ID
+		return ticket, nil
 	}
+}
 
-	var total float64
-	for _, eventTicket := range event.Ticket {
-		for _, ticket := range tickets {
-			if eventTicket.ID == ticket.ID || eventTicket.Type == ticket.Type {
-				if eventTicket.Quantity < ticket.Quantity {
-					return 0, errors.New("NOT ENOUGH TICKET STOCK🤬🚨🤬🚨")
-				}
-				total += (eventTicket.Price) * float64(ticket.Quantity)
+func (repo EventRepo) GetTicketByID(id int, kontek context.Context) (*domain.Ticket, error) {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		slot, exist := repo.Events[repo.tickets[id]]
+		if !exist {
+			return nil, domain.ErrTicketNotFound
+		}
+		for _, ticket := range slot.read().Ticket {
+			if ticket.ID == id {
+				return &ticket, nil
 			}
 		}
+		return nil, domain.ErrTicketNotFound
 	}
+}
+
+// the name, price, available quantity and closed flag can change, the event and the held ticket stay
+func (repo EventRepo) UpdateTicket(ticket *domain.Ticket, kontek context.Context) error {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		slot, exist := repo.Events[repo.tickets[ticket.ID]]
+		if !exist {
+			return domain.ErrTicketNotFound
+		}
+		slot.mutek.Lock()
+		defer slot.mutek.Unlock()
+		i := slices.IndexFunc(slot.event.Ticket, func(old domain.Ticket) bool { return old.ID == ticket.ID })
+		if i < 0 {
+			return domain.ErrTicketNotFound
+		}
+		ticket.EventID = slot.event.ID
+		ticket.Held = slot.event.Ticket[i].Held
+		slot.event.Ticket = slices.Clone(slot.event.Ticket)
+		slot.event.Ticket[i] = *ticket
+		return nil
+	}
+}
+
+// copy of the event, its ticket list is never changed in place so it can be shared
+func (slot *eventSlot) read() domain.Event {
+	slot.mutek.RLock()
+	defer slot.mutek.RUnlock()
+	return slot.event
+}
 
+// sum the requested quantity of every ticket type, each of them must be sold by the event
+func requestedQuantity(event *domain.Event, tickets []domain.TicketLine) (map[int]int, error) {
+	sold := map[int]bool{}
+	for _, eventTicket := range event.Ticket {
+		sold[eventTicket.ID] = true
+	}
+	requested := map[int]int{}
+	for _, ticket := range tickets {
+		if !sold[ticket.ID] {
+			return nil, domain.ErrTicketNotInEvent
+		}
+		requested[ticket.ID] += ticket.Quantity
+	}
+	return requested, nil
+}
+
+// price of the requested ticket, the stock and the sale window are checked but not changed
+func ticketsValue(event *domain.Event, tickets []domain.TicketLine) (domain.Money, error) {
+	requested, err := requestedQuantity(event, tickets)
+	if err != nil {
+		return domain.Money{}, err
+	}
+	var total domain.Money
+	for _, eventTicket := range event.Ticket {
+		quantity, exist := requested[eventTicket.ID]
+		if !exist {
+			continue
+		}
+		if err := eventTicket.CheckSale(time.Now()); err != nil {
+			return domain.Money{}, err
+		}
+		if eventTicket.Quantity < quantity {
+			return domain.Money{}, domain.ErrNotEnoughStock
+		}
+		price, err := eventTicket.Price.Mul(quantity)
+		if err != nil {
+			return domain.Money{}, err
+		}
+		if total, err = total.Add(price); err != nil {
+			return domain.Money{}, err
+		}
+	}
 	return total, nil
 }
@@ -0,0 +1,396 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pemesananTiketOnlineGo/internal/domain"
+	"time"
+)
+
+// event db with sqlite
+type EventRepoSql struct {
+	DB *sql.DB
+}
+
+func NewEventRepoSql(db *sql.DB) EventRepoInterface {
+	return EventRepoSql{
+		DB: db,
+	}
+}
+
+func (repo EventRepoSql) CreateEvent(event *domain.Event, kontek context.Context) (*domain.Event, error) {
+	err := inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+
+		var exist int
+		if err := db.QueryRowContext(kontek, `SELECT COUNT(1) FROM events WHERE name = ?`, event.Name).Scan(&exist); err != nil {
+			return err
+		}
+		if exist > 0 {
+			return domain.ErrEventNameExist
+		}
+
+		refundable, cutoffHours := refundColumns(event.Refund)
+		result, err := db.ExecContext(kontek, `INSERT INTO events (name, start_at, end_at, timezone, description, location, refundable, refund_cutoff_hours, organizer_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			event.Name, event.Start.UnixMilli(), event.End.UnixMilli(), event.TimeZone, event.Description, event.Location, refundable, cutoffHours, event.OrganizerID)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		event.ID = int(id)
+
+		// new event doesn't have any held ticket yet, every ticket type get its own ID
+		tickets := make([]domain.Ticket, 0, len(event.Ticket))
+		for _, ticket := range event.Ticket {
+			ticket.EventID = event.ID
+			ticket.Held = 0
+			if err := repo.insertTicket(&ticket, kontek); err != nil {
+				return err
+			}
+			tickets = append(tickets, ticket)
+		}
+		event.Ticket = tickets
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (repo EventRepoSql) GetEventByID(id int, kontek context.Context) (*domain.Event, error) {
+	events, err := repo.queryEvents(kontek, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, domain.ErrEventNotFound
+	}
+	return &events[0], nil
+}
+
+func (repo EventRepoSql) GetEventByName(name string, kontek context.Context) (*domain.Event, error) {
+	events, err := repo.queryEvents(kontek, `WHERE name = ?`, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, domain.ErrEventNameNotFound
+	}
+	return &events[0], nil
+}
+
+// the ticket type is changed with its own func, not with the event
+func (repo EventRepoSql) UpdateEvent(event *domain.Event, kontek context.Context) error {
+	return inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+
+		refundable, cutoffHours := refundColumns(event.Refund)
+		result, err := db.ExecContext(kontek, `UPDATE events SET name = ?, start_at = ?, end_at = ?, timezone = ?, description = ?, location = ?, refundable = ?, refund_cutoff_hours = ?, organizer_id = ? WHERE id = ?`,
+			event.Name, event.Start.UnixMilli(), event.End.UnixMilli(), event.TimeZone, event.Description, event.Location, refundable, cutoffHours, event.OrganizerID, event.ID)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return domain.ErrEventNotFound
+		}
+
+		old, err := repo.GetEventByID(event.ID, kontek)
+		if err != nil {
+			return err
+		}
+		event.Ticket = old.Ticket
+		return nil
+	})
+}
+
+func (repo EventRepoSql) DeleteEvent(id int, kontek context.Context) error {
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `DELETE FROM events WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrEventNotFound
+	}
+	return nil
+}
+
+func (repo EventRepoSql) GetAllEvents(kontek context.Context) ([]domain.Event, error) {
+	return repo.queryEvents(kontek, ``)
+}
+
+// event that start from the from time until before the to time, a zero time mean no limit
+func (repo EventRepoSql) GetEventsByDate(from, to time.Time, kontek context.Context) ([]domain.Event, error) {
+	where, args := `WHERE 1 = 1`, []any{}
+	if !from.IsZero() {
+		where += ` AND start_at >= ?`
+		args = append(args, from.UnixMilli())
+	}
+	if !to.IsZero() {
+		where += ` AND start_at < ?`
+		args = append(args, to.UnixMilli())
+	}
+	return repo.queryEvents(kontek, where, args...)
+}
+
+func (repo EventRepoSql) DecrementTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error {
+	return repo.changeTicketStock(eventID, tickets, ctx, func(eventTicket *domain.Ticket, quantity int) error {
+		if err := eventTicket.CheckSale(time.Now()); err != nil {
+			return err
+		}
+		if eventTicket.Quantity < quantity {
+			return domain.ErrNotEnoughStock
+		}
+		eventTicket.Quantity -= quantity
+		return nil
+	})
+}
+
+func (repo EventRepoSql) CheckTotalValue(eventID int, tickets []domain.TicketLine, ctx context.Context) (domain.Money, error) {
+	event, err := repo.GetEventByID(eventID, ctx)
+	if err != nil {
+		return domain.Money{}, err
+	}
+	return ticketsValue(event, tickets)
+}
+
+// put the ticket back to the stock when an order is cancelled or refunded
+func (repo EventRepoSql) IncrementTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error {
+	return repo.changeTicketStock(eventID, tickets, ctx, func(eventTicket *domain.Ticket, quantity int) error {
+		eventTicket.Quantity += quantity
+		return nil
+	})
+}
+
+// move ticket from available to held for a reservation
+func (repo EventRepoSql) HoldTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error {
+	return repo.changeTicketStock(eventID, tickets, ctx, func(eventTicket *domain.Ticket, quantity int) error {
+		if err := eventTicket.CheckSale(time.Now()); err != nil {
+			return err
+		}
+		if eventTicket.Quantity < quantity {
+			return domain.ErrNotEnoughStock
+		}
+		eventTicket.Quantity -= quantity
+		eventTicket.Held += quantity
+		return nil
+	})
+}
+
+// move held ticket back to available when the reservation is released or expired
+func (repo EventRepoSql) ReleaseTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error {
+	return repo.changeTicketStock(eventID, tickets, ctx, func(eventTicket *domain.Ticket, quantity int) error {
+		if eventTicket.Held < quantity {
+			return domain.ErrNotEnoughHeld
+		}
+		eventTicket.Held -= quantity
+		eventTicket.Quantity += quantity
+		return nil
+	})
+}
+
+// held ticket is sold when the reservation is paid
+func (repo EventRepoSql) ConfirmTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context) error {
+	return repo.changeTicketStock(eventID, tickets, ctx, func(eventTicket *domain.Ticket, quantity int) error {
+		if eventTicket.Held < quantity {
+			return domain.ErrNotEnoughHeld
+		}
+		eventTicket.Held -= quantity
+		return nil
+	})
+}
+
+// apply change to every event ticket that is requested inside one transaction
+func (repo EventRepoSql) changeTicketStock(eventID int, tickets []domain.TicketLine, ctx context.Context, change func(eventTicket *domain.Ticket, quantity int) error) error {
+	return inSqlTx(repo.DB, ctx, func(ctx context.Context) error {
+		event, err := repo.GetEventByID(eventID, ctx)
+		if err != nil {
+			return err
+		}
+
+		requested, err := requestedQuantity(event, tickets)
+		if err != nil {
+			return err
+		}
+		db := executor(repo.DB, ctx)
+		for _, eventTicket := range event.Ticket {
+			quantity, exist := requested[eventTicket.ID]
+			if !exist {
+				continue
+			}
+			if err := change(&eventTicket, quantity); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx, `UPDATE ticket_types SET quantity = ?, held = ? WHERE id = ?`,
+				eventTicket.Quantity, eventTicket.Held, eventTicket.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// add a ticket type to an existing event
+func (repo EventRepoSql) CreateTicket(ticket *domain.Ticket, kontek context.Context) (*domain.Ticket, error) {
+	err := inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		var exist int
+		if err := executor(repo.DB, kontek).QueryRowContext(kontek, `SELECT COUNT(1) FROM events WHERE id = ?`, ticket.EventID).Scan(&exist); err != nil {
+			return err
+		}
+		if exist == 0 {
+			return domain.ErrEventNotFound
+		}
+		ticket.Held = 0
+		return repo.insertTicket(ticket, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+func (repo EventRepoSql) GetTicketByID(id int, kontek context.Context) (*domain.Ticket, error) {
+	row := executor(repo.DB, kontek).QueryRowContext(kontek, `SELECT `+ticketColumns+` FROM ticket_types WHERE id = ?`, id)
+	ticket, err := scanTicket(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrTicketNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// the name, price, available quantity and closed flag can change, the event and the held ticket stay
+func (repo EventRepoSql) UpdateTicket(ticket *domain.Ticket, kontek context.Context) error {
+	return inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		old, err := repo.GetTicketByID(ticket.ID, kontek)
+		if err != nil {
+			return err
+		}
+		ticket.EventID = old.EventID
+		ticket.Held = old.Held
+		_, err = executor(repo.DB, kontek).ExecContext(kontek, `UPDATE ticket_types SET type = ?, price_minor = ?, currency = ?, quantity = ?, closed = ?, sale_start = ?, sale_end = ?, max_per_order = ?, max_per_user = ?, seated = ? WHERE id = ?`,
+			ticket.Type, ticket.Price.Amount, currencyOf(ticket.Price), ticket.Quantity, ticket.Closed, millisOrZero(ticket.SaleStart), millisOrZero(ticket.SaleEnd), ticket.MaxPerOrder, ticket.MaxPerUser, ticket.Seated, ticket.ID)
+		return err
+	})
+}
+
+// save one ticket type and give it its ID
+func (repo EventRepoSql) insertTicket(ticket *domain.Ticket, kontek context.Context) error {
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `INSERT INTO ticket_types (event_id, type, price_minor, currency, quantity, held, closed, sale_start, sale_end, max_per_order, max_per_user, seated) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ticket.EventID, ticket.Type, ticket.Price.Amount, currencyOf(ticket.Price), ticket.Quantity, ticket.Held, ticket.Closed, millisOrZero(ticket.SaleStart), millisOrZero(ticket.SaleEnd), ticket.MaxPerOrder, ticket.MaxPerUser, ticket.Seated)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	ticket.ID = int(id)
+	return nil
+}
+
+// get the events that match the where clause together with their tickets
+func (repo EventRepoSql) queryEvents(kontek context.Context, where string, args ...any) ([]domain.Event, error) {
+	db := executor(repo.DB, kontek)
+
+	rows, err := db.QueryContext(kontek, `SELECT id, name, start_at, end_at, timezone, description, location, refundable, refund_cutoff_hours, organizer_id FROM events `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []domain.Event{}
+	index := map[int]int{}
+	for rows.Next() {
+		var event domain.Event
+		var refund domain.RefundPolicy
+		var start, end int64
+		if err := rows.Scan(&event.ID, &event.Name, &start, &end, &event.TimeZone, &event.Description, &event.Location, &refund.Refundable, &refund.CutoffHours, &event.OrganizerID); err != nil {
+			return nil, err
+		}
+		event.Start, event.End = time.UnixMilli(start), time.UnixMilli(end)
+		if refund != (domain.RefundPolicy{}) {
+			event.Refund = &refund
+		}
+		index[event.ID] = len(events)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(events) == 0 {
+		return events, nil
+	}
+
+	ticketRows, err := db.QueryContext(kontek, `SELECT `+ticketColumns+` FROM ticket_types
+		WHERE event_id IN (SELECT id FROM events `+where+`) ORDER BY event_id, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer ticketRows.Close()
+
+	for ticketRows.Next() {
+		ticket, err := scanTicket(ticketRows)
+		if err != nil {
+			return nil, err
+		}
+		if i, exist := index[ticket.EventID]; exist {
+			events[i].Ticket = append(events[i].Ticket, *ticket)
+		}
+	}
+	if err := ticketRows.Err(); err != nil {
+		return nil, err
+	}
+
+	// every time is shown in the event time zone
+	for i := range events {
+		if err := events[i].InTimeZone(); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// no refund policy is saved as not refundable
+func refundColumns(refund *domain.RefundPolicy) (bool, int) {
+	if refund == nil {
+		return false, 0
+	}
+	return refund.Refundable, refund.CutoffHours
+}
+
+const ticketColumns = `id, event_id, type, price_minor, currency, quantity, held, closed, sale_start, sale_end, max_per_order, max_per_user, seated`
+
+// read one ticket type in the order of ticketColumns
+func scanTicket(row interface{ Scan(dest ...any) error }) (*domain.Ticket, error) {
+	var ticket domain.Ticket
+	var saleStart, saleEnd int64
+	if err := row.Scan(&ticket.ID, &ticket.EventID, &ticket.Type, &ticket.Price.Amount, &ticket.Price.Currency, &ticket.Quantity, &ticket.Held, &ticket.Closed, &saleStart, &saleEnd, &ticket.MaxPerOrder, &ticket.MaxPerUser, &ticket.Seated); err != nil {
+		return nil, err
+	}
+	ticket.SaleStart, ticket.SaleEnd = timeOrNil(saleStart), timeOrNil(saleEnd)
+	return &ticket, nil
+}
+
+// an optional time is saved as unix millisecond, 0 mean nil
+func millisOrZero(at *time.Time) int64 {
+	if at == nil {
+		return 0
+	}
+	return at.UnixMilli()
+}
+
+func timeOrNil(millis int64) *time.Time {
+	if millis == 0 {
+		return nil
+	}
+	at := time.UnixMilli(millis)
+	return &at
+}
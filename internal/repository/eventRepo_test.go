@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"pemesananTiketOnlineGo/internal/domain"
+	"sync"
+	"testing"
+	"time"
+)
+
+// many purchase take the stock of one ticket at the same time, some of them roll back.
+// the stock never go below zero and what is left plus what is kept add up to the start
+func TestDecrementTicketStockParallel(t *testing.T) {
+	cases := []struct {
+		name     string
+		stock    int
+		buyers   int
+		quantity int
+		// every n-th purchase roll back after taking the stock, 0 is never
+		rollback int
+	}{
+		{name: "more buyer than stock", stock: 50, buyers: 80, quantity: 1},
+		{name: "quantity over the rest", stock: 50, buyers: 40, quantity: 3},
+		{name: "rolled back purchase", stock: 50, buyers: 80, quantity: 2, rollback: 3},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				db := openTestDb(t, backend)
+				eventRepo := newTestEventRepo(db)
+				unitOfWork := newTestUnitOfWork(db)
+				kontek := context.Background()
+
+				event, err := eventRepo.CreateEvent(&domain.Event{Name: "Stock", Start: time.Now().Add(24 * time.Hour), End: time.Now().Add(27 * time.Hour), Description: "Stock", Location: "Stock",
+					Ticket: []domain.Ticket{{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: tc.stock}}}, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tickets := []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: tc.quantity}}
+				errRollback := errors.New("rollback")
+
+				var mutek sync.Mutex
+				kept := 0
+				var wg sync.WaitGroup
+				for i := 1; i <= tc.buyers; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						err := unitOfWork.WithinScope(kontek, []string{EventKey(event.ID)}, func(kontek context.Context) error {
+							if err := eventRepo.DecrementTicketStock(event.ID, tickets, kontek); err != nil {
+								return err
+							}
+							if tc.rollback > 0 && i%tc.rollback == 0 {
+								return errRollback
+							}
+							return nil
+						})
+						switch {
+						case err == nil:
+							mutek.Lock()
+							kept += tc.quantity
+							mutek.Unlock()
+						case !errors.Is(err, domain.ErrNotEnoughStock) && !errors.Is(err, errRollback):
+							t.Error(err)
+						}
+					}(i)
+				}
+				wg.Wait()
+
+				stock, err := eventRepo.GetEventByID(event.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				left := stock.Ticket[0].Quantity
+				if left < 0 {
+					t.Errorf("ticket oversold, stock is %d", left)
+				}
+				if left+kept != tc.stock {
+					t.Errorf("stock %d + sold %d != %d", left, kept, tc.stock)
+				}
+				if kept == 0 {
+					t.Error("no purchase took the stock")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"sync"
+	"time"
+)
+
+// make Hold db with map, the ID come from a counter like the order
+type HoldRepo struct {
+	Holds      map[int]domain.Hold
+	lastHoldID *int
+	mutek      *sync.RWMutex
+}
+
+func NewHoldRepo() HoldRepoInterface {
+	return HoldRepo{
+		Holds:      map[int]domain.Hold{},
+		lastHoldID: new(int),
+		mutek:      &sync.RWMutex{},
+	}
+}
+
+type HoldRepoInterface interface {
+	CreateHold
+	GetHoldByID
+	UpdateHold
+	GetExpiredHolds
+	GetHoldsByEventID
+}
+type CreateHold interface {
+	CreateHold(hold *domain.Hold, kontek context.Context) (*domain.Hold, error)
+}
+type GetHoldByID interface {
+	GetHoldByID(id int, kontek context.Context) (*domain.Hold, error)
+}
+type UpdateHold interface {
+	UpdateHold(hold *domain.Hold, kontek context.Context) error
+}
+type GetExpiredHolds interface {
+	GetExpiredHolds(now time.Time, kontek context.Context) ([]domain.Hold, error)
+}
+type GetHoldsByEventID interface {
+	GetHoldsByEventID(eventID int, kontek context.Context) ([]domain.Hold, error)
+}
+
+func (repo HoldRepo) CreateHold(hold *domain.Hold, kontek context.Context) (*domain.Hold, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		*repo.lastHoldID++
+		hold.ID = *repo.lastHoldID
+		repo.Holds[hold.ID] = *hold
+		holdID := hold.ID
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			delete(repo.Holds, holdID)
+		})
+		return hold, nil
+	}
+}
+
+func (repo HoldRepo) GetHoldByID(id int, kontek context.Context) (*domain.Hold, error) {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		hold, exist := repo.Holds[id]
+		if !exist {
+			return nil, domain.ErrHoldNotFound
+		}
+		return &hold, nil
+	}
+}
+
+func (repo HoldRepo) UpdateHold(hold *domain.Hold, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		old, exist := repo.Holds[hold.ID]
+		if !exist {
+			return domain.ErrHoldNotFound
+		}
+		repo.Holds[hold.ID] = *hold
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			repo.Holds[old.ID] = old
+		})
+		return nil
+	}
+}
+
+// func to get every hold that still held but already pass the expiry time
+func (repo HoldRepo) GetExpiredHolds(now time.Time, kontek context.Context) ([]domain.Hold, error) {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		var holds []domain.Hold
+		for _, hold := range repo.Holds {
+			if hold.Status == domain.HoldHeld && !hold.ExpiresAt.After(now) {
+				holds = append(holds, hold)
+			}
+		}
+		return holds, nil
+	}
+}
+
+// func to get every hold of an event, whatever its status
+func (repo HoldRepo) GetHoldsByEventID(eventID int, kontek context.Context) ([]domain.Hold, error) {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		var holds []domain.Hold
+		for _, hold := range repo.Holds {
+			if hold.EventID == eventID {
+				holds = append(holds, hold)
+			}
+		}
+		return holds, nil
+	}
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"pemesananTiketOnlineGo/internal/domain"
+	"time"
+)
+
+// hold db with sqlite
+type HoldRepoSql struct {
+	DB *sql.DB
+}
+
+func NewHoldRepoSql(db *sql.DB) HoldRepoInterface {
+	return HoldRepoSql{
+		DB: db,
+	}
+}
+
+func (repo HoldRepoSql) CreateHold(hold *domain.Hold, kontek context.Context) (*domain.Hold, error) {
+	err := inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+
+		result, err := db.ExecContext(kontek, `INSERT INTO holds (user_id, event_id, status, expires_at, order_id, promo_code) VALUES (?, ?, ?, ?, ?, ?)`,
+			hold.UserID, hold.EventID, hold.Status, hold.ExpiresAt.UnixMilli(), hold.OrderID, hold.PromoCode)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		hold.ID = int(id)
+
+		for _, ticket := range hold.Ticket {
+			if _, err := db.ExecContext(kontek, `INSERT INTO hold_lines (hold_id, ticket_id, quantity) VALUES (?, ?, ?)`,
+				hold.ID, ticket.ID, ticket.Quantity); err != nil {
+				return err
+			}
+		}
+		for _, seatID := range hold.Seats {
+			if _, err := db.ExecContext(kontek, `INSERT INTO hold_seats (hold_id, seat_id) VALUES (?, ?)`, hold.ID, seatID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (repo HoldRepoSql) GetHoldByID(id int, kontek context.Context) (*domain.Hold, error) {
+	holds, err := repo.queryHolds(kontek, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(holds) == 0 {
+		return nil, domain.ErrHoldNotFound
+	}
+	return &holds[0], nil
+}
+
+// the ticket of a hold never change, only the status and the order
+func (repo HoldRepoSql) UpdateHold(hold *domain.Hold, kontek context.Context) error {
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `UPDATE holds SET status = ?, expires_at = ?, order_id = ? WHERE id = ?`,
+		hold.Status, hold.ExpiresAt.UnixMilli(), hold.OrderID, hold.ID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrHoldNotFound
+	}
+	return nil
+}
+
+// func to get every hold that still held but already pass the expiry time
+func (repo HoldRepoSql) GetExpiredHolds(now time.Time, kontek context.Context) ([]domain.Hold, error) {
+	return repo.queryHolds(kontek, `WHERE status = ? AND expires_at <= ?`, domain.HoldHeld, now.UnixMilli())
+}
+
+// func to get every hold of an event, whatever its status
+func (repo HoldRepoSql) GetHoldsByEventID(eventID int, kontek context.Context) ([]domain.Hold, error) {
+	return repo.queryHolds(kontek, `WHERE event_id = ?`, eventID)
+}
+
+// get the holds that match the where clause together with their lines and seats
+func (repo HoldRepoSql) queryHolds(kontek context.Context, where string, args ...any) ([]domain.Hold, error) {
+	db := executor(repo.DB, kontek)
+
+	rows, err := db.QueryContext(kontek, `SELECT id, user_id, event_id, status, expires_at, order_id, promo_code FROM holds `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []domain.Hold{}
+	index := map[int]int{}
+	for rows.Next() {
+		var hold domain.Hold
+		var expiresAt int64
+		if err := rows.Scan(&hold.ID, &hold.UserID, &hold.EventID, &hold.Status, &expiresAt, &hold.OrderID, &hold.PromoCode); err != nil {
+			return nil, err
+		}
+		hold.ExpiresAt = time.UnixMilli(expiresAt)
+		index[hold.ID] = len(holds)
+		holds = append(holds, hold)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(holds) == 0 {
+		return holds, nil
+	}
+
+	lineRows, err := db.QueryContext(kontek, `SELECT hold_id, ticket_id, quantity FROM hold_lines
+		WHERE hold_id IN (SELECT id FROM holds `+where+`) ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		var holdID int
+		var ticket domain.TicketLine
+		if err := lineRows.Scan(&holdID, &ticket.ID, &ticket.Quantity); err != nil {
+			return nil, err
+		}
+		if i, exist := index[holdID]; exist {
+			holds[i].Ticket = append(holds[i].Ticket, ticket)
+		}
+	}
+	if err := lineRows.Err(); err != nil {
+		return nil, err
+	}
+	lineRows.Close()
+
+	seatRows, err := db.QueryContext(kontek, `SELECT hold_id, seat_id FROM hold_seats
+		WHERE hold_id IN (SELECT id FROM holds `+where+`) ORDER BY rowid`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer seatRows.Close()
+
+	for seatRows.Next() {
+		var holdID, seatID int
+		if err := seatRows.Scan(&holdID, &seatID); err != nil {
+			return nil, err
+		}
+		if i, exist := index[holdID]; exist {
+			holds[i].Seats = append(holds[i].Seats, seatID)
+		}
+	}
+	return holds, seatRows.Err()
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+	"sync"
+	"time"
+)
+
+// make Idempotency db with map, the key is scoped to the user
+type IdempotencyRepo struct {
+	Keys  map[string]domain.Idempotency
+	mutek *sync.Mutex
+}
+
+func NewIdempotencyRepo() IdempotencyRepoInterface {
+	return IdempotencyRepo{
+		Keys:  map[string]domain.Idempotency{},
+		mutek: &sync.Mutex{},
+	}
+}
+
+type IdempotencyRepoInterface interface {
+	CreateIdempotency
+	GetIdempotency
+	UpdateIdempotency
+	DeleteIdempotency
+	DeleteExpiredIdempotency
+}
+type CreateIdempotency interface {
+	CreateIdempotency(record *domain.Idempotency, kontek context.Context) error
+}
+type GetIdempotency interface {
+	GetIdempotency(userID int, key string, kontek context.Context) (*domain.Idempotency, error)
+}
+type UpdateIdempotency interface {
+	UpdateIdempotency(record *domain.Idempotency, kontek context.Context) error
+}
+type DeleteIdempotency interface {
+	DeleteIdempotency(userID int, key string, kontek context.Context) error
+}
+type DeleteExpiredIdempotency interface {
+	DeleteExpiredIdempotency(now time.Time, kontek context.Context) (int, error)
+}
+
+func idempotencyID(userID int, key string) string {
+	return fmt.Sprintf("%d:%s", userID, key)
+}
+
+// save the key, an expired key with the same name is replaced but a live one is not
+func (repo IdempotencyRepo) CreateIdempotency(record *domain.Idempotency, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		id := idempotencyID(record.UserID, record.Key)
+		if old, exist := repo.Keys[id]; exist && time.Now().Before(old.ExpiresAt) {
+			return domain.ErrIdempotencyInUse
+		}
+		repo.Keys[id] = *record
+		return nil
+	}
+}
+
+func (repo IdempotencyRepo) GetIdempotency(userID int, key string, kontek context.Context) (*domain.Idempotency, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		record, exist := repo.Keys[idempotencyID(userID, key)]
+		if !exist {
+			return nil, domain.ErrIdempotencyNotFound
+		}
+		return &record, nil
+	}
+}
+
+func (repo IdempotencyRepo) UpdateIdempotency(record *domain.Idempotency, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		id := idempotencyID(record.UserID, record.Key)
+		if _, exist := repo.Keys[id]; !exist {
+			return domain.ErrIdempotencyNotFound
+		}
+		repo.Keys[id] = *record
+		return nil
+	}
+}
+
+func (repo IdempotencyRepo) DeleteIdempotency(userID int, key string, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		delete(repo.Keys, idempotencyID(userID, key))
+		return nil
+	}
+}
+
+// func to remove every key that pass its expiry time
+func (repo IdempotencyRepo) DeleteExpiredIdempotency(now time.Time, kontek context.Context) (int, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return 0, kontek.Err()
+	default:
+		deleted := 0
+		for id, record := range repo.Keys {
+			if !now.Before(record.ExpiresAt) {
+				delete(repo.Keys, id)
+				deleted++
+			}
+		}
+		return deleted, nil
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pemesananTiketOnlineGo/internal/domain"
+	"time"
+)
+
+// idempotency db with sqlite
+type IdempotencyRepoSql struct {
+	DB *sql.DB
+}
+
+func NewIdempotencyRepoSql(db *sql.DB) IdempotencyRepoInterface {
+	return IdempotencyRepoSql{
+		DB: db,
+	}
+}
+
+// save the key, an expired key with the same name is replaced but a live one is not
+func (repo IdempotencyRepoSql) CreateIdempotency(record *domain.Idempotency, kontek context.Context) error {
+	return inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+
+		var live int
+		if err := db.QueryRowContext(kontek, `SELECT COUNT(1) FROM idempotency_keys WHERE user_id = ? AND key = ? AND expires_at > ?`,
+			record.UserID, record.Key, time.Now().UnixMilli()).Scan(&live); err != nil {
+			return err
+		}
+		if live > 0 {
+			return domain.ErrIdempotencyInUse
+		}
+
+		_, err := db.ExecContext(kontek, `INSERT OR REPLACE INTO idempotency_keys (user_id, key, request_hash, status, response_code, response_body, order_number, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			record.UserID, record.Key, record.RequestHash, record.Status, record.ResponseCode, record.ResponseBody, record.OrderNumber, record.ExpiresAt.UnixMilli())
+		return err
+	})
+}
+
+func (repo IdempotencyRepoSql) GetIdempotency(userID int, key string, kontek context.Context) (*domain.Idempotency, error) {
+	record := domain.Idempotency{UserID: userID, Key: key}
+	var expiresAt int64
+	err := executor(repo.DB, kontek).QueryRowContext(kontek, `SELECT request_hash, status, response_code, response_body, order_number, expires_at
+		FROM idempotency_keys WHERE user_id = ? AND key = ?`, userID, key).
+		Scan(&record.RequestHash, &record.Status, &record.ResponseCode, &record.ResponseBody, &record.OrderNumber, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrIdempotencyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	record.ExpiresAt = time.UnixMilli(expiresAt)
+	return &record, nil
+}
+
+func (repo IdempotencyRepoSql) UpdateIdempotency(record *domain.Idempotency, kontek context.Context) error {
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `UPDATE idempotency_keys SET request_hash = ?, status = ?, response_code = ?, response_body = ?, order_number = ?, expires_at = ?
+		WHERE user_id = ? AND key = ?`,
+		record.RequestHash, record.Status, record.ResponseCode, record.ResponseBody, record.OrderNumber, record.ExpiresAt.UnixMilli(), record.UserID, record.Key)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrIdempotencyNotFound
+	}
+	return nil
+}
+
+func (repo IdempotencyRepoSql) DeleteIdempotency(userID int, key string, kontek context.Context) error {
+	_, err := executor(repo.DB, kontek).ExecContext(kontek, `DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?`, userID, key)
+	return err
+}
+
+// func to remove every key that pass its expiry time
+func (repo IdempotencyRepoSql) DeleteExpiredIdempotency(now time.Time, kontek context.Context) (int, error) {
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `DELETE FROM idempotency_keys WHERE expires_at <= ?`, now.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
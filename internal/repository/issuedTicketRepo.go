@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"pemesananTiketOnlineGo/internal/domain"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// make issued ticket db with map, the code is kept in its own index so the gate find it at once
+type IssuedTicketRepo struct {
+	Tickets      map[int]domain.IssuedTicket
+	byCode       map[string]int
+	lastTicketID *int
+	mutek        *sync.RWMutex
+}
+
+func NewIssuedTicketRepo() IssuedTicketRepoInterface {
+	return IssuedTicketRepo{
+		Tickets:      map[int]domain.IssuedTicket{},
+		byCode:       map[string]int{},
+		lastTicketID: new(int),
+		mutek:        &sync.RWMutex{},
+	}
+}
+
+type IssuedTicketRepoInterface interface {
+	CreateIssuedTicket
+	GetIssuedTicketByCode
+	UpdateIssuedTicket
+	GetIssuedTicketsByUserID
+	GetIssuedTicketsByOrderID
+}
+type CreateIssuedTicket interface {
+	CreateIssuedTicket(ticket *domain.IssuedTicket, kontek context.Context) (*domain.IssuedTicket, error)
+}
+type GetIssuedTicketByCode interface {
+	GetIssuedTicketByCode(code string, kontek context.Context) (*domain.IssuedTicket, error)
+}
+type UpdateIssuedTicket interface {
+	UpdateIssuedTicket(ticket *domain.IssuedTicket, kontek context.Context) error
+}
+type GetIssuedTicketsByUserID interface {
+	GetIssuedTicketsByUserID(userID int, kontek context.Context) ([]domain.IssuedTicket, error)
+}
+type GetIssuedTicketsByOrderID interface {
+	GetIssuedTicketsByOrderID(orderID int, kontek context.Context) ([]domain.IssuedTicket, error)
+}
+
+// save the ticket with a new code
+func (repo IssuedTicketRepo) CreateIssuedTicket(ticket *domain.IssuedTicket, kontek context.Context) (*domain.IssuedTicket, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		code, err := newTicketCode(func(code string) (bool, error) {
+			_, taken := repo.byCode[code]
+			return taken, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		*repo.lastTicketID++
+		ticket.ID = *repo.lastTicketID
+		ticket.Code = code
+		repo.Tickets[ticket.ID] = *ticket
+		repo.byCode[code] = ticket.ID
+		ticketID := ticket.ID
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			delete(repo.Tickets, ticketID)
+			delete(repo.byCode, code)
+		})
+		return ticket, nil
+	}
+}
+
+func (repo IssuedTicketRepo) GetIssuedTicketByCode(code string, kontek context.Context) (*domain.IssuedTicket, error) {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		ticket, exist := repo.Tickets[repo.byCode[code]]
+		if !exist {
+			return nil, domain.ErrIssuedNotFound
+		}
+		return &ticket, nil
+	}
+}
+
+// the code, the order and the seat of a ticket never change
+func (repo IssuedTicketRepo) UpdateIssuedTicket(ticket *domain.IssuedTicket, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		old, exist := repo.Tickets[ticket.ID]
+		if !exist {
+			return domain.ErrIssuedNotFound
+		}
+		ticket.Code = old.Code
+		repo.Tickets[ticket.ID] = *ticket
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			repo.Tickets[old.ID] = old
+		})
+		return nil
+	}
+}
+
+// every ticket the user hold or held, the oldest first
+func (repo IssuedTicketRepo) GetIssuedTicketsByUserID(userID int, kontek context.Context) ([]domain.IssuedTicket, error) {
+	return repo.filter(kontek, func(ticket domain.IssuedTicket) bool { return ticket.UserID == userID })
+}
+
+// every ticket of the order, the transferred one too, the oldest first
+func (repo IssuedTicketRepo) GetIssuedTicketsByOrderID(orderID int, kontek context.Context) ([]domain.IssuedTicket, error) {
+	return repo.filter(kontek, func(ticket domain.IssuedTicket) bool { return ticket.OrderID == orderID })
+}
+
+func (repo IssuedTicketRepo) filter(kontek context.Context, keep func(ticket domain.IssuedTicket) bool) ([]domain.IssuedTicket, error) {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		tickets := []domain.IssuedTicket{}
+		for _, ticket := range repo.Tickets {
+			if keep(ticket) {
+				tickets = append(tickets, ticket)
+			}
+		}
+		slices.SortFunc(tickets, func(a, b domain.IssuedTicket) int { return a.ID - b.ID })
+		return tickets, nil
+	}
+}
+
+// random code of a ticket, 80 bit so it can't be guessed. taken tell whether another ticket already has it
+func newTicketCode(taken func(code string) (bool, error)) (string, error) {
+	for {
+		random := make([]byte, 10)
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+		code := strings.ToUpper(hex.EncodeToString(random))
+		exist, err := taken(code)
+		if err != nil || !exist {
+			return code, err
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"pemesananTiketOnlineGo/internal/domain"
+	"time"
+)
+
+// issued ticket db with sqlite
+type IssuedTicketRepoSql struct {
+	DB *sql.DB
+}
+
+func NewIssuedTicketRepoSql(db *sql.DB) IssuedTicketRepoInterface {
+	return IssuedTicketRepoSql{
+		DB: db,
+	}
+}
+
+// save the ticket with a new code
+func (repo IssuedTicketRepoSql) CreateIssuedTicket(ticket *domain.IssuedTicket, kontek context.Context) (*domain.IssuedTicket, error) {
+	err := inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+		code, err := newTicketCode(func(code string) (bool, error) {
+			var count int
+			err := db.QueryRowContext(kontek, `SELECT COUNT(*) FROM issued_tickets WHERE code = ?`, code).Scan(&count)
+			return count > 0, err
+		})
+		if err != nil {
+			return err
+		}
+
+		result, err := db.ExecContext(kontek, `INSERT INTO issued_tickets (code, order_id, event_id, event_name, ticket_id, type, seat_id, seat, user_id, holder_name,
+			status, issued_at, used_at, transferred_to) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			code, ticket.OrderID, ticket.EventID, ticket.EventName, ticket.TicketID, ticket.Type, ticket.SeatID, ticket.Seat, ticket.UserID, ticket.HolderName,
+			ticket.Status, ticket.IssuedAt.UnixMilli(), millisOrZero(ticket.UsedAt), ticket.TransferredTo)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		ticket.ID = int(id)
+		ticket.Code = code
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+func (repo IssuedTicketRepoSql) GetIssuedTicketByCode(code string, kontek context.Context) (*domain.IssuedTicket, error) {
+	tickets, err := repo.queryIssuedTickets(kontek, `WHERE code = ?`, code)
+	if err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return nil, domain.ErrIssuedNotFound
+	}
+	return &tickets[0], nil
+}
+
+// the code, the order and the seat of a ticket never change
+func (repo IssuedTicketRepoSql) UpdateIssuedTicket(ticket *domain.IssuedTicket, kontek context.Context) error {
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `UPDATE issued_tickets SET user_id = ?, holder_name = ?, status = ?, used_at = ?, transferred_to = ? WHERE id = ?`,
+		ticket.UserID, ticket.HolderName, ticket.Status, millisOrZero(ticket.UsedAt), ticket.TransferredTo, ticket.ID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrIssuedNotFound
+	}
+	return nil
+}
+
+// every ticket the user hold or held, the oldest first
+func (repo IssuedTicketRepoSql) GetIssuedTicketsByUserID(userID int, kontek context.Context) ([]domain.IssuedTicket, error) {
+	return repo.queryIssuedTickets(kontek, `WHERE user_id = ?`, userID)
+}
+
+// every ticket of the order, the transferred one too, the oldest first
+func (repo IssuedTicketRepoSql) GetIssuedTicketsByOrderID(orderID int, kontek context.Context) ([]domain.IssuedTicket, error) {
+	return repo.queryIssuedTickets(kontek, `WHERE order_id = ?`, orderID)
+}
+
+// get the tickets that match the where clause
+func (repo IssuedTicketRepoSql) queryIssuedTickets(kontek context.Context, where string, args ...any) ([]domain.IssuedTicket, error) {
+	rows, err := executor(repo.DB, kontek).QueryContext(kontek, `SELECT id, code, order_id, event_id, event_name, ticket_id, type, seat_id, seat, user_id, holder_name,
+		status, issued_at, used_at, transferred_to FROM issued_tickets `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tickets := []domain.IssuedTicket{}
+	for rows.Next() {
+		var ticket domain.IssuedTicket
+		var issuedAt, usedAt int64
+		if err := rows.Scan(&ticket.ID, &ticket.Code, &ticket.OrderID, &ticket.EventID, &ticket.EventName, &ticket.TicketID, &ticket.Type, &ticket.SeatID, &ticket.Seat,
+			&ticket.UserID, &ticket.HolderName, &ticket.Status, &issuedAt, &usedAt, &ticket.TransferredTo); err != nil {
+			return nil, err
+		}
+		ticket.IssuedAt = time.UnixMilli(issuedAt)
+		ticket.UsedAt = timeOrNil(usedAt)
+		tickets = append(tickets, ticket)
+	}
+	return tickets, rows.Err()
+}
@@ -1,22 +1,45 @@
 package repository
 
 import (
+	"cmp"
 	"context"
-	"errors"
+	"crypto/rand"
+	"encoding/hex"
 	"pemesananTiketOnlineGo/internal/domain"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// make Order db with map
+// make Order db with map, the ID come from a counter so a rolled back order never give its ID
+// to the next one while another purchase still use it. the order of every user are kept in their
+// own slot with its own lock, so saving or paying the order of one user never wait for another
+// user. the map lock is only taken for writing when a new order get its number
 type OrderRepo struct {
-	Orders map[int]domain.Order
-	mutek  *sync.Mutex
+	users map[int]*orderSlot
+	// the user of every order ID, and the order ID of every public order number
+	owners      map[int]int
+	byNumber    map[string]int
+	lastOrderID *atomic.Int64
+	mutek       *sync.RWMutex
+}
+
+// the order of one user sorted by ID, its lock is never held while taking the map lock
+type orderSlot struct {
+	orders []domain.Order
+	mutek  sync.RWMutex
 }
 
 func NewOrderRepo() OrderRepoInterface {
 	return OrderRepo{
-		Orders: map[int]domain.Order{},
-		mutek:  &sync.Mutex{},
+		users:       map[int]*orderSlot{},
+		owners:      map[int]int{},
+		byNumber:    map[string]int{},
+		lastOrderID: &atomic.Int64{},
+		mutek:       &sync.RWMutex{},
 	}
 }
 
@@ -24,6 +47,11 @@ type OrderRepoInterface interface {
 	CreateOrder
 	GetOrderByID
 	GetAllOrders
+	GetOrderByOrderID
+	GetOrderByNumber
+	UpdateOrder
+	GetOrdersByEventID
+	GetExpiredOrders
 }
 type CreateOrder interface {
 	CreateOrder(order *domain.Order, kontek context.Context) (*domain.Order, error)
@@ -34,57 +62,254 @@ type GetOrderByID interface {
 type GetAllOrders interface {
 	GetAllOrders(kontek context.Context) ([]domain.Order, error)
 }
+type GetOrderByOrderID interface {
+	GetOrderByOrderID(id int, kontek context.Context) (*domain.Order, error)
+}
+type GetOrderByNumber interface {
+	GetOrderByNumber(number string, kontek context.Context) (*domain.Order, error)
+}
+type UpdateOrder interface {
+	UpdateOrder(order *domain.Order, kontek context.Context) error
+}
+type GetOrdersByEventID interface {
+	GetOrdersByEventID(eventID int, kontek context.Context) ([]domain.Order, error)
+}
+type GetExpiredOrders interface {
+	GetExpiredOrders(now time.Time, kontek context.Context) ([]domain.Order, error)
+}
 
 func (repo OrderRepo) CreateOrder(order *domain.Order, kontek context.Context) (*domain.Order, error) {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
 	select {
 	case <-kontek.Done():
 		return nil, kontek.Err()
 	default:
-		if repo.Orders == nil || len(repo.Orders) == 0 {
-			order.ID = 1
-		} else {
-			order.ID = repo.Orders[len(repo.Orders)].ID + 1
+		userID, orderID := order.User.ID, int(repo.lastOrderID.Add(1))
+		repo.mutek.Lock()
+		// every saved order get a new number, even the record of a failed one
+		number, err := newOrderNumber(func(number string) (bool, error) {
+			_, taken := repo.byNumber[number]
+			return taken, nil
+		})
+		if err != nil {
+			repo.mutek.Unlock()
+			return nil, err
 		}
+		repo.byNumber[number] = orderID
+		repo.owners[orderID] = userID
+		slot, exist := repo.users[userID]
+		if !exist {
+			slot = &orderSlot{}
+			repo.users[userID] = slot
+		}
+		repo.mutek.Unlock()
 
-		repo.Orders[order.ID] = *order
+		order.ID = orderID
+		order.Number = number
+		slot.mutek.Lock()
+		index, _ := slot.find(orderID)
+		slot.orders = slices.Insert(slot.orders, index, storedOrder(order))
+		slot.mutek.Unlock()
+		onRollback(kontek, func() {
+			slot.mutek.Lock()
+			slot.orders = slices.DeleteFunc(slot.orders, func(order domain.Order) bool { return order.ID == orderID })
+			slot.mutek.Unlock()
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			delete(repo.byNumber, number)
+			delete(repo.owners, orderID)
+		})
 		return order, nil
 	}
 }
 
 // func to get All order by User ID
 func (repo OrderRepo) GetOrderByID(userID int, kontek context.Context) ([]domain.Order, error) {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
 	select {
 	case <-kontek.Done():
 		return nil, kontek.Err()
 	default:
 		var ordersUser []domain.Order
-		for _, Order := range repo.Orders {
-			if Order.User.ID == userID {
-				ordersUser = append(ordersUser, Order)
-			}
+		if slot := repo.slot(userID); slot != nil {
+			slot.mutek.RLock()
+			ordersUser = slices.Clone(slot.orders)
+			slot.mutek.RUnlock()
 		}
 		if len(ordersUser) == 0 {
-			return nil, errors.New("THIS USER HAVENT BUY A TICKET")
+			return nil, domain.ErrUserHasNoOrder
 		}
 		return ordersUser, nil
 	}
 }
 
 func (repo OrderRepo) GetAllOrders(kontek context.Context) ([]domain.Order, error) {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
 	select {
 	case <-kontek.Done():
 		return nil, kontek.Err()
 	default:
-		Orders := make([]domain.Order, 0, len(repo.Orders))
-		for _, Order := range repo.Orders {
-			Orders = append(Orders, Order)
+		return repo.filter(func(domain.Order) bool { return true }), nil
+	}
+}
+
+// func to get every order of an event, oldest first
+func (repo OrderRepo) GetOrdersByEventID(eventID int, kontek context.Context) ([]domain.Order, error) {
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		orders := repo.filter(func(order domain.Order) bool { return order.Event.ID == eventID })
+		sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+		return orders, nil
+	}
+}
+
+// func to get the unpaid order that pass its payment due, oldest first
+func (repo OrderRepo) GetExpiredOrders(now time.Time, kontek context.Context) ([]domain.Order, error) {
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		orders := repo.filter(func(order domain.Order) bool {
+			return order.Status == domain.OrderAwaitingPayment && order.PaymentDue != nil && !order.PaymentDue.After(now)
+		})
+		sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+		return orders, nil
+	}
+}
+
+// func to get one order by its own ID
+func (repo OrderRepo) GetOrderByOrderID(id int, kontek context.Context) (*domain.Order, error) {
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		return repo.order(id)
+	}
+}
+
+// func to get one order by its public number
+func (repo OrderRepo) GetOrderByNumber(number string, kontek context.Context) (*domain.Order, error) {
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		repo.mutek.RLock()
+		id, exist := repo.byNumber[number]
+		repo.mutek.RUnlock()
+		if !exist {
+			return nil, domain.ErrOrderNotFound
+		}
+		return repo.order(id)
+	}
+}
+
+func (repo OrderRepo) UpdateOrder(order *domain.Order, kontek context.Context) error {
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		slot := repo.ownerSlot(order.ID)
+		if slot == nil {
+			return domain.ErrOrderNotFound
+		}
+		slot.mutek.Lock()
+		defer slot.mutek.Unlock()
+		index, exist := slot.find(order.ID)
+		if !exist {
+			return domain.ErrOrderNotFound
+		}
+		old := slot.orders[index]
+		// the number is given once when the order is saved
+		order.Number = old.Number
+		slot.orders[index] = storedOrder(order)
+		onRollback(kontek, func() {
+			slot.mutek.Lock()
+			defer slot.mutek.Unlock()
+			if index, exist := slot.find(old.ID); exist {
+				slot.orders[index] = old
+			}
+		})
+		return nil
+	}
+}
+
+// the slot of the user, nil when the user has no order yet. a slot is never removed
+func (repo OrderRepo) slot(userID int) *orderSlot {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	return repo.users[userID]
+}
+
+// the slot of the user who own the order, nil when the order doesn't exist
+func (repo OrderRepo) ownerSlot(orderID int) *orderSlot {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	userID, exist := repo.owners[orderID]
+	if !exist {
+		return nil
+	}
+	return repo.users[userID]
+}
+
+func (repo OrderRepo) order(id int) (*domain.Order, error) {
+	slot := repo.ownerSlot(id)
+	if slot == nil {
+		return nil, domain.ErrOrderNotFound
+	}
+	slot.mutek.RLock()
+	defer slot.mutek.RUnlock()
+	index, exist := slot.find(id)
+	if !exist {
+		return nil, domain.ErrOrderNotFound
+	}
+	order := slot.orders[index]
+	return &order, nil
+}
+
+// every order that match, the slot are read one by one
+func (repo OrderRepo) filter(match func(order domain.Order) bool) []domain.Order {
+	repo.mutek.RLock()
+	slots := make([]*orderSlot, 0, len(repo.users))
+	for _, slot := range repo.users {
+		slots = append(slots, slot)
+	}
+	repo.mutek.RUnlock()
+
+	orders := []domain.Order{}
+	for _, slot := range slots {
+		slot.mutek.RLock()
+		for _, order := range slot.orders {
+			if match(order) {
+				orders = append(orders, order)
+			}
+		}
+		slot.mutek.RUnlock()
+	}
+	return orders
+}
+
+// the index of the order in the slot, or where it belong when it is not there
+func (slot *orderSlot) find(id int) (int, bool) {
+	return slices.BinarySearchFunc(slot.orders, id, func(order domain.Order, id int) int { return cmp.Compare(order.ID, id) })
+}
+
+// the history is copied so appending to the caller copy doesn't touch the stored order
+func storedOrder(order *domain.Order) domain.Order {
+	copied := *order
+	copied.History = slices.Clone(order.History)
+	return copied
+}
+
+// random public number of an order, taken tell whether another order already has it
+func newOrderNumber(taken func(number string) (bool, error)) (string, error) {
+	for {
+		random := make([]byte, 8)
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+		number := "ORD-" + strings.ToUpper(hex.EncodeToString(random))
+		exist, err := taken(number)
+		if err != nil || !exist {
+			return number, err
 		}
-		return Orders, nil
 	}
 }
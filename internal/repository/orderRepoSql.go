@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"pemesananTiketOnlineGo/internal/domain"
+	"time"
+)
+
+// order db with sqlite
+type OrderRepoSql struct {
+	DB *sql.DB
+}
+
+func NewOrderRepoSql(db *sql.DB) OrderRepoInterface {
+	return OrderRepoSql{
+		DB: db,
+	}
+}
+
+func (repo OrderRepoSql) CreateOrder(order *domain.Order, kontek context.Context) (*domain.Order, error) {
+	err := inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+
+		// every saved order get a new number, even the record of a failed one
+		number, err := newOrderNumber(func(number string) (bool, error) {
+			var count int
+			err := db.QueryRowContext(kontek, `SELECT COUNT(*) FROM orders WHERE number = ?`, number).Scan(&count)
+			return count > 0, err
+		})
+		if err != nil {
+			return err
+		}
+
+		result, err := db.ExecContext(kontek, `INSERT INTO orders (number, order_date, status, failure_reason, payment_method, payment_reference, payment_due, user_id, user_name,
+			event_id, event_name, event_start, event_end, event_timezone, event_location, event_description, subtotal_minor, discount_minor, promo_code,
+			fee_minor, tax_minor, tax_rate_bp, tax_included, total_minor, refund_minor, currency)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			number, order.OrderDate, order.Status, order.FailureReason, order.PaymentMethod, order.PaymentRef, paymentDue(order), order.User.ID, order.User.Name,
+			order.Event.ID, order.Event.Name, order.Event.Start.UnixMilli(), order.Event.End.UnixMilli(), order.Event.TimeZone, order.Event.Location, order.Event.Description,
+			order.Subtotal.Amount, order.Discount.Amount, order.PromoCode,
+			order.Fee.Amount, order.Tax.Amount, order.TaxRate, order.TaxIncluded, order.TotalPrice.Amount, order.RefundAmount.Amount, orderCurrency(order))
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		order.ID = int(id)
+		order.Number = number
+
+		refunded := refundedQuantity(order)
+		for _, ticket := range order.EventTicket {
+			if _, err := db.ExecContext(kontek, `INSERT INTO order_lines (order_id, ticket_id, type, price_minor, quantity, refunded) VALUES (?, ?, ?, ?, ?, ?)`,
+				order.ID, ticket.ID, ticket.Type, ticket.Price.Amount, ticket.Quantity, refunded[ticket.ID]); err != nil {
+				return err
+			}
+		}
+		for _, seat := range order.Seats {
+			if _, err := db.ExecContext(kontek, `INSERT INTO order_seats (order_id, seat_id, ticket_id, section, row_label, number, status) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				order.ID, seat.ID, seat.TicketID, seat.Section, seat.Row, seat.Number, seat.Status); err != nil {
+				return err
+			}
+		}
+		return insertHistory(db, kontek, order.ID, order.History)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// func to get one order by its own ID
+func (repo OrderRepoSql) GetOrderByOrderID(id int, kontek context.Context) (*domain.Order, error) {
+	orders, err := repo.queryOrders(kontek, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, domain.ErrOrderNotFound
+	}
+	return &orders[0], nil
+}
+
+// the bought ticket of an order never change, only the status, the refunded part and the status of its seats.
+// the history only grow, so only the new step is inserted
+func (repo OrderRepoSql) UpdateOrder(order *domain.Order, kontek context.Context) error {
+	return inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+
+		result, err := db.ExecContext(kontek, `UPDATE orders SET status = ?, failure_reason = ?, payment_method = ?, payment_reference = ?, payment_due = ?,
+			total_minor = ?, refund_minor = ?, currency = ? WHERE id = ?`,
+			order.Status, order.FailureReason, order.PaymentMethod, order.PaymentRef, paymentDue(order),
+			order.TotalPrice.Amount, order.RefundAmount.Amount, orderCurrency(order), order.ID)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return domain.ErrOrderNotFound
+		}
+
+		for ticketID, quantity := range refundedQuantity(order) {
+			if _, err := db.ExecContext(kontek, `UPDATE order_lines SET refunded = ? WHERE order_id = ? AND ticket_id = ?`,
+				quantity, order.ID, ticketID); err != nil {
+				return err
+			}
+		}
+		for _, seat := range order.Seats {
+			if _, err := db.ExecContext(kontek, `UPDATE order_seats SET status = ? WHERE order_id = ? AND seat_id = ?`,
+				seat.Status, order.ID, seat.ID); err != nil {
+				return err
+			}
+		}
+
+		var saved int
+		if err := db.QueryRowContext(kontek, `SELECT COUNT(*) FROM order_status_history WHERE order_id = ?`, order.ID).Scan(&saved); err != nil {
+			return err
+		}
+		if saved < len(order.History) {
+			return insertHistory(db, kontek, order.ID, order.History[saved:])
+		}
+		return nil
+	})
+}
+
+// func to get one order by its public number
+func (repo OrderRepoSql) GetOrderByNumber(number string, kontek context.Context) (*domain.Order, error) {
+	orders, err := repo.queryOrders(kontek, `WHERE number = ?`, number)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, domain.ErrOrderNotFound
+	}
+	return &orders[0], nil
+}
+
+// func to get All order by User ID
+func (repo OrderRepoSql) GetOrderByID(userID int, kontek context.Context) ([]domain.Order, error) {
+	ordersUser, err := repo.queryOrders(kontek, `WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ordersUser) == 0 {
+		return nil, domain.ErrUserHasNoOrder
+	}
+	return ordersUser, nil
+}
+
+func (repo OrderRepoSql) GetAllOrders(kontek context.Context) ([]domain.Order, error) {
+	return repo.queryOrders(kontek, ``)
+}
+
+// func to get every order of an event, oldest first
+func (repo OrderRepoSql) GetOrdersByEventID(eventID int, kontek context.Context) ([]domain.Order, error) {
+	return repo.queryOrders(kontek, `WHERE event_id = ?`, eventID)
+}
+
+// func to get the unpaid order that pass its payment due, oldest first
+func (repo OrderRepoSql) GetExpiredOrders(now time.Time, kontek context.Context) ([]domain.Order, error) {
+	return repo.queryOrders(kontek, `WHERE status = ? AND payment_due > 0 AND payment_due <= ?`, domain.OrderAwaitingPayment, now.UnixMilli())
+}
+
+// get the orders that match the where clause together with their lines, seats and history
+func (repo OrderRepoSql) queryOrders(kontek context.Context, where string, args ...any) ([]domain.Order, error) {
+	db := executor(repo.DB, kontek)
+
+	rows, err := db.QueryContext(kontek, `SELECT id, number, order_date, status, failure_reason, payment_method, payment_reference, payment_due, user_id, user_name,
+		event_id, event_name, event_start, event_end, event_timezone, event_location, event_description, subtotal_minor, discount_minor, promo_code,
+		fee_minor, tax_minor, tax_rate_bp, tax_included, total_minor, refund_minor, currency
+		FROM orders `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []domain.Order{}
+	index := map[int]int{}
+	for rows.Next() {
+		var order domain.Order
+		var due, start, end int64
+		if err := rows.Scan(&order.ID, &order.Number, &order.OrderDate, &order.Status, &order.FailureReason, &order.PaymentMethod, &order.PaymentRef, &due, &order.User.ID, &order.User.Name,
+			&order.Event.ID, &order.Event.Name, &start, &end, &order.Event.TimeZone, &order.Event.Location, &order.Event.Description,
+			&order.Subtotal.Amount, &order.Discount.Amount, &order.PromoCode,
+			&order.Fee.Amount, &order.Tax.Amount, &order.TaxRate, &order.TaxIncluded, &order.TotalPrice.Amount, &order.RefundAmount.Amount, &order.TotalPrice.Currency); err != nil {
+			return nil, err
+		}
+		order.Event.Start, order.Event.End = time.UnixMilli(start), time.UnixMilli(end)
+		if err := order.Event.InTimeZone(); err != nil {
+			return nil, err
+		}
+		if due > 0 {
+			paymentDue := time.UnixMilli(due)
+			order.PaymentDue = &paymentDue
+		}
+		order.RefundAmount.Currency = order.TotalPrice.Currency
+		order.Subtotal.Currency = order.TotalPrice.Currency
+		order.Discount.Currency = order.TotalPrice.Currency
+		order.Fee.Currency = order.TotalPrice.Currency
+		order.Tax.Currency = order.TotalPrice.Currency
+		index[order.ID] = len(orders)
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	lineRows, err := db.QueryContext(kontek, `SELECT order_id, ticket_id, type, price_minor, quantity, refunded FROM order_lines
+		WHERE order_id IN (SELECT id FROM orders `+where+`) ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer lineRows.Close()
+
+	for lineRows.Next() {
+		var orderID, refunded int
+		var ticket domain.Ticket
+		if err := lineRows.Scan(&orderID, &ticket.ID, &ticket.Type, &ticket.Price.Amount, &ticket.Quantity, &refunded); err != nil {
+			return nil, err
+		}
+		if i, exist := index[orderID]; exist {
+			ticket.Price.Currency = orders[i].TotalPrice.Currency
+			orders[i].EventTicket = append(orders[i].EventTicket, ticket)
+			if refunded > 0 {
+				ticket.Quantity = refunded
+				orders[i].Refunded = append(orders[i].Refunded, ticket)
+			}
+		}
+	}
+	if err := lineRows.Err(); err != nil {
+		return nil, err
+	}
+	lineRows.Close()
+
+	seatRows, err := db.QueryContext(kontek, `SELECT order_id, seat_id, ticket_id, section, row_label, number, status FROM order_seats
+		WHERE order_id IN (SELECT id FROM orders `+where+`) ORDER BY rowid`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer seatRows.Close()
+
+	for seatRows.Next() {
+		var orderID int
+		var seat domain.Seat
+		if err := seatRows.Scan(&orderID, &seat.ID, &seat.TicketID, &seat.Section, &seat.Row, &seat.Number, &seat.Status); err != nil {
+			return nil, err
+		}
+		if i, exist := index[orderID]; exist {
+			seat.EventID = orders[i].Event.ID
+			orders[i].Seats = append(orders[i].Seats, seat)
+		}
+	}
+	if err := seatRows.Err(); err != nil {
+		return nil, err
+	}
+	seatRows.Close()
+
+	historyRows, err := db.QueryContext(kontek, `SELECT order_id, from_status, to_status, reason, changed_at FROM order_status_history
+		WHERE order_id IN (SELECT id FROM orders `+where+`) ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer historyRows.Close()
+
+	for historyRows.Next() {
+		var orderID int
+		var changedAt int64
+		var change domain.OrderStatusChange
+		if err := historyRows.Scan(&orderID, &change.From, &change.To, &change.Reason, &changedAt); err != nil {
+			return nil, err
+		}
+		change.At = time.UnixMilli(changedAt)
+		if i, exist := index[orderID]; exist {
+			orders[i].History = append(orders[i].History, change)
+		}
+	}
+	return orders, historyRows.Err()
+}
+
+// save the status step of the order, oldest first
+func insertHistory(db sqlExecutor, kontek context.Context, orderID int, history []domain.OrderStatusChange) error {
+	for _, change := range history {
+		if _, err := db.ExecContext(kontek, `INSERT INTO order_status_history (order_id, from_status, to_status, reason, changed_at) VALUES (?, ?, ?, ?, ?)`,
+			orderID, change.From, change.To, change.Reason, change.At.UnixMilli()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// an order without payment due is stored as 0
+func paymentDue(order *domain.Order) int64 {
+	if order.PaymentDue == nil {
+		return 0
+	}
+	return order.PaymentDue.UnixMilli()
+}
+
+// refunded quantity of every ticket in the order
+func refundedQuantity(order *domain.Order) map[int]int {
+	refunded := map[int]int{}
+	for _, ticket := range order.Refunded {
+		refunded[ticket.ID] += ticket.Quantity
+	}
+	return refunded
+}
+
+// an order is in one currency, the total, the refund and every line share it
+func orderCurrency(order *domain.Order) string {
+	return currencyOf(order.TotalPrice)
+}
+
+// money without currency is stored in the default one
+func currencyOf(money domain.Money) string {
+	if money.Currency == "" {
+		return domain.DefaultCurrency
+	}
+	return money.Currency
+}
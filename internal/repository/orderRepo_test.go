@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"pemesananTiketOnlineGo/internal/domain"
+	"sync"
+	"testing"
+)
+
+// many order is saved at the same time, some of them roll back. every order keep its own ID
+// and public number, and in memory a rolled back order never give its ID to another one
+func TestCreateOrderUniqueID(t *testing.T) {
+	cases := []struct {
+		name   string
+		orders int
+		// every n-th order roll back after it is saved, 0 is never
+		rollback int
+	}{
+		{name: "all committed", orders: 100},
+		{name: "some rolled back", orders: 100, rollback: 3},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				db := openTestDb(t, backend)
+				orderRepo := newTestOrderRepo(db)
+				unitOfWork := newTestUnitOfWork(db)
+				kontek := context.Background()
+				errRollback := errors.New("rollback")
+
+				var mutek sync.Mutex
+				committed := map[int]string{}
+				rolledBack := map[int]bool{}
+				rollbacks := 0
+				var wg sync.WaitGroup
+				for i := 1; i <= tc.orders; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						var saved *domain.Order
+						err := unitOfWork.WithinScope(kontek, []string{UserKey(i)}, func(kontek context.Context) error {
+							var err error
+							saved, err = orderRepo.CreateOrder(&domain.Order{Status: domain.OrderPending, User: domain.User{ID: i}, Event: domain.Event{ID: 1}}, kontek)
+							if err != nil {
+								return err
+							}
+							if tc.rollback > 0 && i%tc.rollback == 0 {
+								return errRollback
+							}
+							return nil
+						})
+						mutek.Lock()
+						defer mutek.Unlock()
+						switch {
+						case err == nil:
+							if _, taken := committed[saved.ID]; taken {
+								t.Errorf("order ID %d is given twice", saved.ID)
+							}
+							committed[saved.ID] = saved.Number
+						case errors.Is(err, errRollback):
+							rolledBack[saved.ID] = true
+							rollbacks++
+						default:
+							t.Error(err)
+						}
+					}(i)
+				}
+				wg.Wait()
+
+				numbers := map[string]int{}
+				for id, number := range committed {
+					// sqlite give the ID of a rolled back insert again, that row never existed
+					if backend == "memory" && rolledBack[id] {
+						t.Errorf("order ID %d of a rolled back order is given again", id)
+					}
+					if other, taken := numbers[number]; taken || number == "" {
+						t.Errorf("order %d and %d have the number %q", other, id, number)
+					}
+					numbers[number] = id
+					order, err := orderRepo.GetOrderByNumber(number, kontek)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if order.ID != id {
+						t.Errorf("number %s find order %d, want %d", number, order.ID, id)
+					}
+				}
+				if len(committed)+rollbacks != tc.orders {
+					t.Errorf("%d committed and %d rolled back of %d order", len(committed), rollbacks, tc.orders)
+				}
+			})
+		}
+	}
+}
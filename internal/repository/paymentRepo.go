@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"sync"
+)
+
+// make the payment of the simulated gateway with map, the key is the reference
+type PaymentRepo struct {
+	Payments map[string]domain.SimulatedPayment
+	mutek    *sync.Mutex
+}
+
+func NewPaymentRepo() PaymentRepoInterface {
+	return PaymentRepo{
+		Payments: map[string]domain.SimulatedPayment{},
+		mutek:    &sync.Mutex{},
+	}
+}
+
+type PaymentRepoInterface interface {
+	CreatePayment
+	GetPaymentByReference
+	SettlePayment
+	RefundPayment
+}
+type CreatePayment interface {
+	CreatePayment(payment *domain.SimulatedPayment, kontek context.Context) error
+}
+type GetPaymentByReference interface {
+	GetPaymentByReference(reference string, kontek context.Context) (*domain.SimulatedPayment, error)
+}
+type SettlePayment interface {
+	SettlePayment(reference string, status string, kontek context.Context) (*domain.SimulatedPayment, error)
+}
+type RefundPayment interface {
+	RefundPayment(reference string, amount domain.Money, kontek context.Context) error
+}
+
+// save a new payment, a reference is only used once
+func (repo PaymentRepo) CreatePayment(payment *domain.SimulatedPayment, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		if _, exist := repo.Payments[payment.Reference]; exist {
+			return domain.NewError(domain.ErrConflict, "PAYMENT_EXISTS", "A PAYMENT WITH THAT REFERENCE ALREADY EXISTS")
+		}
+		repo.Payments[payment.Reference] = *payment
+
+		reference := payment.Reference
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			delete(repo.Payments, reference)
+		})
+		return nil
+	}
+}
+
+func (repo PaymentRepo) GetPaymentByReference(reference string, kontek context.Context) (*domain.SimulatedPayment, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		payment, exist := repo.Payments[reference]
+		if !exist {
+			return nil, domain.ErrPaymentNotFound
+		}
+		return &payment, nil
+	}
+}
+
+// move a pending payment to paid or failed, a payment is only settled once
+func (repo PaymentRepo) SettlePayment(reference string, status string, kontek context.Context) (*domain.SimulatedPayment, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		payment, exist := repo.Payments[reference]
+		if !exist {
+			return nil, domain.ErrPaymentNotFound
+		}
+		if payment.Status != domain.PaymentPending {
+			return nil, domain.ErrPaymentSettled
+		}
+		old := payment
+		payment.Status = status
+		repo.Payments[reference] = payment
+
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			repo.Payments[reference] = old
+		})
+		return &payment, nil
+	}
+}
+
+// give part of a paid payment back, never more than what is paid
+func (repo PaymentRepo) RefundPayment(reference string, amount domain.Money, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		payment, exist := repo.Payments[reference]
+		if !exist {
+			return domain.ErrPaymentNotFound
+		}
+		if payment.Status != domain.PaymentPaid {
+			return domain.ErrPaymentNotPaid
+		}
+		refunded, err := payment.Refunded.Add(amount)
+		if err != nil {
+			return err
+		}
+		if left, err := payment.Amount.Sub(refunded); err != nil || left.IsNegative() {
+			return domain.ErrRefundTooMuch
+		}
+		old := payment
+		payment.Refunded = refunded
+		repo.Payments[reference] = payment
+
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			repo.Payments[reference] = old
+		})
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pemesananTiketOnlineGo/internal/domain"
+)
+
+// payment of the simulated gateway with sqlite
+type PaymentRepoSql struct {
+	DB *sql.DB
+}
+
+func NewPaymentRepoSql(db *sql.DB) PaymentRepoInterface {
+	return PaymentRepoSql{
+		DB: db,
+	}
+}
+
+// save a new payment, a reference is only used once
+func (repo PaymentRepoSql) CreatePayment(payment *domain.SimulatedPayment, kontek context.Context) error {
+	return inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+
+		var count int
+		if err := db.QueryRowContext(kontek, `SELECT COUNT(*) FROM simulated_payments WHERE reference = ?`, payment.Reference).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return domain.NewError(domain.ErrConflict, "PAYMENT_EXISTS", "A PAYMENT WITH THAT REFERENCE ALREADY EXISTS")
+		}
+
+		_, err := db.ExecContext(kontek, `INSERT INTO simulated_payments (reference, method, order_id, amount_minor, currency, status, instruction, refunded_minor, silent)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			payment.Reference, payment.Method, payment.OrderID, payment.Amount.Amount, currencyOf(payment.Amount), payment.Status, payment.Instruction, payment.Refunded.Amount, payment.Silent)
+		return err
+	})
+}
+
+func (repo PaymentRepoSql) GetPaymentByReference(reference string, kontek context.Context) (*domain.SimulatedPayment, error) {
+	var payment domain.SimulatedPayment
+	err := executor(repo.DB, kontek).QueryRowContext(kontek, `SELECT reference, method, order_id, amount_minor, currency, status, instruction, refunded_minor, silent
+		FROM simulated_payments WHERE reference = ?`, reference).
+		Scan(&payment.Reference, &payment.Method, &payment.OrderID, &payment.Amount.Amount, &payment.Amount.Currency, &payment.Status, &payment.Instruction, &payment.Refunded.Amount, &payment.Silent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	payment.Refunded.Currency = payment.Amount.Currency
+	return &payment, nil
+}
+
+// move a pending payment to paid or failed, a payment is only settled once
+func (repo PaymentRepoSql) SettlePayment(reference string, status string, kontek context.Context) (*domain.SimulatedPayment, error) {
+	var payment *domain.SimulatedPayment
+	err := inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		var err error
+		if payment, err = repo.GetPaymentByReference(reference, kontek); err != nil {
+			return err
+		}
+		if payment.Status != domain.PaymentPending {
+			return domain.ErrPaymentSettled
+		}
+		payment.Status = status
+		_, err = executor(repo.DB, kontek).ExecContext(kontek, `UPDATE simulated_payments SET status = ? WHERE reference = ?`, status, reference)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// give part of a paid payment back, never more than what is paid
+func (repo PaymentRepoSql) RefundPayment(reference string, amount domain.Money, kontek context.Context) error {
+	return inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		payment, err := repo.GetPaymentByReference(reference, kontek)
+		if err != nil {
+			return err
+		}
+		if payment.Status != domain.PaymentPaid {
+			return domain.ErrPaymentNotPaid
+		}
+		refunded, err := payment.Refunded.Add(amount)
+		if err != nil {
+			return err
+		}
+		if left, err := payment.Amount.Sub(refunded); err != nil || left.IsNegative() {
+			return domain.ErrRefundTooMuch
+		}
+		_, err = executor(repo.DB, kontek).ExecContext(kontek, `UPDATE simulated_payments SET refunded_minor = ? WHERE reference = ?`, refunded.Amount, reference)
+		return err
+	})
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// make pricing db with map, one pricing for every currency
+type PricingRepo struct {
+	Pricings map[string]domain.Pricing
+	mutek    *sync.Mutex
+}
+
+func NewPricingRepo() PricingRepoInterface {
+	return PricingRepo{
+		Pricings: map[string]domain.Pricing{},
+		mutek:    &sync.Mutex{},
+	}
+}
+
+type PricingRepoInterface interface {
+	GetPricing
+	GetAllPricings
+	SetPricing
+}
+type GetPricing interface {
+	GetPricing(currency string, kontek context.Context) (*domain.Pricing, error)
+}
+type GetAllPricings interface {
+	GetAllPricings(kontek context.Context) ([]domain.Pricing, error)
+}
+type SetPricing interface {
+	SetPricing(pricing *domain.Pricing, kontek context.Context) error
+}
+
+func (repo PricingRepo) GetPricing(currency string, kontek context.Context) (*domain.Pricing, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		pricing, exist := repo.Pricings[currency]
+		if !exist {
+			return nil, domain.ErrPricingNotFound
+		}
+		return &pricing, nil
+	}
+}
+
+func (repo PricingRepo) GetAllPricings(kontek context.Context) ([]domain.Pricing, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		pricings := make([]domain.Pricing, 0, len(repo.Pricings))
+		for _, pricing := range repo.Pricings {
+			pricings = append(pricings, pricing)
+		}
+		slices.SortFunc(pricings, func(a, b domain.Pricing) int { return strings.Compare(a.Currency, b.Currency) })
+		return pricings, nil
+	}
+}
+
+// save the pricing of its currency, the old one is replaced
+func (repo PricingRepo) SetPricing(pricing *domain.Pricing, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		old, exist := repo.Pricings[pricing.Currency]
+		repo.Pricings[pricing.Currency] = *pricing
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			if exist {
+				repo.Pricings[old.Currency] = old
+			} else {
+				delete(repo.Pricings, old.Currency)
+			}
+		})
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"pemesananTiketOnlineGo/internal/domain"
+)
+
+// pricing db with sqlite
+type PricingRepoSql struct {
+	DB *sql.DB
+}
+
+func NewPricingRepoSql(db *sql.DB) PricingRepoInterface {
+	return PricingRepoSql{
+		DB: db,
+	}
+}
+
+func (repo PricingRepoSql) GetPricing(currency string, kontek context.Context) (*domain.Pricing, error) {
+	pricings, err := repo.queryPricings(kontek, `WHERE currency = ?`, currency)
+	if err != nil {
+		return nil, err
+	}
+	if len(pricings) == 0 {
+		return nil, domain.ErrPricingNotFound
+	}
+	return &pricings[0], nil
+}
+
+func (repo PricingRepoSql) GetAllPricings(kontek context.Context) ([]domain.Pricing, error) {
+	return repo.queryPricings(kontek, ``)
+}
+
+// save the pricing of its currency, the old one is replaced
+func (repo PricingRepoSql) SetPricing(pricing *domain.Pricing, kontek context.Context) error {
+	_, err := executor(repo.DB, kontek).ExecContext(kontek, `INSERT OR REPLACE INTO pricings (currency, ticket_fee_minor, order_fee_minor, tax_rate_bp, tax_included, tax_on_fee, rounding, round_to)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		pricing.Currency, pricing.TicketFee.Amount, pricing.OrderFee.Amount, pricing.TaxRate, pricing.TaxIncluded, pricing.TaxOnFee, pricing.Rounding, pricing.RoundTo)
+	return err
+}
+
+// get the pricings that match the where clause
+func (repo PricingRepoSql) queryPricings(kontek context.Context, where string, args ...any) ([]domain.Pricing, error) {
+	rows, err := executor(repo.DB, kontek).QueryContext(kontek, `SELECT currency, ticket_fee_minor, order_fee_minor, tax_rate_bp, tax_included, tax_on_fee, rounding, round_to
+		FROM pricings `+where+` ORDER BY currency`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pricings := []domain.Pricing{}
+	for rows.Next() {
+		var pricing domain.Pricing
+		if err := rows.Scan(&pricing.Currency, &pricing.TicketFee.Amount, &pricing.OrderFee.Amount, &pricing.TaxRate,
+			&pricing.TaxIncluded, &pricing.TaxOnFee, &pricing.Rounding, &pricing.RoundTo); err != nil {
+			return nil, err
+		}
+		pricing.TicketFee.Currency = pricing.Currency
+		pricing.OrderFee.Currency = pricing.Currency
+		pricings = append(pricings, pricing)
+	}
+	return pricings, rows.Err()
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"slices"
+	"sync"
+)
+
+// make promo db with map, an order use at most one promo so the redemption is keyed by the order
+type PromoRepo struct {
+	Promos      map[int]domain.Promo
+	Redemptions map[int]domain.PromoRedemption
+	lastPromoID *int
+	mutek       *sync.Mutex
+}
+
+func NewPromoRepo() PromoRepoInterface {
+	return PromoRepo{
+		Promos:      map[int]domain.Promo{},
+		Redemptions: map[int]domain.PromoRedemption{},
+		lastPromoID: new(int),
+		mutek:       &sync.Mutex{},
+	}
+}
+
+type PromoRepoInterface interface {
+	CreatePromo
+	GetPromoByCode
+	GetAllPromos
+	UpdatePromo
+	CreateRedemption
+	CountRedemptions
+	ReleaseRedemption
+}
+type CreatePromo interface {
+	CreatePromo(promo *domain.Promo, kontek context.Context) (*domain.Promo, error)
+}
+type GetPromoByCode interface {
+	GetPromoByCode(code string, kontek context.Context) (*domain.Promo, error)
+}
+type GetAllPromos interface {
+	GetAllPromos(kontek context.Context) ([]domain.Promo, error)
+}
+type UpdatePromo interface {
+	UpdatePromo(promo *domain.Promo, kontek context.Context) error
+}
+type CreateRedemption interface {
+	CreateRedemption(redemption *domain.PromoRedemption, kontek context.Context) error
+}
+type CountRedemptions interface {
+	CountRedemptions(promoID int, userID int, kontek context.Context) (int, error)
+}
+type ReleaseRedemption interface {
+	ReleaseRedemption(orderID int, kontek context.Context) error
+}
+
+func (repo PromoRepo) CreatePromo(promo *domain.Promo, kontek context.Context) (*domain.Promo, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		for _, old := range repo.Promos {
+			if old.Code == promo.Code {
+				return nil, domain.ErrPromoExist
+			}
+		}
+		*repo.lastPromoID++
+		promo.ID = *repo.lastPromoID
+		promo.Used = 0
+		promo.TicketIDs = slices.Clone(promo.TicketIDs)
+		repo.Promos[promo.ID] = *promo
+		return promo, nil
+	}
+}
+
+func (repo PromoRepo) GetPromoByCode(code string, kontek context.Context) (*domain.Promo, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		for _, promo := range repo.Promos {
+			if promo.Code == code {
+				promo.Used = repo.used(promo.ID, 0)
+				return &promo, nil
+			}
+		}
+		return nil, domain.ErrPromoNotFound
+	}
+}
+
+func (repo PromoRepo) GetAllPromos(kontek context.Context) ([]domain.Promo, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		promos := make([]domain.Promo, 0, len(repo.Promos))
+		for _, promo := range repo.Promos {
+			promo.Used = repo.used(promo.ID, 0)
+			promos = append(promos, promo)
+		}
+		slices.SortFunc(promos, func(a, b domain.Promo) int { return a.ID - b.ID })
+		return promos, nil
+	}
+}
+
+// the code and the usage stay, everything else is replaced
+func (repo PromoRepo) UpdatePromo(promo *domain.Promo, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		old, exist := repo.Promos[promo.ID]
+		if !exist {
+			return domain.ErrPromoNotFound
+		}
+		promo.Code = old.Code
+		promo.CreatedBy = old.CreatedBy
+		promo.Used = repo.used(promo.ID, 0)
+		promo.TicketIDs = slices.Clone(promo.TicketIDs)
+		repo.Promos[promo.ID] = *promo
+		return nil
+	}
+}
+
+func (repo PromoRepo) CreateRedemption(redemption *domain.PromoRedemption, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		repo.Redemptions[redemption.OrderID] = *redemption
+		orderID := redemption.OrderID
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			delete(repo.Redemptions, orderID)
+		})
+		return nil
+	}
+}
+
+// redemption of the promo by the user that is not released
+func (repo PromoRepo) CountRedemptions(promoID int, userID int, kontek context.Context) (int, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return 0, kontek.Err()
+	default:
+		return repo.used(promoID, userID), nil
+	}
+}
+
+// give the use of the promo back, an order without promo is ignored
+func (repo PromoRepo) ReleaseRedemption(orderID int, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		redemption, exist := repo.Redemptions[orderID]
+		if !exist || redemption.Released {
+			return nil
+		}
+		redemption.Released = true
+		repo.Redemptions[orderID] = redemption
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			redemption.Released = false
+			repo.Redemptions[orderID] = redemption
+		})
+		return nil
+	}
+}
+
+// count the live redemption of a promo, of one user when userID is not 0. the caller hold the lock
+func (repo PromoRepo) used(promoID int, userID int) int {
+	used := 0
+	for _, redemption := range repo.Redemptions {
+		if redemption.PromoID == promoID && !redemption.Released && (userID == 0 || redemption.UserID == userID) {
+			used++
+		}
+	}
+	return used
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"pemesananTiketOnlineGo/internal/domain"
+)
+
+// promo db with sqlite
+type PromoRepoSql struct {
+	DB *sql.DB
+}
+
+func NewPromoRepoSql(db *sql.DB) PromoRepoInterface {
+	return PromoRepoSql{
+		DB: db,
+	}
+}
+
+func (repo PromoRepoSql) CreatePromo(promo *domain.Promo, kontek context.Context) (*domain.Promo, error) {
+	err := inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+
+		var exist int
+		if err := db.QueryRowContext(kontek, `SELECT COUNT(1) FROM promos WHERE code = ?`, promo.Code).Scan(&exist); err != nil {
+			return err
+		}
+		if exist > 0 {
+			return domain.ErrPromoExist
+		}
+
+		result, err := db.ExecContext(kontek, `INSERT INTO promos (code, kind, percent, amount_minor, currency, event_id, max_uses, max_per_user, valid_from, valid_until, created_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			promo.Code, promo.Kind, promo.Percent, promo.Amount.Amount, currencyOf(promo.Amount), promo.EventID, promo.MaxUses, promo.MaxPerUser,
+			millisOrZero(promo.ValidFrom), millisOrZero(promo.ValidUntil), promo.CreatedBy)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		promo.ID = int(id)
+		promo.Used = 0
+		return repo.insertPromoTickets(promo, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promo, nil
+}
+
+func (repo PromoRepoSql) GetPromoByCode(code string, kontek context.Context) (*domain.Promo, error) {
+	promos, err := repo.queryPromos(kontek, `WHERE code = ?`, code)
+	if err != nil {
+		return nil, err
+	}
+	if len(promos) == 0 {
+		return nil, domain.ErrPromoNotFound
+	}
+	return &promos[0], nil
+}
+
+func (repo PromoRepoSql) GetAllPromos(kontek context.Context) ([]domain.Promo, error) {
+	return repo.queryPromos(kontek, ``)
+}
+
+// the code and the usage stay, everything else is replaced
+func (repo PromoRepoSql) UpdatePromo(promo *domain.Promo, kontek context.Context) error {
+	return inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+
+		result, err := db.ExecContext(kontek, `UPDATE promos SET kind = ?, percent = ?, amount_minor = ?, currency = ?, event_id = ?, max_uses = ?, max_per_user = ?, valid_from = ?, valid_until = ?
+			WHERE id = ?`,
+			promo.Kind, promo.Percent, promo.Amount.Amount, currencyOf(promo.Amount), promo.EventID, promo.MaxUses, promo.MaxPerUser,
+			millisOrZero(promo.ValidFrom), millisOrZero(promo.ValidUntil), promo.ID)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return domain.ErrPromoNotFound
+		}
+		if _, err := db.ExecContext(kontek, `DELETE FROM promo_tickets WHERE promo_id = ?`, promo.ID); err != nil {
+			return err
+		}
+		if err := repo.insertPromoTickets(promo, kontek); err != nil {
+			return err
+		}
+
+		promos, err := repo.queryPromos(kontek, `WHERE id = ?`, promo.ID)
+		if err != nil {
+			return err
+		}
+		*promo = promos[0]
+		return nil
+	})
+}
+
+func (repo PromoRepoSql) CreateRedemption(redemption *domain.PromoRedemption, kontek context.Context) error {
+	_, err := executor(repo.DB, kontek).ExecContext(kontek, `INSERT INTO promo_redemptions (order_id, promo_id, user_id, discount_minor, currency, released) VALUES (?, ?, ?, ?, ?, ?)`,
+		redemption.OrderID, redemption.PromoID, redemption.UserID, redemption.Discount.Amount, currencyOf(redemption.Discount), redemption.Released)
+	return err
+}
+
+// redemption of the promo by the user that is not released
+func (repo PromoRepoSql) CountRedemptions(promoID int, userID int, kontek context.Context) (int, error) {
+	var used int
+	err := executor(repo.DB, kontek).QueryRowContext(kontek, `SELECT COUNT(1) FROM promo_redemptions WHERE promo_id = ? AND user_id = ? AND released = 0`,
+		promoID, userID).Scan(&used)
+	return used, err
+}
+
+// give the use of the promo back, an order without promo is ignored
+func (repo PromoRepoSql) ReleaseRedemption(orderID int, kontek context.Context) error {
+	_, err := executor(repo.DB, kontek).ExecContext(kontek, `UPDATE promo_redemptions SET released = 1 WHERE order_id = ?`, orderID)
+	return err
+}
+
+// save the ticket scope of one promo
+func (repo PromoRepoSql) insertPromoTickets(promo *domain.Promo, kontek context.Context) error {
+	db := executor(repo.DB, kontek)
+	for _, ticketID := range promo.TicketIDs {
+		if _, err := db.ExecContext(kontek, `INSERT INTO promo_tickets (promo_id, ticket_id) VALUES (?, ?)`, promo.ID, ticketID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get the promos that match the where clause together with their ticket scope and usage
+func (repo PromoRepoSql) queryPromos(kontek context.Context, where string, args ...any) ([]domain.Promo, error) {
+	db := executor(repo.DB, kontek)
+
+	rows, err := db.QueryContext(kontek, `SELECT id, code, kind, percent, amount_minor, currency, event_id, max_uses, max_per_user, valid_from, valid_until, created_by,
+		(SELECT COUNT(1) FROM promo_redemptions r WHERE r.promo_id = promos.id AND r.released = 0)
+		FROM promos `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promos := []domain.Promo{}
+	index := map[int]int{}
+	for rows.Next() {
+		var promo domain.Promo
+		var validFrom, validUntil int64
+		if err := rows.Scan(&promo.ID, &promo.Code, &promo.Kind, &promo.Percent, &promo.Amount.Amount, &promo.Amount.Currency, &promo.EventID,
+			&promo.MaxUses, &promo.MaxPerUser, &validFrom, &validUntil, &promo.CreatedBy, &promo.Used); err != nil {
+			return nil, err
+		}
+		promo.ValidFrom, promo.ValidUntil = timeOrNil(validFrom), timeOrNil(validUntil)
+		index[promo.ID] = len(promos)
+		promos = append(promos, promo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(promos) == 0 {
+		return promos, nil
+	}
+
+	ticketRows, err := db.QueryContext(kontek, `SELECT promo_id, ticket_id FROM promo_tickets
+		WHERE promo_id IN (SELECT id FROM promos `+where+`) ORDER BY promo_id, ticket_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer ticketRows.Close()
+
+	for ticketRows.Next() {
+		var promoID, ticketID int
+		if err := ticketRows.Scan(&promoID, &ticketID); err != nil {
+			return nil, err
+		}
+		if i, exist := index[promoID]; exist {
+			promos[i].TicketIDs = append(promos[i].TicketIDs, ticketID)
+		}
+	}
+	return promos, ticketRows.Err()
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"slices"
+	"sync"
+)
+
+// make waiting room db with map, it has its own lock so the queue never wait for the event lock.
+// the entry ID give the order the user joined in
+type QueueRepo struct {
+	Rooms       map[int]domain.WaitingRoom
+	Entries     map[int]domain.QueueEntry
+	tokens      map[string]int
+	lastEntryID *int
+	mutek       *sync.Mutex
+}
+
+func NewQueueRepo() QueueRepoInterface {
+	return QueueRepo{
+		Rooms:       map[int]domain.WaitingRoom{},
+		Entries:     map[int]domain.QueueEntry{},
+		tokens:      map[string]int{},
+		lastEntryID: new(int),
+		mutek:       &sync.Mutex{},
+	}
+}
+
+type QueueRepoInterface interface {
+	SaveWaitingRoom
+	GetWaitingRoom
+	GetActiveWaitingRooms
+	CreateQueueEntry
+	GetQueueEntryByToken
+	GetQueueEntryByUser
+	UpdateQueueEntry
+	GetQueueByStatus
+	CountQueue
+}
+type SaveWaitingRoom interface {
+	SaveWaitingRoom(room *domain.WaitingRoom, kontek context.Context) error
+}
+type GetWaitingRoom interface {
+	GetWaitingRoom(eventID int, kontek context.Context) (*domain.WaitingRoom, error)
+}
+type GetActiveWaitingRooms interface {
+	GetActiveWaitingRooms(kontek context.Context) ([]domain.WaitingRoom, error)
+}
+type CreateQueueEntry interface {
+	CreateQueueEntry(entry *domain.QueueEntry, kontek context.Context) (*domain.QueueEntry, error)
+}
+type GetQueueEntryByToken interface {
+	GetQueueEntryByToken(token string, kontek context.Context) (*domain.QueueEntry, error)
+}
+type GetQueueEntryByUser interface {
+	GetQueueEntryByUser(eventID int, userID int, kontek context.Context) (*domain.QueueEntry, error)
+}
+type UpdateQueueEntry interface {
+	UpdateQueueEntry(entry *domain.QueueEntry, kontek context.Context) error
+}
+type GetQueueByStatus interface {
+	GetQueueByStatus(eventID int, status string, limit int, kontek context.Context) ([]domain.QueueEntry, error)
+}
+type CountQueue interface {
+	CountQueue(eventID int, status string, beforeID int, kontek context.Context) (int, error)
+}
+
+// save the room of its event, the old one is replaced
+func (repo QueueRepo) SaveWaitingRoom(room *domain.WaitingRoom, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		old, exist := repo.Rooms[room.EventID]
+		saved := *room
+		saved.Waiting, saved.Admitted = 0, 0
+		repo.Rooms[room.EventID] = saved
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			if !exist {
+				delete(repo.Rooms, old.EventID)
+				return
+			}
+			repo.Rooms[old.EventID] = old
+		})
+		return nil
+	}
+}
+
+func (repo QueueRepo) GetWaitingRoom(eventID int, kontek context.Context) (*domain.WaitingRoom, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		room, exist := repo.Rooms[eventID]
+		if !exist {
+			return nil, domain.ErrRoomNotFound
+		}
+		return &room, nil
+	}
+}
+
+func (repo QueueRepo) GetActiveWaitingRooms(kontek context.Context) ([]domain.WaitingRoom, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		rooms := []domain.WaitingRoom{}
+		for _, room := range repo.Rooms {
+			if room.Active {
+				rooms = append(rooms, room)
+			}
+		}
+		slices.SortFunc(rooms, func(a, b domain.WaitingRoom) int { return a.EventID - b.EventID })
+		return rooms, nil
+	}
+}
+
+func (repo QueueRepo) CreateQueueEntry(entry *domain.QueueEntry, kontek context.Context) (*domain.QueueEntry, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		if _, exist := repo.tokens[entry.Token]; exist {
+			return nil, domain.NewError(domain.ErrConflict, "QUEUE_TOKEN_EXIST", "THAT QUEUE TOKEN IS ALREADY USED")
+		}
+		*repo.lastEntryID++
+		entry.ID = *repo.lastEntryID
+		repo.Entries[entry.ID] = *entry
+		repo.tokens[entry.Token] = entry.ID
+		entryID, token := entry.ID, entry.Token
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			delete(repo.Entries, entryID)
+			delete(repo.tokens, token)
+		})
+		return entry, nil
+	}
+}
+
+func (repo QueueRepo) GetQueueEntryByToken(token string, kontek context.Context) (*domain.QueueEntry, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		id, exist := repo.tokens[token]
+		if !exist {
+			return nil, domain.ErrQueueNotFound
+		}
+		entry := repo.Entries[id]
+		return &entry, nil
+	}
+}
+
+// the last place the user took in the queue of the event
+func (repo QueueRepo) GetQueueEntryByUser(eventID int, userID int, kontek context.Context) (*domain.QueueEntry, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		var last *domain.QueueEntry
+		for _, entry := range repo.Entries {
+			if entry.EventID == eventID && entry.UserID == userID && (last == nil || entry.ID > last.ID) {
+				last = &entry
+			}
+		}
+		if last == nil {
+			return nil, domain.ErrQueueNotFound
+		}
+		return last, nil
+	}
+}
+
+// the event, the user, the token and the join time never change
+func (repo QueueRepo) UpdateQueueEntry(entry *domain.QueueEntry, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		old, exist := repo.Entries[entry.ID]
+		if !exist {
+			return domain.ErrQueueNotFound
+		}
+		updated := old
+		updated.Status = entry.Status
+		updated.AdmittedAt = entry.AdmittedAt
+		updated.ExpiresAt = entry.ExpiresAt
+		repo.Entries[entry.ID] = updated
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			repo.Entries[old.ID] = old
+		})
+		return nil
+	}
+}
+
+// the entries of the event with that status, the oldest first, every one of them when limit is 0
+func (repo QueueRepo) GetQueueByStatus(eventID int, status string, limit int, kontek context.Context) ([]domain.QueueEntry, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		entries := []domain.QueueEntry{}
+		for _, entry := range repo.Entries {
+			if entry.EventID == eventID && entry.Status == status {
+				entries = append(entries, entry)
+			}
+		}
+		slices.SortFunc(entries, func(a, b domain.QueueEntry) int { return a.ID - b.ID })
+		if limit > 0 && len(entries) > limit {
+			entries = entries[:limit]
+		}
+		return entries, nil
+	}
+}
+
+// how many entries of the event have that status, only the one joined before beforeID when it is not 0
+func (repo QueueRepo) CountQueue(eventID int, status string, beforeID int, kontek context.Context) (int, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return 0, kontek.Err()
+	default:
+		count := 0
+		for _, entry := range repo.Entries {
+			if entry.EventID == eventID && entry.Status == status && (beforeID == 0 || entry.ID < beforeID) {
+				count++
+			}
+		}
+		return count, nil
+	}
+}
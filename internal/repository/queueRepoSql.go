@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"pemesananTiketOnlineGo/internal/domain"
+	"time"
+)
+
+// waiting room db with sqlite
+type QueueRepoSql struct {
+	DB *sql.DB
+}
+
+func NewQueueRepoSql(db *sql.DB) QueueRepoInterface {
+	return QueueRepoSql{
+		DB: db,
+	}
+}
+
+// save the room of its event, the old one is replaced
+func (repo QueueRepoSql) SaveWaitingRoom(room *domain.WaitingRoom, kontek context.Context) error {
+	_, err := executor(repo.DB, kontek).ExecContext(kontek, `INSERT OR REPLACE INTO waiting_rooms (event_id, active, admit_per_minute, admit_window_seconds, last_admit_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		room.EventID, room.Active, room.AdmitPerMinute, room.AdmitWindowSeconds, room.LastAdmitAt.UnixMilli())
+	return err
+}
+
+func (repo QueueRepoSql) GetWaitingRoom(eventID int, kontek context.Context) (*domain.WaitingRoom, error) {
+	rooms, err := repo.queryRooms(kontek, `WHERE event_id = ?`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rooms) == 0 {
+		return nil, domain.ErrRoomNotFound
+	}
+	return &rooms[0], nil
+}
+
+func (repo QueueRepoSql) GetActiveWaitingRooms(kontek context.Context) ([]domain.WaitingRoom, error) {
+	return repo.queryRooms(kontek, `WHERE active = 1`)
+}
+
+func (repo QueueRepoSql) CreateQueueEntry(entry *domain.QueueEntry, kontek context.Context) (*domain.QueueEntry, error) {
+	var count int
+	if err := executor(repo.DB, kontek).QueryRowContext(kontek, `SELECT COUNT(*) FROM queue_entries WHERE token = ?`, entry.Token).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, domain.NewError(domain.ErrConflict, "QUEUE_TOKEN_EXIST", "THAT QUEUE TOKEN IS ALREADY USED")
+	}
+
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `INSERT INTO queue_entries (event_id, user_id, token, status, joined_at, admitted_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.EventID, entry.UserID, entry.Token, entry.Status, entry.JoinedAt.UnixMilli(), millisOrZero(entry.AdmittedAt), millisOrZero(entry.ExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	entry.ID = int(id)
+	return entry, nil
+}
+
+func (repo QueueRepoSql) GetQueueEntryByToken(token string, kontek context.Context) (*domain.QueueEntry, error) {
+	entries, err := repo.queryQueue(kontek, `WHERE token = ?`, token)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, domain.ErrQueueNotFound
+	}
+	return &entries[0], nil
+}
+
+// the last place the user took in the queue of the event
+func (repo QueueRepoSql) GetQueueEntryByUser(eventID int, userID int, kontek context.Context) (*domain.QueueEntry, error) {
+	entries, err := repo.queryQueue(kontek, `WHERE id = (SELECT MAX(id) FROM queue_entries WHERE event_id = ? AND user_id = ?)`, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, domain.ErrQueueNotFound
+	}
+	return &entries[0], nil
+}
+
+// the event, the user, the token and the join time never change
+func (repo QueueRepoSql) UpdateQueueEntry(entry *domain.QueueEntry, kontek context.Context) error {
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `UPDATE queue_entries SET status = ?, admitted_at = ?, expires_at = ? WHERE id = ?`,
+		entry.Status, millisOrZero(entry.AdmittedAt), millisOrZero(entry.ExpiresAt), entry.ID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrQueueNotFound
+	}
+	return nil
+}
+
+// the entries of the event with that status, the oldest first, every one of them when limit is 0
+func (repo QueueRepoSql) GetQueueByStatus(eventID int, status string, limit int, kontek context.Context) ([]domain.QueueEntry, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	return repo.queryQueue(kontek, `WHERE event_id = ? AND status = ? ORDER BY id LIMIT ?`, eventID, status, limit)
+}
+
+// how many entries of the event have that status, only the one joined before beforeID when it is not 0
+func (repo QueueRepoSql) CountQueue(eventID int, status string, beforeID int, kontek context.Context) (int, error) {
+	var count int
+	err := executor(repo.DB, kontek).QueryRowContext(kontek, `SELECT COUNT(*) FROM queue_entries WHERE event_id = ? AND status = ? AND (? = 0 OR id < ?)`,
+		eventID, status, beforeID, beforeID).Scan(&count)
+	return count, err
+}
+
+// get the rooms that match the where clause
+func (repo QueueRepoSql) queryRooms(kontek context.Context, where string, args ...any) ([]domain.WaitingRoom, error) {
+	rows, err := executor(repo.DB, kontek).QueryContext(kontek, `SELECT event_id, active, admit_per_minute, admit_window_seconds, last_admit_at
+		FROM waiting_rooms `+where+` ORDER BY event_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rooms := []domain.WaitingRoom{}
+	for rows.Next() {
+		var room domain.WaitingRoom
+		var lastAdmitAt int64
+		if err := rows.Scan(&room.EventID, &room.Active, &room.AdmitPerMinute, &room.AdmitWindowSeconds, &lastAdmitAt); err != nil {
+			return nil, err
+		}
+		room.LastAdmitAt = time.UnixMilli(lastAdmitAt)
+		rooms = append(rooms, room)
+	}
+	return rooms, rows.Err()
+}
+
+// get the entries that match the where clause, it bring its own order
+func (repo QueueRepoSql) queryQueue(kontek context.Context, where string, args ...any) ([]domain.QueueEntry, error) {
+	rows, err := executor(repo.DB, kontek).QueryContext(kontek, `SELECT id, event_id, user_id, token, status, joined_at, admitted_at, expires_at
+		FROM queue_entries `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.QueueEntry{}
+	for rows.Next() {
+		var entry domain.QueueEntry
+		var joinedAt, admittedAt, expiresAt int64
+		if err := rows.Scan(&entry.ID, &entry.EventID, &entry.UserID, &entry.Token, &entry.Status, &joinedAt, &admittedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		entry.JoinedAt = time.UnixMilli(joinedAt)
+		entry.AdmittedAt = timeOrNil(admittedAt)
+		entry.ExpiresAt = timeOrNil(expiresAt)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"slices"
+	"sync"
+)
+
+// make seat db with map, a seat only change its status by one of the seat func so
+// two buyer can never get the same seat
+type SeatRepo struct {
+	Seats      map[int]domain.Seat
+	lastSeatID *int
+	mutek      *sync.Mutex
+}
+
+func NewSeatRepo() SeatRepoInterface {
+	return SeatRepo{
+		Seats:      map[int]domain.Seat{},
+		lastSeatID: new(int),
+		mutek:      &sync.Mutex{},
+	}
+}
+
+type SeatRepoInterface interface {
+	CreateSeats
+	GetSeatsByEventID
+	GetSeatsByIDs
+	HoldSeats
+	SellSeats
+	ReleaseSeats
+}
+type CreateSeats interface {
+	CreateSeats(seats []domain.Seat, kontek context.Context) ([]domain.Seat, error)
+}
+type GetSeatsByEventID interface {
+	GetSeatsByEventID(eventID int, kontek context.Context) ([]domain.Seat, error)
+}
+type GetSeatsByIDs interface {
+	GetSeatsByIDs(eventID int, ids []int, kontek context.Context) ([]domain.Seat, error)
+}
+type HoldSeats interface {
+	HoldSeats(ids []int, holdID int, kontek context.Context) error
+}
+type SellSeats interface {
+	SellSeats(ids []int, orderID int, holdID int, kontek context.Context) error
+}
+type ReleaseSeats interface {
+	ReleaseSeats(ids []int, kontek context.Context) error
+}
+
+// add new available seats, a seat that already exist in the event stop the whole list
+func (repo SeatRepo) CreateSeats(seats []domain.Seat, kontek context.Context) ([]domain.Seat, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		taken := map[domain.Seat]bool{}
+		for _, old := range repo.Seats {
+			taken[domain.Seat{EventID: old.EventID, Section: old.Section, Row: old.Row, Number: old.Number}] = true
+		}
+		for _, seat := range seats {
+			place := domain.Seat{EventID: seat.EventID, Section: seat.Section, Row: seat.Row, Number: seat.Number}
+			if taken[place] {
+				return nil, domain.ErrSeatExist
+			}
+			taken[place] = true
+		}
+		created := make([]domain.Seat, 0, len(seats))
+		for _, seat := range seats {
+			*repo.lastSeatID++
+			seat.ID = *repo.lastSeatID
+			seat.Status = domain.SeatAvailable
+			seat.HoldID, seat.OrderID = 0, 0
+			repo.Seats[seat.ID] = seat
+			created = append(created, seat)
+		}
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			for _, seat := range created {
+				delete(repo.Seats, seat.ID)
+			}
+		})
+		return created, nil
+	}
+}
+
+// every seat of the event in the order it was made
+func (repo SeatRepo) GetSeatsByEventID(eventID int, kontek context.Context) ([]domain.Seat, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		seats := []domain.Seat{}
+		for _, seat := range repo.Seats {
+			if seat.EventID == eventID {
+				seats = append(seats, seat)
+			}
+		}
+		slices.SortFunc(seats, func(a, b domain.Seat) int { return a.ID - b.ID })
+		return seats, nil
+	}
+}
+
+// the seats in the order of the ids, every one of them must be in the event
+func (repo SeatRepo) GetSeatsByIDs(eventID int, ids []int, kontek context.Context) ([]domain.Seat, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		seats := make([]domain.Seat, 0, len(ids))
+		for _, id := range ids {
+			seat, exist := repo.Seats[id]
+			if !exist || seat.EventID != eventID {
+				return nil, domain.ErrSeatNotFound
+			}
+			seats = append(seats, seat)
+		}
+		return seats, nil
+	}
+}
+
+// hold available seats for a reservation
+func (repo SeatRepo) HoldSeats(ids []int, holdID int, kontek context.Context) error {
+	return repo.changeSeats(ids, kontek, func(seat *domain.Seat) error {
+		if seat.Status != domain.SeatAvailable {
+			return domain.ErrSeatTaken
+		}
+		seat.Status = domain.SeatHeld
+		seat.HoldID = holdID
+		return nil
+	})
+}
+
+// sell available seats, or the seats held by the hold when holdID is not 0
+func (repo SeatRepo) SellSeats(ids []int, orderID int, holdID int, kontek context.Context) error {
+	return repo.changeSeats(ids, kontek, func(seat *domain.Seat) error {
+		if seat.Status != domain.SeatAvailable && (seat.Status != domain.SeatHeld || holdID == 0 || seat.HoldID != holdID) {
+			return domain.ErrSeatTaken
+		}
+		seat.Status = domain.SeatSold
+		seat.OrderID = orderID
+		seat.HoldID = 0
+		return nil
+	})
+}
+
+// make the seats available again
+func (repo SeatRepo) ReleaseSeats(ids []int, kontek context.Context) error {
+	return repo.changeSeats(ids, kontek, func(seat *domain.Seat) error {
+		seat.Status = domain.SeatAvailable
+		seat.HoldID, seat.OrderID = 0, 0
+		return nil
+	})
+}
+
+// change every seat or none of them
+func (repo SeatRepo) changeSeats(ids []int, kontek context.Context, change func(seat *domain.Seat) error) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		changed := make([]domain.Seat, 0, len(ids))
+		for _, id := range ids {
+			seat, exist := repo.Seats[id]
+			if !exist {
+				return domain.ErrSeatNotFound
+			}
+			if err := change(&seat); err != nil {
+				return err
+			}
+			changed = append(changed, seat)
+		}
+		old := make([]domain.Seat, 0, len(changed))
+		for _, seat := range changed {
+			old = append(old, repo.Seats[seat.ID])
+			repo.Seats[seat.ID] = seat
+		}
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			for i := len(old) - 1; i >= 0; i-- {
+				repo.Seats[old[i].ID] = old[i]
+			}
+		})
+		return nil
+	}
+}
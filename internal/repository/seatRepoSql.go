@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"pemesananTiketOnlineGo/internal/domain"
+)
+
+// seat db with sqlite, a seat change its status with a conditional update so
+// two buyer can never get the same seat
+type SeatRepoSql struct {
+	DB *sql.DB
+}
+
+func NewSeatRepoSql(db *sql.DB) SeatRepoInterface {
+	return SeatRepoSql{
+		DB: db,
+	}
+}
+
+// add new available seats, a seat that already exist in the event stop the whole list
+func (repo SeatRepoSql) CreateSeats(seats []domain.Seat, kontek context.Context) ([]domain.Seat, error) {
+	created := make([]domain.Seat, 0, len(seats))
+	err := inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+		for _, seat := range seats {
+			var exist int
+			if err := db.QueryRowContext(kontek, `SELECT COUNT(1) FROM seats WHERE event_id = ? AND section = ? AND row_label = ? AND number = ?`,
+				seat.EventID, seat.Section, seat.Row, seat.Number).Scan(&exist); err != nil {
+				return err
+			}
+			if exist > 0 {
+				return domain.ErrSeatExist
+			}
+
+			seat.Status = domain.SeatAvailable
+			seat.HoldID, seat.OrderID = 0, 0
+			result, err := db.ExecContext(kontek, `INSERT INTO seats (event_id, ticket_id, section, row_label, number, status) VALUES (?, ?, ?, ?, ?, ?)`,
+				seat.EventID, seat.TicketID, seat.Section, seat.Row, seat.Number, seat.Status)
+			if err != nil {
+				return err
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			seat.ID = int(id)
+			created = append(created, seat)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// every seat of the event in the order it was made
+func (repo SeatRepoSql) GetSeatsByEventID(eventID int, kontek context.Context) ([]domain.Seat, error) {
+	return repo.querySeats(kontek, `WHERE event_id = ?`, eventID)
+}
+
+// the seats in the order of the ids, every one of them must be in the event
+func (repo SeatRepoSql) GetSeatsByIDs(eventID int, ids []int, kontek context.Context) ([]domain.Seat, error) {
+	seats := make([]domain.Seat, 0, len(ids))
+	for _, id := range ids {
+		found, err := repo.querySeats(kontek, `WHERE id = ? AND event_id = ?`, id, eventID)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return nil, domain.ErrSeatNotFound
+		}
+		seats = append(seats, found[0])
+	}
+	return seats, nil
+}
+
+// hold available seats for a reservation
+func (repo SeatRepoSql) HoldSeats(ids []int, holdID int, kontek context.Context) error {
+	return repo.changeSeats(ids, kontek, `UPDATE seats SET status = ?, hold_id = ? WHERE id = ? AND status = ?`,
+		func(id int) []any { return []any{domain.SeatHeld, holdID, id, domain.SeatAvailable} })
+}
+
+// sell available seats, or the seats held by the hold when holdID is not 0
+func (repo SeatRepoSql) SellSeats(ids []int, orderID int, holdID int, kontek context.Context) error {
+	return repo.changeSeats(ids, kontek, `UPDATE seats SET status = ?, order_id = ?, hold_id = 0
+		WHERE id = ? AND (status = ? OR (status = ? AND hold_id = ? AND hold_id != 0))`,
+		func(id int) []any {
+			return []any{domain.SeatSold, orderID, id, domain.SeatAvailable, domain.SeatHeld, holdID}
+		})
+}
+
+// make the seats available again
+func (repo SeatRepoSql) ReleaseSeats(ids []int, kontek context.Context) error {
+	return repo.changeSeats(ids, kontek, `UPDATE seats SET status = ?, hold_id = 0, order_id = 0 WHERE id = ?`,
+		func(id int) []any { return []any{domain.SeatAvailable, id} })
+}
+
+// change every seat or none of them, a seat that doesn't match the condition is taken by somebody else
+func (repo SeatRepoSql) changeSeats(ids []int, kontek context.Context, query string, args func(id int) []any) error {
+	return inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		db := executor(repo.DB, kontek)
+		for _, id := range ids {
+			result, err := db.ExecContext(kontek, query, args(id)...)
+			if err != nil {
+				return err
+			}
+			if affected, _ := result.RowsAffected(); affected > 0 {
+				continue
+			}
+			var exist int
+			if err := db.QueryRowContext(kontek, `SELECT COUNT(1) FROM seats WHERE id = ?`, id).Scan(&exist); err != nil {
+				return err
+			}
+			if exist == 0 {
+				return domain.ErrSeatNotFound
+			}
+			return domain.ErrSeatTaken
+		}
+		return nil
+	})
+}
+
+// get the seats that match the where clause
+func (repo SeatRepoSql) querySeats(kontek context.Context, where string, args ...any) ([]domain.Seat, error) {
+	rows, err := executor(repo.DB, kontek).QueryContext(kontek, `SELECT id, event_id, ticket_id, section, row_label, number, status, hold_id, order_id
+		FROM seats `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seats := []domain.Seat{}
+	for rows.Next() {
+		var seat domain.Seat
+		if err := rows.Scan(&seat.ID, &seat.EventID, &seat.TicketID, &seat.Section, &seat.Row, &seat.Number, &seat.Status, &seat.HoldID, &seat.OrderID); err != nil {
+			return nil, err
+		}
+		seats = append(seats, seat)
+	}
+	return seats, rows.Err()
+}
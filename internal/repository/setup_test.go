@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// every storage backend the repo has
+var backends = []string{"memory", "sqlite"}
+
+// a fresh sqlite file for one test, nil for the memory backend
+func openTestDb(t *testing.T, backend string) *sql.DB {
+	t.Helper()
+	if backend == "memory" {
+		return nil
+	}
+	db, err := OpenSqlite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestEventRepo(db *sql.DB) EventRepoInterface {
+	if db == nil {
+		return NewEventRepo()
+	}
+	return NewEventRepoSql(db)
+}
+
+func newTestWalletRepo(db *sql.DB) WalletRepoInterface {
+	if db == nil {
+		return NewWalletRepo()
+	}
+	return NewWalletRepoSql(db)
+}
+
+func newTestUnitOfWork(db *sql.DB) UnitOfWorkInterface {
+	if db == nil {
+		return NewUnitOfWork()
+	}
+	return NewUnitOfWorkSql(db)
+}
+
+func newTestOrderRepo(db *sql.DB) OrderRepoInterface {
+	if db == nil {
+		return NewOrderRepo()
+	}
+	return NewOrderRepoSql(db)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"pemesananTiketOnlineGo/internal/domain"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// how many lock the scope key are spread on, two key on the same stripe just wait for each other
+const scopeStripes = 256
+
+// unit of work so a purchase across event, user and order repo commit or rollback together.
+// a transaction lock everything, a scope only lock the stripe of its key so purchase of
+// different event and user run at the same time
+type UnitOfWork struct {
+	mutek   *sync.RWMutex
+	stripes []sync.Mutex
+}
+
+func NewUnitOfWork() UnitOfWorkInterface {
+	return UnitOfWork{
+		mutek:   &sync.RWMutex{},
+		stripes: make([]sync.Mutex, scopeStripes),
+	}
+}
+
+type UnitOfWorkInterface interface {
+	WithinTransaction
+	WithinScope
+}
+type WithinTransaction interface {
+	WithinTransaction(kontek context.Context, fn func(kontek context.Context) error) error
+}
+type WithinScope interface {
+	WithinScope(kontek context.Context, keys []string, fn func(kontek context.Context) error) error
+}
+
+// the key of a scope, every write in the scope must belong to one of its key
+func EventKey(eventID int) string {
+	return fmt.Sprintf("event:%d", eventID)
+}
+
+func UserKey(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+func PromoKey(code string) string {
+	return "promo:" + strings.ToUpper(strings.TrimSpace(code))
+}
+
+// journal keep the undo func of every write that happen inside a transaction,
+// and what to do once it is committed
+type journal struct {
+	undo []func()
+	done []func()
+}
+
+// get the journal from kontek, nil when the call is not inside a transaction
+func journalFrom(kontek context.Context) *journal {
+	jurnal, _ := kontek.Value(domain.Key("tx")).(*journal)
+	return jurnal
+}
+
+// register undo func so the write can be reverted when the transaction fail
+func onRollback(kontek context.Context, undo func()) {
+	if jurnal := journalFrom(kontek); jurnal != nil {
+		jurnal.undo = append(jurnal.undo, undo)
+	}
+}
+
+// run fn once the transaction of kontek is committed, or at once outside of a transaction.
+// fn is dropped when the transaction roll back, so nothing outside see a write that never happen
+func AfterCommit(kontek context.Context, fn func()) {
+	if jurnal := journalFrom(kontek); jurnal != nil {
+		jurnal.done = append(jurnal.done, fn)
+		return
+	}
+	if done, ok := kontek.Value(domain.Key("sqltxdone")).(*[]func()); ok {
+		*done = append(*done, fn)
+		return
+	}
+	fn()
+}
+
+func (uow UnitOfWork) WithinTransaction(kontek context.Context, fn func(kontek context.Context) error) error {
+	// already inside a transaction, just join it
+	if journalFrom(kontek) != nil {
+		return fn(kontek)
+	}
+
+	uow.mutek.Lock()
+	defer uow.mutek.Unlock()
+	return run(kontek, fn)
+}
+
+// transaction that only wait for the other scope sharing one of its key and for the full transaction,
+// the stripe are always locked from the lowest so two scope never wait on each other
+func (uow UnitOfWork) WithinScope(kontek context.Context, keys []string, fn func(kontek context.Context) error) error {
+	if journalFrom(kontek) != nil {
+		return fn(kontek)
+	}
+
+	stripes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		hash := fnv.New32a()
+		hash.Write([]byte(key))
+		stripes = append(stripes, int(hash.Sum32()%scopeStripes))
+	}
+	slices.Sort(stripes)
+	stripes = slices.Compact(stripes)
+
+	uow.mutek.RLock()
+	defer uow.mutek.RUnlock()
+	for _, stripe := range stripes {
+		uow.stripes[stripe].Lock()
+		defer uow.stripes[stripe].Unlock()
+	}
+	return run(kontek, fn)
+}
+
+// run fn with a new journal, the write are reverted from the newest one when it fail
+func run(kontek context.Context, fn func(kontek context.Context) error) error {
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		jurnal := &journal{}
+		err := fn(context.WithValue(kontek, domain.Key("tx"), jurnal))
+		if err != nil {
+			for i := len(jurnal.undo) - 1; i >= 0; i-- {
+				jurnal.undo[i]()
+			}
+			return err
+		}
+		for _, done := range jurnal.done {
+			done()
+		}
+		return nil
+	}
+}
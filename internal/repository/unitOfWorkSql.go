@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// unit of work on top of sqlite transaction
+type UnitOfWorkSql struct {
+	DB *sql.DB
+}
+
+func NewUnitOfWorkSql(db *sql.DB) UnitOfWorkInterface {
+	return UnitOfWorkSql{
+		DB: db,
+	}
+}
+
+func (uow UnitOfWorkSql) WithinTransaction(kontek context.Context, fn func(kontek context.Context) error) error {
+	return inSqlTx(uow.DB, kontek, fn)
+}
+
+// sqlite already lock the database for every write, so the scope is just a transaction
+func (uow UnitOfWorkSql) WithinScope(kontek context.Context, keys []string, fn func(kontek context.Context) error) error {
+	return inSqlTx(uow.DB, kontek, fn)
+}
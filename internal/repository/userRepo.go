@@ -2,21 +2,22 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"pemesananTiketOnlineGo/internal/domain"
 	"sync"
 )
 
-// make User db with map
+// make User db with map, the ID come from a counter so a deleted user never give its ID to a new one
 type UserRepo struct {
-	Users map[int]domain.User
-	mutek *sync.Mutex
+	Users      map[int]domain.User
+	lastUserID *int
+	mutek      *sync.RWMutex
 }
 
 func NewUserRepo() UserRepoInterface {
 	return UserRepo{
-		Users: map[int]domain.User{},
-		mutek: &sync.Mutex{},
+		Users:      map[int]domain.User{},
+		lastUserID: new(int),
+		mutek:      &sync.RWMutex{},
 	}
 }
 
@@ -27,7 +28,6 @@ type UserRepoInterface interface {
 	UpdateUser
 	DeleteUser
 	GetAllUsers
-	DecreaseBalance
 }
 type CreateUser interface {
 	CreateUser(User *domain.User, kontek context.Context) (*domain.User, error)
@@ -47,9 +47,6 @@ type DeleteUser interface {
 type GetAllUsers interface {
 	GetAllUsers(kontek context.Context) ([]domain.User, error)
 }
-type DecreaseBalance interface {
-	DecreaseBalance(userID int, totalAmount float64, kontek context.Context) (*domain.User, error)
-}
 
 func (repo UserRepo) CreateUser(User *domain.User, kontek context.Context) (*domain.User, error) {
 	repo.mutek.Lock()
@@ -58,11 +55,11 @@ func (repo UserRepo) CreateUser(User *domain.User, kontek context.Context) (*dom
 	case <-kontek.Done():
 		return nil, kontek.Err()
 	default:
-		if repo.Users == nil || len(repo.Users) == 0 {
-			User.ID = 1
-		} else {
-			User.ID = repo.Users[len(repo.Users)].ID + 1
+		if repo.nameTaken(User.Name, 0) {
+			return nil, domain.ErrUserNameExist
 		}
+		*repo.lastUserID++
+		User.ID = *repo.lastUserID
 		repo.Users[User.ID] = *User
 		return User, nil
 	}
@@ -70,24 +67,23 @@ func (repo UserRepo) CreateUser(User *domain.User, kontek context.Context) (*dom
 }
 
 func (repo UserRepo) GetUserByID(id int, kontek context.Context) (*domain.User, error) {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
 	select {
 	case <-kontek.Done():
 		return nil, kontek.Err()
 	default:
-		for _, User := range repo.Users {
-			if User.ID == id {
-				return &User, nil
-			}
+		User, exist := repo.Users[id]
+		if !exist {
+			return nil, domain.ErrUserNotFound
 		}
-		return nil, errors.New("THERE'S NO USER WITH THAT ID🤬🚨🤬🚨")
+		return &User, nil
 	}
 }
 
 func (repo UserRepo) GetUserByName(name string, kontek context.Context) (*domain.User, error) {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
 	select {
 	case <-kontek.Done():
 		return nil, kontek.Err()
@@ -97,7 +93,7 @@ func (repo UserRepo) GetUserByName(name string, kontek context.Context) (*domain
 				return &User, nil
 			}
 		}
-		return nil, errors.New("THERE'S NO USER WITH THAT NAME🤬🚨🤬🚨")
+		return nil, domain.ErrUserNameNotFound
 	}
 }
 
@@ -109,7 +105,10 @@ func (repo UserRepo) UpdateUser(User *domain.User, kontek context.Context) error
 		return kontek.Err()
 	default:
 		if _, exist := repo.Users[User.ID]; !exist {
-			return errors.New("THERE'S NO USER WITH THAT ID🤬🤬🤬🚨🚨")
+			return domain.ErrUserNotFound
+		}
+		if repo.nameTaken(User.Name, User.ID) {
+			return domain.ErrUserNameExist
 		}
 		repo.Users[User.ID] = *User
 		return nil
@@ -124,7 +123,7 @@ func (repo UserRepo) DeleteUser(id int, kontek context.Context) error {
 		return kontek.Err()
 	default:
 		if _, exist := repo.Users[id]; !exist {
-			return errors.New("THERE'S NO USER WITH THAT ID🤬🚨🤬🚨")
+			return domain.ErrUserNotFound
 		}
 		delete(repo.Users, id)
 		return nil
@@ -132,8 +131,8 @@ func (repo UserRepo) DeleteUser(id int, kontek context.Context) error {
 }
 
 func (repo UserRepo) GetAllUsers(kontek context.Context) ([]domain.User, error) {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
 	select {
 	case <-kontek.Done():
 		return nil, kontek.Err()
@@ -146,22 +145,12 @@ func (repo UserRepo) GetAllUsers(kontek context.Context) ([]domain.User, error)
 	}
 }
 
-func (repo UserRepo) DecreaseBalance(userID int, totalAmount float64, kontek context.Context) (*domain.User, error) {
-	repo.mutek.Lock()
-	defer repo.mutek.Unlock()
-	select {
-	case <-kontek.Done():
-		return nil, kontek.Err()
-	default:
-		user, exist := repo.Users[userID]
-		if !exist {
-			return nil, errors.New("THERE'S NO USER WITH THAT ID🤬🤬🤬🚨🚨")
-		}
-		if user.Balance < totalAmount {
-			return nil, errors.New("INSUFFICIENT BALANCE🤬🤬🤬🚨🚨")
+// the name is used to login so it must be unique, exceptID is the user itself
+func (repo UserRepo) nameTaken(name string, exceptID int) bool {
+	for _, User := range repo.Users {
+		if User.Name == name && User.ID != exceptID {
+			return true
 		}
-		user.Balance -= totalAmount
-		repo.Users[userID] = user
-		return &user, nil
 	}
+	return false
 }
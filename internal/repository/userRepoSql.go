@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"pemesananTiketOnlineGo/internal/domain"
+)
+
+// user db with sqlite
+type UserRepoSql struct {
+	DB *sql.DB
+}
+
+func NewUserRepoSql(db *sql.DB) UserRepoInterface {
+	return UserRepoSql{
+		DB: db,
+	}
+}
+
+func (repo UserRepoSql) CreateUser(User *domain.User, kontek context.Context) (*domain.User, error) {
+	err := inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		if err := repo.checkNameTaken(User.Name, 0, kontek); err != nil {
+			return err
+		}
+		result, err := executor(repo.DB, kontek).ExecContext(kontek, `INSERT INTO users (name, password_hash, role) VALUES (?, ?, ?)`,
+			User.Name, User.PasswordHash, User.Role)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		User.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return User, nil
+}
+
+func (repo UserRepoSql) GetUserByID(id int, kontek context.Context) (*domain.User, error) {
+	users, err := repo.queryUsers(kontek, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, domain.ErrUserNotFound
+	}
+	return &users[0], nil
+}
+
+func (repo UserRepoSql) GetUserByName(name string, kontek context.Context) (*domain.User, error) {
+	users, err := repo.queryUsers(kontek, `WHERE name = ?`, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, domain.ErrUserNameNotFound
+	}
+	return &users[0], nil
+}
+
+func (repo UserRepoSql) UpdateUser(User *domain.User, kontek context.Context) error {
+	return inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		if err := repo.checkNameTaken(User.Name, User.ID, kontek); err != nil {
+			return err
+		}
+		result, err := executor(repo.DB, kontek).ExecContext(kontek, `UPDATE users SET name = ?, password_hash = ?, role = ? WHERE id = ?`,
+			User.Name, User.PasswordHash, User.Role, User.ID)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return domain.ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+func (repo UserRepoSql) DeleteUser(id int, kontek context.Context) error {
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (repo UserRepoSql) GetAllUsers(kontek context.Context) ([]domain.User, error) {
+	return repo.queryUsers(kontek, ``)
+}
+
+// get the users that match the where clause
+func (repo UserRepoSql) queryUsers(kontek context.Context, where string, args ...any) ([]domain.User, error) {
+	rows, err := executor(repo.DB, kontek).QueryContext(kontek, `SELECT id, name, password_hash, role FROM users `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []domain.User{}
+	for rows.Next() {
+		var user domain.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.PasswordHash, &user.Role); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// the name is used to login so it must be unique, exceptID is the user itself
+func (repo UserRepoSql) checkNameTaken(name string, exceptID int, kontek context.Context) error {
+	var count int
+	err := executor(repo.DB, kontek).QueryRowContext(kontek, `SELECT COUNT(*) FROM users WHERE name = ? AND id != ?`, name, exceptID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return domain.ErrUserNameExist
+	}
+	return nil
+}
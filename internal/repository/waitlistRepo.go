@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"slices"
+	"sync"
+)
+
+// make waitlist db with map, the entry ID give the order the user joined in
+type WaitlistRepo struct {
+	Entries     map[int]domain.WaitlistEntry
+	lastEntryID *int
+	mutek       *sync.Mutex
+}
+
+func NewWaitlistRepo() WaitlistRepoInterface {
+	return WaitlistRepo{
+		Entries:     map[int]domain.WaitlistEntry{},
+		lastEntryID: new(int),
+		mutek:       &sync.Mutex{},
+	}
+}
+
+type WaitlistRepoInterface interface {
+	CreateWaitlistEntry
+	GetWaitlistEntryByID
+	UpdateWaitlistEntry
+	GetWaitlistByUserID
+	GetOpenWaitlist
+}
+type CreateWaitlistEntry interface {
+	CreateWaitlistEntry(entry *domain.WaitlistEntry, kontek context.Context) (*domain.WaitlistEntry, error)
+}
+type GetWaitlistEntryByID interface {
+	GetWaitlistEntryByID(id int, kontek context.Context) (*domain.WaitlistEntry, error)
+}
+type UpdateWaitlistEntry interface {
+	UpdateWaitlistEntry(entry *domain.WaitlistEntry, kontek context.Context) error
+}
+type GetWaitlistByUserID interface {
+	GetWaitlistByUserID(userID int, kontek context.Context) ([]domain.WaitlistEntry, error)
+}
+type GetOpenWaitlist interface {
+	GetOpenWaitlist(ticketID int, kontek context.Context) ([]domain.WaitlistEntry, error)
+}
+
+func (repo WaitlistRepo) CreateWaitlistEntry(entry *domain.WaitlistEntry, kontek context.Context) (*domain.WaitlistEntry, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		*repo.lastEntryID++
+		entry.ID = *repo.lastEntryID
+		repo.Entries[entry.ID] = *entry
+		entryID := entry.ID
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			delete(repo.Entries, entryID)
+		})
+		return entry, nil
+	}
+}
+
+func (repo WaitlistRepo) GetWaitlistEntryByID(id int, kontek context.Context) (*domain.WaitlistEntry, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		entry, exist := repo.Entries[id]
+		if !exist {
+			return nil, domain.ErrWaitlistNotFound
+		}
+		return &entry, nil
+	}
+}
+
+func (repo WaitlistRepo) UpdateWaitlistEntry(entry *domain.WaitlistEntry, kontek context.Context) error {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return kontek.Err()
+	default:
+		old, exist := repo.Entries[entry.ID]
+		if !exist {
+			return domain.ErrWaitlistNotFound
+		}
+		repo.Entries[entry.ID] = *entry
+		onRollback(kontek, func() {
+			repo.mutek.Lock()
+			defer repo.mutek.Unlock()
+			repo.Entries[old.ID] = old
+		})
+		return nil
+	}
+}
+
+// every entry of the user, the oldest first
+func (repo WaitlistRepo) GetWaitlistByUserID(userID int, kontek context.Context) ([]domain.WaitlistEntry, error) {
+	return repo.filter(kontek, func(entry domain.WaitlistEntry) bool { return entry.UserID == userID })
+}
+
+// the waiting and offered entry of the ticket type, or of every ticket type when ticketID is 0, the oldest first
+func (repo WaitlistRepo) GetOpenWaitlist(ticketID int, kontek context.Context) ([]domain.WaitlistEntry, error) {
+	return repo.filter(kontek, func(entry domain.WaitlistEntry) bool {
+		return (ticketID == 0 || entry.TicketID == ticketID) && (entry.Status == domain.WaitlistWaiting || entry.Status == domain.WaitlistOffered)
+	})
+}
+
+func (repo WaitlistRepo) filter(kontek context.Context, keep func(entry domain.WaitlistEntry) bool) ([]domain.WaitlistEntry, error) {
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		entries := []domain.WaitlistEntry{}
+		for _, entry := range repo.Entries {
+			if keep(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		slices.SortFunc(entries, func(a, b domain.WaitlistEntry) int { return a.ID - b.ID })
+		return entries, nil
+	}
+}
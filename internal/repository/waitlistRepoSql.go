@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"pemesananTiketOnlineGo/internal/domain"
+	"time"
+)
+
+// waitlist db with sqlite
+type WaitlistRepoSql struct {
+	DB *sql.DB
+}
+
+func NewWaitlistRepoSql(db *sql.DB) WaitlistRepoInterface {
+	return WaitlistRepoSql{
+		DB: db,
+	}
+}
+
+func (repo WaitlistRepoSql) CreateWaitlistEntry(entry *domain.WaitlistEntry, kontek context.Context) (*domain.WaitlistEntry, error) {
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `INSERT INTO waitlist (user_id, event_id, ticket_id, quantity, status, joined_at, hold_id, offer_expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.UserID, entry.EventID, entry.TicketID, entry.Quantity, entry.Status, entry.JoinedAt.UnixMilli(), entry.HoldID, millisOrZero(entry.OfferExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	entry.ID = int(id)
+	return entry, nil
+}
+
+func (repo WaitlistRepoSql) GetWaitlistEntryByID(id int, kontek context.Context) (*domain.WaitlistEntry, error) {
+	entries, err := repo.queryWaitlist(kontek, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, domain.ErrWaitlistNotFound
+	}
+	return &entries[0], nil
+}
+
+// the user, the ticket and the join time never change
+func (repo WaitlistRepoSql) UpdateWaitlistEntry(entry *domain.WaitlistEntry, kontek context.Context) error {
+	result, err := executor(repo.DB, kontek).ExecContext(kontek, `UPDATE waitlist SET status = ?, hold_id = ?, offer_expires_at = ? WHERE id = ?`,
+		entry.Status, entry.HoldID, millisOrZero(entry.OfferExpiresAt), entry.ID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrWaitlistNotFound
+	}
+	return nil
+}
+
+// every entry of the user, the oldest first
+func (repo WaitlistRepoSql) GetWaitlistByUserID(userID int, kontek context.Context) ([]domain.WaitlistEntry, error) {
+	return repo.queryWaitlist(kontek, `WHERE user_id = ?`, userID)
+}
+
+// the waiting and offered entry of the ticket type, or of every ticket type when ticketID is 0, the oldest first
+func (repo WaitlistRepoSql) GetOpenWaitlist(ticketID int, kontek context.Context) ([]domain.WaitlistEntry, error) {
+	return repo.queryWaitlist(kontek, `WHERE (? = 0 OR ticket_id = ?) AND status IN (?, ?)`, ticketID, ticketID, domain.WaitlistWaiting, domain.WaitlistOffered)
+}
+
+// get the entries that match the where clause
+func (repo WaitlistRepoSql) queryWaitlist(kontek context.Context, where string, args ...any) ([]domain.WaitlistEntry, error) {
+	rows, err := executor(repo.DB, kontek).QueryContext(kontek, `SELECT id, user_id, event_id, ticket_id, quantity, status, joined_at, hold_id, offer_expires_at
+		FROM waitlist `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.WaitlistEntry{}
+	for rows.Next() {
+		var entry domain.WaitlistEntry
+		var joinedAt, offerExpiresAt int64
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.EventID, &entry.TicketID, &entry.Quantity, &entry.Status, &joinedAt, &entry.HoldID, &offerExpiresAt); err != nil {
+			return nil, err
+		}
+		entry.JoinedAt = time.UnixMilli(joinedAt)
+		entry.OfferExpiresAt = timeOrNil(offerExpiresAt)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// make the wallet ledger with map, entry is only added never changed. the ID come from a counter
+// like the order. every user has its own account with its own lock and a running balance, so the
+// debit of one user never wait for another user. the map lock is only taken for writing when the
+// first entry of a user is added
+type WalletRepo struct {
+	accounts    map[int]*walletAccount
+	lastEntryID *atomic.Int64
+	mutek       *sync.RWMutex
+}
+
+// the entries of one user, oldest first, and their sum
+type walletAccount struct {
+	entries []domain.WalletEntry
+	balance domain.Money
+	mutek   sync.Mutex
+}
+
+func NewWalletRepo() WalletRepoInterface {
+	return WalletRepo{
+		accounts:    map[int]*walletAccount{},
+		lastEntryID: &atomic.Int64{},
+		mutek:       &sync.RWMutex{},
+	}
+}
+
+type WalletRepoInterface interface {
+	AddEntry
+	GetBalance
+	GetEntriesByUserID
+	GetAllEntries
+}
+type AddEntry interface {
+	AddEntry(entry *domain.WalletEntry, kontek context.Context) (*domain.WalletEntry, error)
+}
+type GetBalance interface {
+	GetBalance(userID int, kontek context.Context) (domain.Money, error)
+}
+type GetEntriesByUserID interface {
+	GetEntriesByUserID(userID int, kontek context.Context) ([]domain.WalletEntry, error)
+}
+type GetAllEntries interface {
+	GetAllEntries(kontek context.Context) ([]domain.WalletEntry, error)
+}
+
+// add an entry to the ledger, money out fail when the balance is not enough
+func (repo WalletRepo) AddEntry(entry *domain.WalletEntry, kontek context.Context) (*domain.WalletEntry, error) {
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		account := repo.openAccount(entry.UserID)
+		account.mutek.Lock()
+		defer account.mutek.Unlock()
+		balance, err := account.balance.Add(entry.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Amount.IsNegative() && balance.IsNegative() {
+			return nil, domain.ErrNotEnoughBalance
+		}
+		entry.ID = int(repo.lastEntryID.Add(1))
+		entry.CreatedAt = time.Now()
+		account.entries = append(account.entries, *entry)
+		account.balance = balance
+		entryID := entry.ID
+		onRollback(kontek, func() {
+			account.mutek.Lock()
+			defer account.mutek.Unlock()
+			account.entries = slices.DeleteFunc(account.entries, func(entry domain.WalletEntry) bool { return entry.ID == entryID })
+			account.balance = sumEntries(account.entries)
+		})
+		return entry, nil
+	}
+}
+
+func (repo WalletRepo) GetBalance(userID int, kontek context.Context) (domain.Money, error) {
+	select {
+	case <-kontek.Done():
+		return domain.Money{}, kontek.Err()
+	default:
+		account := repo.account(userID)
+		if account == nil {
+			return domain.NewMoney(0, domain.DefaultCurrency), nil
+		}
+		account.mutek.Lock()
+		defer account.mutek.Unlock()
+		return account.balance, nil
+	}
+}
+
+// func to get the entries of a user, oldest first
+func (repo WalletRepo) GetEntriesByUserID(userID int, kontek context.Context) ([]domain.WalletEntry, error) {
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		account := repo.account(userID)
+		if account == nil {
+			return []domain.WalletEntry{}, nil
+		}
+		account.mutek.Lock()
+		defer account.mutek.Unlock()
+		return slices.Clone(account.entries), nil
+	}
+}
+
+// func to get the whole ledger, oldest first
+func (repo WalletRepo) GetAllEntries(kontek context.Context) ([]domain.WalletEntry, error) {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	select {
+	case <-kontek.Done():
+		return nil, kontek.Err()
+	default:
+		entries := []domain.WalletEntry{}
+		for _, account := range repo.accounts {
+			account.mutek.Lock()
+			entries = append(entries, account.entries...)
+			account.mutek.Unlock()
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+		return entries, nil
+	}
+}
+
+// the account of the user, nil when it has no entry yet
+func (repo WalletRepo) account(userID int) *walletAccount {
+	repo.mutek.RLock()
+	defer repo.mutek.RUnlock()
+	return repo.accounts[userID]
+}
+
+// the account of the user, made on its first entry
+func (repo WalletRepo) openAccount(userID int) *walletAccount {
+	if account := repo.account(userID); account != nil {
+		return account
+	}
+	repo.mutek.Lock()
+	defer repo.mutek.Unlock()
+	account, exist := repo.accounts[userID]
+	if !exist {
+		account = &walletAccount{balance: domain.NewMoney(0, domain.DefaultCurrency)}
+		repo.accounts[userID] = account
+	}
+	return account
+}
+
+// sum of the entries left after a rollback, the entries were all summed before so an overflow is
+// not checked again
+func sumEntries(entries []domain.WalletEntry) domain.Money {
+	balance := domain.NewMoney(0, domain.DefaultCurrency)
+	for _, entry := range entries {
+		balance, _ = balance.Add(entry.Amount)
+	}
+	return balance
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"pemesananTiketOnlineGo/internal/domain"
+	"time"
+)
+
+// wallet ledger with sqlite
+type WalletRepoSql struct {
+	DB *sql.DB
+}
+
+func NewWalletRepoSql(db *sql.DB) WalletRepoInterface {
+	return WalletRepoSql{
+		DB: db,
+	}
+}
+
+// add an entry to the ledger, money out fail when the balance is not enough
+func (repo WalletRepoSql) AddEntry(entry *domain.WalletEntry, kontek context.Context) (*domain.WalletEntry, error) {
+	err := inSqlTx(repo.DB, kontek, func(kontek context.Context) error {
+		balance, err := repo.GetBalance(entry.UserID, kontek)
+		if err != nil {
+			return err
+		}
+		if balance, err = balance.Add(entry.Amount); err != nil {
+			return err
+		}
+		if entry.Amount.IsNegative() && balance.IsNegative() {
+			return domain.ErrNotEnoughBalance
+		}
+
+		entry.CreatedAt = time.Now()
+		result, err := executor(repo.DB, kontek).ExecContext(kontek, `INSERT INTO wallet_entries (user_id, kind, amount_minor, currency, order_id, note, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			entry.UserID, entry.Kind, entry.Amount.Amount, currencyOf(entry.Amount), entry.OrderID, entry.Note, entry.CreatedAt.UnixMilli())
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		entry.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// sum per currency, the wallet hold only one currency so more than one row is a mismatch
+func (repo WalletRepoSql) GetBalance(userID int, kontek context.Context) (domain.Money, error) {
+	rows, err := executor(repo.DB, kontek).QueryContext(kontek, `SELECT currency, SUM(amount_minor) FROM wallet_entries WHERE user_id = ? GROUP BY currency`, userID)
+	if err != nil {
+		return domain.Money{}, err
+	}
+	defer rows.Close()
+
+	balance := domain.NewMoney(0, domain.DefaultCurrency)
+	for rows.Next() {
+		var sum domain.Money
+		if err := rows.Scan(&sum.Currency, &sum.Amount); err != nil {
+			return domain.Money{}, err
+		}
+		if balance, err = balance.Add(sum); err != nil {
+			return domain.Money{}, err
+		}
+	}
+	return balance, rows.Err()
+}
+
+// func to get the entries of a user, oldest first
+func (repo WalletRepoSql) GetEntriesByUserID(userID int, kontek context.Context) ([]domain.WalletEntry, error) {
+	return repo.queryEntries(kontek, `WHERE user_id = ?`, userID)
+}
+
+// func to get the whole ledger, oldest first
+func (repo WalletRepoSql) GetAllEntries(kontek context.Context) ([]domain.WalletEntry, error) {
+	return repo.queryEntries(kontek, ``)
+}
+
+// get the entries that match the where clause
+func (repo WalletRepoSql) queryEntries(kontek context.Context, where string, args ...any) ([]domain.WalletEntry, error) {
+	rows, err := executor(repo.DB, kontek).QueryContext(kontek, `SELECT id, user_id, kind, amount_minor, currency, order_id, note, created_at FROM wallet_entries `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []domain.WalletEntry{}
+	for rows.Next() {
+		var entry domain.WalletEntry
+		var createdAt int64
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Kind, &entry.Amount.Amount, &entry.Amount.Currency, &entry.OrderID, &entry.Note, &createdAt); err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = time.UnixMilli(createdAt)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"pemesananTiketOnlineGo/internal/domain"
+	"sync"
+	"testing"
+)
+
+// many purchase debit the same wallet at the same time, the balance never go below zero
+// and the ledger keep exactly the debit that went through
+func TestAddEntryParallel(t *testing.T) {
+	cases := []struct {
+		name    string
+		balance int64
+		debits  int
+		amount  int64
+	}{
+		{name: "enough for half", balance: 50000, debits: 20, amount: 5000},
+		{name: "enough for all", balance: 200000, debits: 20, amount: 5000},
+		{name: "amount over the rest", balance: 50000, debits: 20, amount: 7000},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				walletRepo := newTestWalletRepo(openTestDb(t, backend))
+				kontek := context.Background()
+				userID := 1
+
+				if _, err := walletRepo.AddEntry(&domain.WalletEntry{UserID: userID, Kind: domain.WalletTopUp, Amount: domain.NewMoney(tc.balance, domain.DefaultCurrency)}, kontek); err != nil {
+					t.Fatal(err)
+				}
+
+				var mutek sync.Mutex
+				var taken int64
+				var wg sync.WaitGroup
+				for i := 1; i <= tc.debits; i++ {
+					wg.Add(1)
+					go func(orderID int) {
+						defer wg.Done()
+						_, err := walletRepo.AddEntry(&domain.WalletEntry{UserID: userID, Kind: domain.WalletPurchase, Amount: domain.NewMoney(-tc.amount, domain.DefaultCurrency), OrderID: orderID}, kontek)
+						switch {
+						case err == nil:
+							mutek.Lock()
+							taken += tc.amount
+							mutek.Unlock()
+						case !errors.Is(err, domain.ErrNotEnoughBalance):
+							t.Error(err)
+						}
+					}(i)
+				}
+				wg.Wait()
+
+				balance, err := walletRepo.GetBalance(userID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if balance.IsNegative() {
+					t.Errorf("balance is negative %s", balance)
+				}
+				if want := min(tc.balance, int64(tc.debits)*tc.amount) / tc.amount * tc.amount; taken != want {
+					t.Errorf("debited %d, want %d", taken, want)
+				}
+				if balance.Amount+taken != tc.balance {
+					t.Errorf("balance lost, %d + %d != %d", balance.Amount, taken, tc.balance)
+				}
+
+				// the purchase in the ledger add up to what the buyer was told is debited
+				entries, err := walletRepo.GetEntriesByUserID(userID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var debited int64
+				for _, entry := range entries {
+					if entry.Kind == domain.WalletPurchase {
+						debited -= entry.Amount.Amount
+					}
+				}
+				if debited != taken {
+					t.Errorf("ledger debited %d, the purchase took %d", debited, taken)
+				}
+			})
+		}
+	}
+}
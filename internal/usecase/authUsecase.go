@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// make a connection to repo, the secret sign every token
+type AuthUsecase struct {
+	UserRepo repository.UserRepoInterface
+	Secret   []byte
+	TokenTTL time.Duration
+}
+
+func NewAuthUsecase(userRepo repository.UserRepoInterface, secret []byte, tokenTTL time.Duration) AuthUsecaseInterface {
+	return AuthUsecase{
+		UserRepo: userRepo,
+		Secret:   secret,
+		TokenTTL: tokenTTL,
+	}
+}
+
+type AuthUsecaseInterface interface {
+	Login
+	Authenticate
+}
+type Login interface {
+	Login(credential domain.Credential, kontek context.Context) (*domain.Token, error)
+}
+type Authenticate interface {
+	Authenticate(token string, kontek context.Context) (*domain.User, error)
+}
+
+// header of every token, only HS256 is used
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// hash of a password nobody has, an unknown name is checked against it so it take as long as a known one
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("pemesananTiketOnlineGo"), bcrypt.DefaultCost)
+
+// check the password and give a signed token for the user
+func (uc AuthUsecase) Login(credential domain.Credential, kontek context.Context) (*domain.Token, error) {
+	user, err := uc.UserRepo.GetUserByName(credential.Name, kontek)
+	if errors.Is(err, domain.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(credential.Password))
+		return nil, domain.ErrWrongCredential
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(credential.Password)) != nil {
+		return nil, domain.ErrWrongCredential
+	}
+
+	now := time.Now()
+	claims := domain.Claims{
+		UserID:    user.ID,
+		Name:      user.Name,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(uc.TokenTTL).Unix(),
+	}
+	token, err := uc.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Token{Token: token, ExpiresAt: time.Unix(claims.ExpiresAt, 0)}, nil
+}
+
+// check the token and get the user that own it
+func (uc AuthUsecase) Authenticate(token string, kontek context.Context) (*domain.User, error) {
+	claims, err := uc.verify(token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	// the user could be deleted after the token is given
+	user, err := uc.UserRepo.GetUserByID(claims.UserID, kontek)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// token is header.claims.signature, every part is base64url without padding
+func (uc AuthUsecase) sign(claims domain.Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + uc.signature(unsigned), nil
+}
+
+func (uc AuthUsecase) verify(token string, now time.Time) (*domain.Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return nil, domain.ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(uc.signature(parts[0]+"."+parts[1]))) {
+		return nil, domain.ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, domain.ErrInvalidToken
+	}
+	var claims domain.Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, domain.ErrInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, domain.ErrTokenExpired
+	}
+	return &claims, nil
+}
+
+func (uc AuthUsecase) signature(unsigned string) string {
+	mac := hmac.New(sha256.New, uc.Secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// admin and the server itself can do everything, a context with nobody in it can do nothing
+func isAdmin(kontek context.Context) bool {
+	if domain.IsSystem(kontek) {
+		return true
+	}
+	user, ok := domain.CurrentUser(kontek)
+	return ok && user.Role == domain.RoleAdmin
+}
+
+// the logged in user can access the data of ownerID, admin and the system can access everything
+func canAccess(ownerID int, kontek context.Context) bool {
+	if isAdmin(kontek) {
+		return true
+	}
+	user, ok := domain.CurrentUser(kontek)
+	return ok && user.ID == ownerID
+}
+
+// admin can manage every event, organizer only the event they own
+func canManageEvent(event *domain.Event, kontek context.Context) bool {
+	if isAdmin(kontek) {
+		return true
+	}
+	user, ok := domain.CurrentUser(kontek)
+	return ok && user.Role == domain.RoleOrganizer && event.OrganizerID == user.ID
+}
@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// a token is valid only as it was signed, with the same secret and before it expire
+func TestVerifyToken(t *testing.T) {
+	auth := AuthUsecase{Secret: []byte("secret"), TokenTTL: time.Hour}
+	now := time.Now()
+	token, err := auth.sign(domain.Claims{UserID: 7, Name: "budi", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	// the same claims of another user, signed with the signature of the first one
+	other, err := auth.sign(domain.Claims{UserID: 8, Name: "budi", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		token  string
+		secret string
+		now    time.Time
+		code   string
+	}{
+		{name: "valid", token: token, secret: "secret", now: now},
+		{name: "a second before it expire", token: token, secret: "secret", now: now.Add(time.Hour - time.Second)},
+		{name: "expired", token: token, secret: "secret", now: now.Add(time.Hour), code: "TOKEN_EXPIRED"},
+		{name: "tampered signature", token: flipByte(token, len(parts[0])+len(parts[1])+5), secret: "secret", now: now, code: "INVALID_TOKEN"},
+		{name: "tampered claims", token: flipByte(token, len(parts[0])+3), secret: "secret", now: now, code: "INVALID_TOKEN"},
+		{name: "claims of another token", token: strings.Split(other, ".")[0] + "." + strings.Split(other, ".")[1] + "." + parts[2], secret: "secret", now: now, code: "INVALID_TOKEN"},
+		{name: "other secret", token: token, secret: "other", now: now, code: "INVALID_TOKEN"},
+		{name: "other header", token: "e30." + parts[1] + "." + parts[2], secret: "secret", now: now, code: "INVALID_TOKEN"},
+		{name: "no signature", token: parts[0] + "." + parts[1], secret: "secret", now: now, code: "INVALID_TOKEN"},
+		{name: "empty", token: "", secret: "secret", now: now, code: "INVALID_TOKEN"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := AuthUsecase{Secret: []byte(tc.secret)}.verify(tc.token, tc.now)
+			if errorCode(err) != tc.code {
+				t.Fatalf("verify got %v, want %q", err, tc.code)
+			}
+			if tc.code == "" && (claims.UserID != 7 || claims.Name != "budi") {
+				t.Errorf("claims is %+v, want user 7 budi", claims)
+			}
+		})
+	}
+}
+
+// login give a token of the user, a wrong password and an unknown name get the same answer
+func TestLogin(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			t.Parallel()
+			repos := newTestRepos(t, backend)
+			auth := NewAuthUsecase(repos.User, []byte("secret"), time.Hour)
+			kontek := context.Background()
+
+			hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+			if err != nil {
+				t.Fatal(err)
+			}
+			user, err := repos.User.CreateUser(&domain.User{Name: "budi", PasswordHash: string(hash)}, systemContext())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := auth.Login(domain.Credential{Name: "budi", Password: "password123"}, kontek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			owner, err := auth.Authenticate(token.Token, kontek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if owner.ID != user.ID {
+				t.Errorf("token belong to user %d, want %d", owner.ID, user.ID)
+			}
+
+			start := time.Now()
+			_, wrong := auth.Login(domain.Credential{Name: "budi", Password: "password124"}, kontek)
+			known := time.Since(start)
+			start = time.Now()
+			_, unknown := auth.Login(domain.Credential{Name: "nobody", Password: "password123"}, kontek)
+			missing := time.Since(start)
+			if errorCode(wrong) != "INVALID_CREDENTIAL" || errorCode(unknown) != "INVALID_CREDENTIAL" {
+				t.Errorf("wrong password got %v and unknown name got %v, want INVALID_CREDENTIAL", wrong, unknown)
+			}
+			// the unknown name still pay for a bcrypt compare, so its time doesn't tell it apart
+			if missing < known/4 {
+				t.Errorf("unknown name took %s, a wrong password took %s", missing, known)
+			}
+
+			// a token of a deleted user is no longer valid
+			if err := repos.User.DeleteUser(user.ID, systemContext()); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := auth.Authenticate(token.Token, kontek); errorCode(err) != "INVALID_TOKEN" {
+				t.Errorf("token of a deleted user got %v, want INVALID_TOKEN", err)
+			}
+		})
+	}
+}
+
+// who can do what, a context with nobody in it can do nothing
+func TestAccess(t *testing.T) {
+	event := &domain.Event{ID: 1, OrganizerID: 10}
+	cases := []struct {
+		name   string
+		kontek context.Context
+		// the access to everything, to the data of user 10 and to the event of organizer 10
+		admin, access, manage bool
+	}{
+		{name: "nobody", kontek: context.Background()},
+		{name: "system", kontek: systemContext(), admin: true, access: true, manage: true},
+		{name: "admin", kontek: domain.WithUser(context.Background(), domain.User{ID: 1, Role: domain.RoleAdmin}), admin: true, access: true, manage: true},
+		{name: "customer owner", kontek: domain.WithUser(context.Background(), domain.User{ID: 10, Role: domain.RoleCustomer}), access: true},
+		{name: "other customer", kontek: domain.WithUser(context.Background(), domain.User{ID: 11, Role: domain.RoleCustomer})},
+		{name: "organizer owner", kontek: domain.WithUser(context.Background(), domain.User{ID: 10, Role: domain.RoleOrganizer}), access: true, manage: true},
+		{name: "other organizer", kontek: domain.WithUser(context.Background(), domain.User{ID: 11, Role: domain.RoleOrganizer})},
+		{name: "no role", kontek: domain.WithUser(context.Background(), domain.User{ID: 10}), access: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isAdmin(tc.kontek); got != tc.admin {
+				t.Errorf("isAdmin is %v, want %v", got, tc.admin)
+			}
+			if got := canAccess(10, tc.kontek); got != tc.access {
+				t.Errorf("canAccess is %v, want %v", got, tc.access)
+			}
+			if got := canManageEvent(event, tc.kontek); got != tc.manage {
+				t.Errorf("canManageEvent is %v, want %v", got, tc.manage)
+			}
+		})
+	}
+}
@@ -4,16 +4,24 @@ import (
 	"context"
 	"pemesananTiketOnlineGo/internal/domain"
 	"pemesananTiketOnlineGo/internal/repository"
+	"sort"
+	"time"
 )
 
 // make a connection to repo
 type EventUsecase struct {
-	EventRepo repository.EventRepoInterface
+	EventRepo  repository.EventRepoInterface
+	HoldRepo   repository.HoldRepoInterface
+	OrderRepo  repository.OrderRepoInterface
+	UnitOfWork repository.UnitOfWorkInterface
 }
 
-func NewEventUsecase(eventRepo repository.EventRepoInterface) EventUsecaseInterface {
+func NewEventUsecase(eventRepo repository.EventRepoInterface, holdRepo repository.HoldRepoInterface, orderRepo repository.OrderRepoInterface, unitOfWork repository.UnitOfWorkInterface) EventUsecaseInterface {
 	return EventUsecase{
-		EventRepo: eventRepo,
+		EventRepo:  eventRepo,
+		HoldRepo:   holdRepo,
+		OrderRepo:  orderRepo,
+		UnitOfWork: unitOfWork,
 	}
 }
 
@@ -24,6 +32,8 @@ type EventUsecaseInterface interface {
 	UpdateEvent
 	DeleteEvent
 	GetAllEvents
+	GetEventsByDate
+	GetEventAvailability
 }
 type CreateEvent interface {
 	CreateEvent(event domain.Event, kontek context.Context) (*domain.Event, error)
@@ -43,8 +53,35 @@ type DeleteEvent interface {
 type GetAllEvents interface {
 	GetAllEvents(kontek context.Context) ([]domain.Event, error)
 }
+type GetEventsByDate interface {
+	GetEventsByDate(from, to time.Time, kontek context.Context) ([]domain.Event, error)
+}
+type GetEventAvailability interface {
+	GetEventAvailability(id int, kontek context.Context) (*domain.EventAvailability, error)
+}
 
+// event made by an organizer is owned by them, admin can pick the organizer
 func (uc EventUsecase) CreateEvent(event domain.Event, kontek context.Context) (*domain.Event, error) {
+	user, ok := domain.CurrentUser(kontek)
+	switch {
+	case ok && user.Role == domain.RoleOrganizer:
+		event.OrganizerID = user.ID
+	case !isAdmin(kontek):
+		return nil, domain.ErrRoleNotAllowed
+	}
+	if err := checkTicketCurrency(event.Ticket); err != nil {
+		return nil, err
+	}
+	for i, ticket := range event.Ticket {
+		if !ticket.ValidSaleWindow() {
+			return nil, domain.ErrInvalidSaleWindow
+		}
+		// a ticket type become seated when a seat map is made for it
+		event.Ticket[i].Seated = false
+	}
+	if err := event.InTimeZone(); err != nil {
+		return nil, err
+	}
 	return uc.EventRepo.CreateEvent(&event, kontek)
 }
 func (uc EventUsecase) GetEventByID(id int, kontek context.Context) (*domain.Event, error) {
@@ -53,12 +90,121 @@ func (uc EventUsecase) GetEventByID(id int, kontek context.Context) (*domain.Eve
 func (uc EventUsecase) GetEventByName(name string, kontek context.Context) (*domain.Event, error) {
 	return uc.EventRepo.GetEventByName(name, kontek)
 }
+
+// organizer can only update their own event and can't give it to somebody else
 func (uc EventUsecase) UpdateEvent(event domain.Event, kontek context.Context) error {
+	old, err := uc.EventRepo.GetEventByID(event.ID, kontek)
+	if err != nil {
+		return err
+	}
+	if !canManageEvent(old, kontek) {
+		return domain.ErrNotYourEvent
+	}
+	if !isAdmin(kontek) {
+		event.OrganizerID = old.OrganizerID
+	}
+	if err := event.InTimeZone(); err != nil {
+		return err
+	}
 	return uc.EventRepo.UpdateEvent(&event, kontek)
 }
 func (uc EventUsecase) DeleteEvent(id int, kontek context.Context) error {
-	return uc.EventRepo.DeleteEvent(id, kontek)
+	event, err := uc.EventRepo.GetEventByID(id, kontek)
+	if err != nil {
+		return err
+	}
+	if !canManageEvent(event, kontek) {
+		return domain.ErrNotYourEvent
+	}
+	// a held or unpaid ticket still need its event to go back to the stock, so the event
+	// wait until the reaper or the buyer is done with it
+	return uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		holds, err := uc.HoldRepo.GetHoldsByEventID(id, kontek)
+		if err != nil {
+			return err
+		}
+		for _, hold := range holds {
+			if hold.Status == domain.HoldHeld {
+				return domain.ErrEventHasOpenSale
+			}
+		}
+		orders, err := uc.OrderRepo.GetOrdersByEventID(id, kontek)
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if order.Status == domain.OrderPending || order.Status == domain.OrderAwaitingPayment {
+				return domain.ErrEventHasOpenSale
+			}
+		}
+		return uc.EventRepo.DeleteEvent(id, kontek)
+	})
 }
 func (uc EventUsecase) GetAllEvents(kontek context.Context) ([]domain.Event, error) {
 	return uc.EventRepo.GetAllEvents(kontek)
 }
+
+// event that start inside the range, the earliest first
+func (uc EventUsecase) GetEventsByDate(from, to time.Time, kontek context.Context) ([]domain.Event, error) {
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, domain.ErrInvalidDateRange
+	}
+	events, err := uc.EventRepo.GetEventsByDate(from, to, kontek)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Start.Equal(events[j].Start) {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].Start.Before(events[j].Start)
+	})
+	return events, nil
+}
+
+// the sale status of every ticket type now, and the earliest sale start that is still to come
+func (uc EventUsecase) GetEventAvailability(id int, kontek context.Context) (*domain.EventAvailability, error) {
+	event, err := uc.EventRepo.GetEventByID(id, kontek)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(event.Start.Location())
+	availability := domain.EventAvailability{EventID: event.ID, At: now, Ticket: []domain.TicketAvailability{}}
+	for _, ticket := range event.Ticket {
+		status := ticket.SaleStatus(now)
+		availability.Ticket = append(availability.Ticket, domain.TicketAvailability{
+			Ticket:      ticket,
+			Status:      status,
+			Purchasable: status == domain.SaleOnSale && !event.HasStarted(now),
+		})
+
+		// the next phase can only open before the event start
+		if status != domain.SaleUpcoming || !ticket.SaleStart.Before(event.Start) {
+			continue
+		}
+		switch {
+		case availability.NextPhaseAt == nil || ticket.SaleStart.Before(*availability.NextPhaseAt):
+			availability.NextPhaseAt = ticket.SaleStart
+			availability.NextPhase = []int{ticket.ID}
+		case ticket.SaleStart.Equal(*availability.NextPhaseAt):
+			availability.NextPhase = append(availability.NextPhase, ticket.ID)
+		}
+	}
+	return &availability, nil
+}
+
+// every ticket of an event is sold in the same currency so an order has one total
+func checkTicketCurrency(tickets []domain.Ticket) error {
+	currency := ""
+	for _, ticket := range tickets {
+		if ticket.Price.Currency == "" {
+			continue
+		}
+		if currency != "" && ticket.Price.Currency != currency {
+			return domain.ErrCurrencyMismatch
+		}
+		currency = ticket.Price.Currency
+	}
+	return nil
+}
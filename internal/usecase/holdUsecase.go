@@ -0,0 +1,357 @@
+package usecase
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/payment"
+	"pemesananTiketOnlineGo/internal/repository"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// make a connection to repo
+type HoldUsecase struct {
+	HoldRepo         repository.HoldRepoInterface
+	OrderRepo        repository.OrderRepoInterface
+	EventRepo        repository.EventRepoInterface
+	UserRepo         repository.UserRepoInterface
+	PromoRepo        repository.PromoRepoInterface
+	PricingRepo      repository.PricingRepoInterface
+	SeatRepo         repository.SeatRepoInterface
+	WaitlistRepo     repository.WaitlistRepoInterface
+	QueueRepo        repository.QueueRepoInterface
+	IssuedTicketRepo repository.IssuedTicketRepoInterface
+	Payments         payment.Providers
+	UnitOfWork       repository.UnitOfWorkInterface
+	// the stock that come back is offered to the waitlist right after the commit
+	Waitlist   OfferReturnedStock
+	HoldWindow time.Duration
+}
+
+func NewHoldUsecase(holdRepo repository.HoldRepoInterface, orderRepo repository.OrderRepoInterface, eventRepo repository.EventRepoInterface, userRepo repository.UserRepoInterface, promoRepo repository.PromoRepoInterface, pricingRepo repository.PricingRepoInterface, seatRepo repository.SeatRepoInterface, waitlistRepo repository.WaitlistRepoInterface, queueRepo repository.QueueRepoInterface, issuedTicketRepo repository.IssuedTicketRepoInterface, payments payment.Providers, unitOfWork repository.UnitOfWorkInterface, waitlist OfferReturnedStock, holdWindow time.Duration) HoldUsecaseInterface {
+	return HoldUsecase{
+		HoldRepo:         holdRepo,
+		OrderRepo:        orderRepo,
+		EventRepo:        eventRepo,
+		UserRepo:         userRepo,
+		PromoRepo:        promoRepo,
+		PricingRepo:      pricingRepo,
+		SeatRepo:         seatRepo,
+		WaitlistRepo:     waitlistRepo,
+		QueueRepo:        queueRepo,
+		IssuedTicketRepo: issuedTicketRepo,
+		Payments:         payments,
+		UnitOfWork:       unitOfWork,
+		Waitlist:         waitlist,
+		HoldWindow:       holdWindow,
+	}
+}
+
+type HoldUsecaseInterface interface {
+	ReserveTicket
+	ConfirmHold
+	ReleaseHold
+	GetHoldByID
+	ReapExpiredHolds
+	RunHoldReaper
+}
+type ReserveTicket interface {
+	ReserveTicket(orderReq domain.OrderRequest, kontek context.Context) (*domain.Hold, error)
+}
+type ConfirmHold interface {
+	ConfirmHold(id int, kontek context.Context) (*domain.Order, error)
+}
+type ReleaseHold interface {
+	ReleaseHold(id int, kontek context.Context) (*domain.Hold, error)
+}
+type GetHoldByID interface {
+	GetHoldByID(id int, kontek context.Context) (*domain.Hold, error)
+}
+type ReapExpiredHolds interface {
+	ReapExpiredHolds(kontek context.Context) (int, error)
+}
+type RunHoldReaper interface {
+	RunHoldReaper(kontek context.Context, interval time.Duration)
+}
+
+// hold the ticket for the user until the hold window pass
+func (uc HoldUsecase) ReserveTicket(orderReq domain.OrderRequest, kontek context.Context) (*domain.Hold, error) {
+	// a user still waiting in the queue is turned away before touching the event
+	if err := checkQueue(uc.QueueRepo, orderReq.EventID, orderReq.UserID, orderReq.QueueToken, kontek); err != nil {
+		return nil, err
+	}
+
+	event, err := uc.EventRepo.GetEventByID(orderReq.EventID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if event.HasStarted(time.Now()) {
+		return nil, domain.ErrEventStarted
+	}
+	if _, err := uc.UserRepo.GetUserByID(orderReq.UserID, kontek); err != nil {
+		return nil, err
+	}
+	// the promo is only redeemed when the hold is confirmed, an unknown code is refused at once
+	if orderReq.PromoCode != "" {
+		if _, err := uc.PromoRepo.GetPromoByCode(promoCode(orderReq.PromoCode), kontek); err != nil {
+			return nil, err
+		}
+	}
+
+	tickets, seats, err := seatedLines(uc.SeatRepo, event, orderReq.Ticket, orderReq.Seats, kontek)
+	if err != nil {
+		return nil, err
+	}
+
+	hold := domain.Hold{
+		UserID:    orderReq.UserID,
+		EventID:   orderReq.EventID,
+		Ticket:    tickets,
+		Status:    domain.HoldHeld,
+		ExpiresAt: time.Now().Add(uc.HoldWindow),
+		PromoCode: promoCode(orderReq.PromoCode),
+		Seats:     orderReq.Seats,
+	}
+	err = uc.UnitOfWork.WithinScope(kontek, purchaseScope(orderReq), func(kontek context.Context) error {
+		if err := checkPurchaseLimit(uc.OrderRepo, event, hold.UserID, hold.Ticket, kontek); err != nil {
+			return err
+		}
+		if err := checkWaitlist(uc.WaitlistRepo, uc.EventRepo, hold.Ticket, kontek); err != nil {
+			return err
+		}
+		if err := uc.EventRepo.HoldTicketStock(hold.EventID, hold.Ticket, kontek); err != nil {
+			return err
+		}
+		if _, err := uc.HoldRepo.CreateHold(&hold, kontek); err != nil {
+			return err
+		}
+		if len(seats) == 0 {
+			return nil
+		}
+		return uc.SeatRepo.HoldSeats(hold.Seats, hold.ID, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// turn the hold into an order and pay it from the user wallet. the order is saved with the hold
+// and paid after the commit like every other order, a failed payment give the ticket back to the stock
+func (uc HoldUsecase) ConfirmHold(id int, kontek context.Context) (*domain.Order, error) {
+	provider, err := uc.Payments.Get(domain.PaymentWallet)
+	if err != nil {
+		return nil, err
+	}
+	// the hold is read again inside the scope, its event, user and promo never change
+	held, err := uc.GetHoldByID(id, kontek)
+	if err != nil {
+		return nil, err
+	}
+	var order domain.Order
+	scope := purchaseScope(domain.OrderRequest{EventID: held.EventID, UserID: held.UserID, PromoCode: held.PromoCode})
+	err = uc.UnitOfWork.WithinScope(kontek, scope, func(kontek context.Context) error {
+		hold, err := uc.activeHold(id, kontek)
+		if err != nil {
+			return err
+		}
+		event, err := uc.EventRepo.GetEventByID(hold.EventID, kontek)
+		if err != nil {
+			return err
+		}
+		if event.HasStarted(time.Now()) {
+			return domain.ErrEventStarted
+		}
+		// the user can have bought more since the reservation was made
+		if err := checkPurchaseLimit(uc.OrderRepo, event, hold.UserID, hold.Ticket, kontek); err != nil {
+			return err
+		}
+		user, err := uc.UserRepo.GetUserByID(hold.UserID, kontek)
+		if err != nil {
+			return err
+		}
+
+		// the stock is already taken by the hold so it is not checked again
+		order = newOrder(user, event, pricedTickets(event, hold.Ticket), domain.PaymentWallet)
+		if order.Subtotal, err = ticketsTotal(order.EventTicket); err != nil {
+			return err
+		}
+		order.TotalPrice = order.Subtotal
+		var promo *domain.Promo
+		if hold.PromoCode != "" {
+			if promo, err = applyPromo(uc.PromoRepo, &order, hold.PromoCode, kontek); err != nil {
+				return err
+			}
+		}
+		if err := chargeOrder(uc.PricingRepo, &order, kontek); err != nil {
+			return err
+		}
+		if err := uc.EventRepo.ConfirmTicketStock(hold.EventID, hold.Ticket, kontek); err != nil {
+			return err
+		}
+		if len(hold.Seats) > 0 {
+			seats, err := uc.SeatRepo.GetSeatsByIDs(hold.EventID, hold.Seats, kontek)
+			if err != nil {
+				return err
+			}
+			order.Seats = orderSeats(seats)
+		}
+
+		if err := changeStatus(&order, domain.OrderPending, ""); err != nil {
+			return err
+		}
+		if err := changeStatus(&order, domain.OrderAwaitingPayment, ""); err != nil {
+			return err
+		}
+		if _, err := uc.OrderRepo.CreateOrder(&order, kontek); err != nil {
+			return err
+		}
+		if len(hold.Seats) > 0 {
+			if err := uc.SeatRepo.SellSeats(hold.Seats, order.ID, hold.ID, kontek); err != nil {
+				return err
+			}
+		}
+		if promo != nil {
+			if err := redeemPromo(uc.PromoRepo, promo, &order, kontek); err != nil {
+				return err
+			}
+		}
+
+		hold.Status = domain.HoldConfirmed
+		hold.OrderID = order.ID
+		return uc.HoldRepo.UpdateHold(hold, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.payments().startPayment(provider, &order, kontek)
+}
+
+// the payment side of the hold usecase
+func (uc HoldUsecase) payments() PaymentUsecase {
+	return PaymentUsecase{
+		OrderRepo:        uc.OrderRepo,
+		EventRepo:        uc.EventRepo,
+		PromoRepo:        uc.PromoRepo,
+		SeatRepo:         uc.SeatRepo,
+		IssuedTicketRepo: uc.IssuedTicketRepo,
+		UnitOfWork:       uc.UnitOfWork,
+		Payments:         uc.Payments,
+		Waitlist:         uc.Waitlist,
+	}
+}
+
+// give the held ticket back to the stock before the hold expire
+func (uc HoldUsecase) ReleaseHold(id int, kontek context.Context) (*domain.Hold, error) {
+	var hold *domain.Hold
+	err := uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		var err error
+		hold, err = uc.activeHold(id, kontek)
+		if err != nil {
+			return err
+		}
+		return uc.endHold(hold, domain.HoldReleased, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	uc.Waitlist.OfferReturnedStock(ticketIDs(hold.Ticket), kontek)
+	return hold, nil
+}
+
+func (uc HoldUsecase) GetHoldByID(id int, kontek context.Context) (*domain.Hold, error) {
+	hold, err := uc.HoldRepo.GetHoldByID(id, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !canAccess(hold.UserID, kontek) {
+		return nil, domain.ErrNotYourHold
+	}
+	return hold, nil
+}
+
+// expire every hold that pass the window and return the ticket to the stock
+func (uc HoldUsecase) ReapExpiredHolds(kontek context.Context) (int, error) {
+	holds, err := uc.HoldRepo.GetExpiredHolds(time.Now(), kontek)
+	if err != nil {
+		return 0, err
+	}
+
+	reaped := 0
+	for _, expired := range holds {
+		stillHeld := false
+		err := uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+			// the hold can be confirmed or released after it was listed
+			hold, err := uc.HoldRepo.GetHoldByID(expired.ID, kontek)
+			if err != nil {
+				return err
+			}
+			if hold.Status != domain.HoldHeld {
+				return nil
+			}
+			stillHeld = true
+			return uc.endHold(hold, domain.HoldExpired, kontek)
+		})
+		// one hold that can't be expired must not keep the stock of every other hold
+		if err != nil {
+			log.Error().Err(err).Int("hold", expired.ID).Msg("Hold Reaper Failed")
+			continue
+		}
+		if stillHeld {
+			reaped++
+			uc.Waitlist.OfferReturnedStock(ticketIDs(expired.Ticket), kontek)
+		}
+	}
+	return reaped, nil
+}
+
+// run the reaper every interval until kontek is done
+func (uc HoldUsecase) RunHoldReaper(kontek context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-kontek.Done():
+			return
+		case <-ticker.C:
+			reaped, err := uc.ReapExpiredHolds(kontek)
+			if err != nil {
+				log.Error().Err(err).Msg("Hold Reaper Failed")
+				continue
+			}
+			if reaped > 0 {
+				log.Info().Int("expired", reaped).Msg("Hold Reaper Success")
+			}
+		}
+	}
+}
+
+// get the hold and make sure it can still be confirmed or released
+func (uc HoldUsecase) activeHold(id int, kontek context.Context) (*domain.Hold, error) {
+	hold, err := uc.GetHoldByID(id, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != domain.HoldHeld {
+		return nil, domain.NewError(domain.ErrConflict, "RESERVATION_NOT_ACTIVE", "RESERVATION IS ALREADY "+hold.Status)
+	}
+	if !time.Now().Before(hold.ExpiresAt) {
+		return nil, domain.ErrHoldExpired
+	}
+	return hold, nil
+}
+
+// put the held ticket and seat back and close the hold with the given status
+func (uc HoldUsecase) endHold(hold *domain.Hold, status string, kontek context.Context) error {
+	if err := uc.EventRepo.ReleaseTicketStock(hold.EventID, hold.Ticket, kontek); err != nil {
+		return err
+	}
+	if len(hold.Seats) > 0 {
+		if err := uc.SeatRepo.ReleaseSeats(hold.Seats, kontek); err != nil {
+			return err
+		}
+	}
+	hold.Status = status
+	return uc.HoldRepo.UpdateHold(hold, kontek)
+}
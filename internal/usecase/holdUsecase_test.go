@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"pemesananTiketOnlineGo/internal/domain"
+	"testing"
+	"time"
+)
+
+// a hold whose event is gone can't be expired, the reaper still return the stock of every other hold
+func TestReapExpiredHoldsSkipFailedHold(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			t.Parallel()
+			repos := newTestRepos(t, backend)
+			holdUsecase := repos.holdUsecase(-time.Second)
+			kontek := systemContext()
+
+			gone := newTestEvent(t, repos, "Gone", domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10})
+			kept := newTestEvent(t, repos, "Kept", domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10})
+			user := newTestUser(t, repos, "holder", 0)
+			for _, event := range []*domain.Event{gone, kept} {
+				if _, err := holdUsecase.ReserveTicket(domain.OrderRequest{UserID: user.ID, EventID: event.ID, Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: 4}}}, kontek); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// straight through the repo, the usecase refuse to delete an event with a hold
+			if err := repos.Event.DeleteEvent(gone.ID, kontek); err != nil {
+				t.Fatal(err)
+			}
+
+			reaped, err := holdUsecase.ReapExpiredHolds(kontek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reaped != 1 {
+				t.Errorf("reaped %d hold, want 1", reaped)
+			}
+			stock, err := repos.Event.GetEventByID(kept.ID, kontek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stock.Ticket[0].Quantity != 10 {
+				t.Errorf("stock is %d after the reaper, want 10", stock.Ticket[0].Quantity)
+			}
+		})
+	}
+}
+
+// an event can't be deleted while a ticket of it is held or waiting for the payment
+func TestDeleteEventWithOpenSale(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			t.Parallel()
+			repos := newTestRepos(t, backend)
+			holdUsecase := repos.holdUsecase(time.Minute)
+			eventUsecase := NewEventUsecase(repos.Event, repos.Hold, repos.Order, repos.UnitOfWork)
+			kontek := systemContext()
+
+			event := newTestEvent(t, repos, "Open", domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10})
+			user := newTestUser(t, repos, "holder", 0)
+			hold, err := holdUsecase.ReserveTicket(domain.OrderRequest{UserID: user.ID, EventID: event.ID, Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: 2}}}, kontek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := eventUsecase.DeleteEvent(event.ID, kontek); errorCode(err) != "EVENT_HAS_OPEN_SALE" {
+				t.Fatalf("delete with a held ticket got %v, want EVENT_HAS_OPEN_SALE", err)
+			}
+
+			unpaid := domain.Order{OrderDate: time.Now().Format(time.DateTime), User: *user, Event: *event, EventTicket: []domain.Ticket{{ID: event.Ticket[0].ID, Type: "GA", Quantity: 2}}, Status: domain.OrderAwaitingPayment, PaymentMethod: domain.PaymentWallet, TotalPrice: domain.NewMoney(50000, domain.DefaultCurrency)}
+			if _, err := repos.Order.CreateOrder(&unpaid, kontek); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := holdUsecase.ReleaseHold(hold.ID, kontek); err != nil {
+				t.Fatal(err)
+			}
+			if err := eventUsecase.DeleteEvent(event.ID, kontek); errorCode(err) != "EVENT_HAS_OPEN_SALE" {
+				t.Fatalf("delete with an unpaid order got %v, want EVENT_HAS_OPEN_SALE", err)
+			}
+
+			unpaid.Status = domain.OrderExpired
+			if err := repos.Order.UpdateOrder(&unpaid, kontek); err != nil {
+				t.Fatal(err)
+			}
+			if err := eventUsecase.DeleteEvent(event.ID, kontek); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// a confirmed hold is paid like every other order, a failed payment give the ticket back to the stock
+func TestConfirmHold(t *testing.T) {
+	cases := []struct {
+		name    string
+		balance int64
+		// what the order end as, its failure reason and the stock left after it
+		status string
+		code   string
+		stock  int
+	}{
+		{name: "paid", balance: 100000, status: domain.OrderFulfilled, stock: 8},
+		{name: "not enough balance", balance: 10000, status: domain.OrderFailed, code: "INSUFFICIENT_BALANCE", stock: 10},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				holdUsecase := repos.holdUsecase(time.Minute)
+				kontek := systemContext()
+
+				event := newTestEvent(t, repos, "Confirm", domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10})
+				user := newTestUser(t, repos, "holder", tc.balance)
+				hold, err := holdUsecase.ReserveTicket(domain.OrderRequest{UserID: user.ID, EventID: event.ID, Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: 2}}}, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				order, err := holdUsecase.ConfirmHold(hold.ID, kontek)
+				if errorCode(err) != tc.code {
+					t.Fatalf("confirm got %v, want %q", err, tc.code)
+				}
+				if order.Status != tc.status || order.FailureReason != tc.code {
+					t.Errorf("order is %s %q, want %s %q", order.Status, order.FailureReason, tc.status, tc.code)
+				}
+				saved, err := repos.Hold.GetHoldByID(hold.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if saved.Status != domain.HoldConfirmed || saved.OrderID != order.ID {
+					t.Errorf("hold is %s of order %d, want CONFIRMED of order %d", saved.Status, saved.OrderID, order.ID)
+				}
+				stock, err := repos.Event.GetEventByID(event.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stock.Ticket[0].Quantity != tc.stock || stock.Ticket[0].Held != 0 {
+					t.Errorf("stock is %d with %d held, want %d with none held", stock.Ticket[0].Quantity, stock.Ticket[0].Held, tc.stock)
+				}
+				balance, err := repos.Wallet.GetBalance(user.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// only a paid order take the money
+				want := tc.balance
+				if tc.status == domain.OrderFulfilled {
+					want -= order.TotalPrice.Amount
+				}
+				if balance.Amount != want {
+					t.Errorf("balance is %d after the order of %s, want %d", balance.Amount, order.TotalPrice, want)
+				}
+			})
+		}
+	}
+}
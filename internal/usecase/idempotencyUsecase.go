@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// a key that stay in progress longer than this is left by a request that died, so it can be used again
+const idempotencyLock = time.Minute
+
+// make a connection to repo
+type IdempotencyUsecase struct {
+	IdempotencyRepo repository.IdempotencyRepoInterface
+	// how long the response of a key is kept for a retry
+	TTL time.Duration
+}
+
+func NewIdempotencyUsecase(idempotencyRepo repository.IdempotencyRepoInterface, ttl time.Duration) IdempotencyUsecaseInterface {
+	return IdempotencyUsecase{
+		IdempotencyRepo: idempotencyRepo,
+		TTL:             ttl,
+	}
+}
+
+type IdempotencyUsecaseInterface interface {
+	BeginRequest
+	FinishRequest
+	ReapExpiredKeys
+	RunKeyReaper
+}
+type BeginRequest interface {
+	BeginRequest(key string, requestHash string, kontek context.Context) (*domain.Idempotency, error)
+}
+type FinishRequest interface {
+	FinishRequest(record *domain.Idempotency, orderNumber string, responseCode int, responseBody []byte, kontek context.Context) error
+}
+type ReapExpiredKeys interface {
+	ReapExpiredKeys(kontek context.Context) (int, error)
+}
+type RunKeyReaper interface {
+	RunKeyReaper(kontek context.Context, interval time.Duration)
+}
+
+// claim the key of the logged in user. a DONE record is the saved response to replay, an ORDER_SAVED
+// record is the order to replay, an IN_PROGRESS record mean the request is new and has to be finished after it run
+func (uc IdempotencyUsecase) BeginRequest(key string, requestHash string, kontek context.Context) (*domain.Idempotency, error) {
+	if key == "" || len(key) > domain.MaxIdempotencyKey {
+		return nil, domain.ErrInvalidIdempotencyKey
+	}
+	var userID int
+	if caller, ok := domain.CurrentUser(kontek); ok {
+		userID = caller.ID
+	}
+
+	saved, err := uc.IdempotencyRepo.GetIdempotency(userID, key, kontek)
+	if err != nil && !errors.Is(err, domain.ErrIdempotencyNotFound) {
+		return nil, err
+	}
+	if saved != nil && time.Now().Before(saved.ExpiresAt) {
+		if saved.RequestHash != requestHash {
+			return nil, domain.ErrIdempotencyReused
+		}
+		if saved.Status == domain.IdempotencyDone || saved.Status == domain.IdempotencyOrderSaved {
+			return saved, nil
+		}
+		return nil, domain.ErrIdempotencyInUse
+	}
+
+	record := domain.Idempotency{
+		Key:         key,
+		UserID:      userID,
+		RequestHash: requestHash,
+		Status:      domain.IdempotencyInProgress,
+		ExpiresAt:   time.Now().Add(idempotencyLock),
+	}
+	// two retry can arrive together, only one of them get to save the key
+	if err := uc.IdempotencyRepo.CreateIdempotency(&record, kontek); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// save the response for the retry. a server error or a timeout that saved nothing is forgotten so the
+// retry run the request again. once the order is saved, like a payment that time out after the debit,
+// the key point to the order instead so the retry never buy it twice
+func (uc IdempotencyUsecase) FinishRequest(record *domain.Idempotency, orderNumber string, responseCode int, responseBody []byte, kontek context.Context) error {
+	if responseCode >= http.StatusInternalServerError && orderNumber == "" {
+		return uc.IdempotencyRepo.DeleteIdempotency(record.UserID, record.Key, kontek)
+	}
+	record.ExpiresAt = time.Now().Add(uc.TTL)
+	if responseCode >= http.StatusInternalServerError {
+		record.Status = domain.IdempotencyOrderSaved
+		record.OrderNumber = orderNumber
+		return uc.IdempotencyRepo.UpdateIdempotency(record, kontek)
+	}
+	record.Status = domain.IdempotencyDone
+	record.ResponseCode = responseCode
+	record.ResponseBody = responseBody
+	return uc.IdempotencyRepo.UpdateIdempotency(record, kontek)
+}
+
+// forget every key that pass its TTL
+func (uc IdempotencyUsecase) ReapExpiredKeys(kontek context.Context) (int, error) {
+	return uc.IdempotencyRepo.DeleteExpiredIdempotency(time.Now(), kontek)
+}
+
+// run the reaper every interval until kontek is done
+func (uc IdempotencyUsecase) RunKeyReaper(kontek context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-kontek.Done():
+			return
+		case <-ticker.C:
+			reaped, err := uc.ReapExpiredKeys(kontek)
+			if err != nil {
+				log.Error().Err(err).Msg("Idempotency Key Reaper Failed")
+				continue
+			}
+			if reaped > 0 {
+				log.Info().Int("expired", reaped).Msg("Idempotency Key Reaper Success")
+			}
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+	"strings"
+	"time"
+)
+
+// make a connection to repo, the pass key sign the QR code of every ticket
+type IssuedTicketUsecase struct {
+	IssuedTicketRepo repository.IssuedTicketRepoInterface
+	EventRepo        repository.EventRepoInterface
+	UserRepo         repository.UserRepoInterface
+	UnitOfWork       repository.UnitOfWorkInterface
+	PassKey          ed25519.PrivateKey
+}
+
+func NewIssuedTicketUsecase(issuedTicketRepo repository.IssuedTicketRepoInterface, eventRepo repository.EventRepoInterface, userRepo repository.UserRepoInterface, unitOfWork repository.UnitOfWorkInterface, passKey ed25519.PrivateKey) IssuedTicketUsecaseInterface {
+	return IssuedTicketUsecase{
+		IssuedTicketRepo: issuedTicketRepo,
+		EventRepo:        eventRepo,
+		UserRepo:         userRepo,
+		UnitOfWork:       unitOfWork,
+		PassKey:          passKey,
+	}
+}
+
+type IssuedTicketUsecaseInterface interface {
+	GetMyTickets
+	TransferTicket
+	CheckInTicket
+	GetTicketPass
+	GetPassKey
+}
+type GetMyTickets interface {
+	GetMyTickets(userID int, kontek context.Context) ([]domain.IssuedTicket, error)
+}
+type TransferTicket interface {
+	TransferTicket(transferReq domain.TransferRequest, kontek context.Context) (*domain.IssuedTicket, error)
+}
+type CheckInTicket interface {
+	CheckInTicket(code string, kontek context.Context) (*domain.IssuedTicket, error)
+}
+type GetTicketPass interface {
+	GetTicketPass(code string, kontek context.Context) (string, error)
+}
+type GetPassKey interface {
+	GetPassKey() domain.PassKey
+}
+
+// a signed pass is passPrefix + payload + "." + signature, both base64url without padding.
+// the version in the prefix let the format change later without breaking the printed one
+const passPrefix = "TKT1."
+
+// every ticket the user hold or held, the one given away show as TRANSFERRED
+func (uc IssuedTicketUsecase) GetMyTickets(userID int, kontek context.Context) ([]domain.IssuedTicket, error) {
+	if !canAccess(userID, kontek) {
+		return nil, domain.ErrNotYourTicket
+	}
+	return uc.IssuedTicketRepo.GetIssuedTicketsByUserID(userID, kontek)
+}
+
+// give a valid ticket to another user before the event start. the new holder get a new code,
+// so a copy of the old one kept by the giver can't enter anymore
+func (uc IssuedTicketUsecase) TransferTicket(transferReq domain.TransferRequest, kontek context.Context) (*domain.IssuedTicket, error) {
+	var given *domain.IssuedTicket
+	err := uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		ticket, err := uc.IssuedTicketRepo.GetIssuedTicketByCode(ticketCode(transferReq.Code), kontek)
+		if err != nil {
+			return err
+		}
+		if !canAccess(ticket.UserID, kontek) {
+			return domain.ErrNotYourTicket
+		}
+		if err := checkValid(ticket); err != nil {
+			return err
+		}
+		event, err := uc.EventRepo.GetEventByID(ticket.EventID, kontek)
+		if err != nil {
+			return err
+		}
+		if event.HasStarted(time.Now()) {
+			return domain.ErrEventStarted
+		}
+		holder, err := uc.UserRepo.GetUserByName(transferReq.To, kontek)
+		if err != nil {
+			return err
+		}
+		if holder.ID == ticket.UserID {
+			return domain.ErrTransferToSelf
+		}
+
+		given = &domain.IssuedTicket{
+			OrderID:    ticket.OrderID,
+			EventID:    ticket.EventID,
+			EventName:  ticket.EventName,
+			TicketID:   ticket.TicketID,
+			Type:       ticket.Type,
+			SeatID:     ticket.SeatID,
+			Seat:       ticket.Seat,
+			UserID:     holder.ID,
+			HolderName: holder.Name,
+			Status:     domain.IssuedValid,
+			IssuedAt:   time.Now(),
+		}
+		if _, err := uc.IssuedTicketRepo.CreateIssuedTicket(given, kontek); err != nil {
+			return err
+		}
+		ticket.Status = domain.IssuedTransferred
+		ticket.TransferredTo = given.ID
+		return uc.IssuedTicketRepo.UpdateIssuedTicket(ticket, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return given, nil
+}
+
+// let the holder in, a ticket can only be used once. the code can also be the scanned QR,
+// a QR that is not signed by us never reach the repo
+func (uc IssuedTicketUsecase) CheckInTicket(code string, kontek context.Context) (*domain.IssuedTicket, error) {
+	if strings.HasPrefix(code, passPrefix) {
+		pass, err := VerifyTicketPass(code, uc.PassKey.Public().(ed25519.PublicKey), time.Now())
+		if err != nil {
+			return nil, err
+		}
+		code = pass.Code
+	}
+	var ticket *domain.IssuedTicket
+	err := uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		var err error
+		ticket, err = uc.IssuedTicketRepo.GetIssuedTicketByCode(ticketCode(code), kontek)
+		if err != nil {
+			return err
+		}
+		event, err := uc.EventRepo.GetEventByID(ticket.EventID, kontek)
+		if err != nil {
+			return err
+		}
+		if !canManageEvent(event, kontek) {
+			return domain.ErrNotYourEvent
+		}
+		if err := checkValid(ticket); err != nil {
+			return err
+		}
+		now := time.Now()
+		ticket.Status = domain.IssuedUsed
+		ticket.UsedAt = &now
+		return uc.IssuedTicketRepo.UpdateIssuedTicket(ticket, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// sign the QR payload of a valid ticket for its holder, the pass stop being valid when the event end
+func (uc IssuedTicketUsecase) GetTicketPass(code string, kontek context.Context) (string, error) {
+	ticket, err := uc.IssuedTicketRepo.GetIssuedTicketByCode(ticketCode(code), kontek)
+	if err != nil {
+		return "", err
+	}
+	if !canAccess(ticket.UserID, kontek) {
+		return "", domain.ErrNotYourTicket
+	}
+	if err := checkValid(ticket); err != nil {
+		return "", err
+	}
+	event, err := uc.EventRepo.GetEventByID(ticket.EventID, kontek)
+	if err != nil {
+		return "", err
+	}
+	return signTicketPass(domain.TicketPass{
+		Code:       ticket.Code,
+		EventID:    ticket.EventID,
+		Type:       ticket.Type,
+		Seat:       ticket.Seat,
+		HolderName: ticket.HolderName,
+		ValidUntil: event.End.Unix(),
+	}, uc.PassKey)
+}
+
+// the public half of the pass key, a gate load it once and then check every QR offline
+func (uc IssuedTicketUsecase) GetPassKey() domain.PassKey {
+	return domain.PassKey{Algorithm: "Ed25519", PublicKey: base64.RawURLEncoding.EncodeToString(uc.PassKey.Public().(ed25519.PublicKey))}
+}
+
+func signTicketPass(pass domain.TicketPass, key ed25519.PrivateKey) (string, error) {
+	payload, err := json.Marshal(pass)
+	if err != nil {
+		return "", err
+	}
+	unsigned := passPrefix + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(unsigned))), nil
+}
+
+// check a scanned ticket QR with only the public key, no repo is needed so a gate can run it offline.
+// it prove the ticket was issued by us for that holder, whether it was already used, refunded or
+// given away is only known by the server
+func VerifyTicketPass(signed string, publicKey ed25519.PublicKey, now time.Time) (*domain.TicketPass, error) {
+	if !strings.HasPrefix(signed, passPrefix) || len(publicKey) != ed25519.PublicKeySize {
+		return nil, domain.ErrInvalidPass
+	}
+	dot := strings.LastIndexByte(signed, '.')
+	if dot < len(passPrefix) {
+		return nil, domain.ErrInvalidPass
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(signed[dot+1:])
+	if err != nil || !ed25519.Verify(publicKey, []byte(signed[:dot]), signature) {
+		return nil, domain.ErrInvalidPass
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(signed[len(passPrefix):dot])
+	if err != nil {
+		return nil, domain.ErrInvalidPass
+	}
+	var pass domain.TicketPass
+	if err := json.Unmarshal(payload, &pass); err != nil || pass.Code == "" {
+		return nil, domain.ErrInvalidPass
+	}
+	if now.Unix() >= pass.ValidUntil {
+		return nil, domain.ErrPassExpired
+	}
+	return &pass, nil
+}
+
+// only a valid ticket can be used or given away
+func checkValid(ticket *domain.IssuedTicket) error {
+	switch ticket.Status {
+	case domain.IssuedValid:
+		return nil
+	case domain.IssuedUsed:
+		return domain.ErrTicketUsed
+	default:
+		return domain.ErrTicketNotValid
+	}
+}
+
+// the code is printed in upper case, a typed one may not be
+func ticketCode(code string) string {
+	return promoCode(code)
+}
+
+// one ticket for every person of the paid order, a seated ticket type get one ticket per sold seat
+func issueTickets(issuedTicketRepo repository.IssuedTicketRepoInterface, order *domain.Order, kontek context.Context) error {
+	now := time.Now()
+	for _, line := range order.EventTicket {
+		seats := []domain.Seat{}
+		for _, seat := range order.Seats {
+			if seat.TicketID == line.ID && seat.Status == domain.SeatSold {
+				seats = append(seats, seat)
+			}
+		}
+		for i := 0; i < line.Quantity; i++ {
+			ticket := domain.IssuedTicket{
+				OrderID:    order.ID,
+				EventID:    order.Event.ID,
+				EventName:  order.Event.Name,
+				TicketID:   line.ID,
+				Type:       line.Type,
+				UserID:     order.User.ID,
+				HolderName: order.User.Name,
+				Status:     domain.IssuedValid,
+				IssuedAt:   now,
+			}
+			if i < len(seats) {
+				ticket.SeatID = seats[i].ID
+				ticket.Seat = fmt.Sprintf("%s-%s-%d", seats[i].Section, seats[i].Row, seats[i].Number)
+			}
+			if _, err := issuedTicketRepo.CreateIssuedTicket(&ticket, kontek); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// void the ticket of the refunded line, the ticket of a refunded seat first and then any valid ticket
+// of the same type. a ticket that is used or given to another user can't be refunded
+func voidTickets(issuedTicketRepo repository.IssuedTicketRepoInterface, order *domain.Order, lines []domain.Ticket, seats []int, kontek context.Context) error {
+	tickets, err := issuedTicketRepo.GetIssuedTicketsByOrderID(order.ID, kontek)
+	if err != nil {
+		return err
+	}
+	remaining := ticketQuantity(lines)
+	void := func(ticket domain.IssuedTicket) error {
+		remaining[ticket.TicketID]--
+		ticket.Status = domain.IssuedVoid
+		return issuedTicketRepo.UpdateIssuedTicket(&ticket, kontek)
+	}
+
+	voided := map[int]bool{}
+	for _, seatID := range seats {
+		for _, ticket := range tickets {
+			if ticket.SeatID != seatID || ticket.Status == domain.IssuedTransferred || ticket.Status == domain.IssuedVoid {
+				continue
+			}
+			if ticket.Status == domain.IssuedUsed || ticket.UserID != order.User.ID {
+				return domain.ErrTicketNotRefund
+			}
+			if err := void(ticket); err != nil {
+				return err
+			}
+			voided[ticket.ID] = true
+		}
+	}
+	for _, ticket := range tickets {
+		if remaining[ticket.TicketID] > 0 && ticket.Status == domain.IssuedValid && ticket.UserID == order.User.ID && !voided[ticket.ID] {
+			if err := void(ticket); err != nil {
+				return err
+			}
+		}
+	}
+	for _, quantity := range remaining {
+		if quantity > 0 {
+			return domain.ErrTicketNotRefund
+		}
+	}
+	return nil
+}
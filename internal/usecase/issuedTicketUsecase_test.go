@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// every person of a fulfilled order get one ticket with its own code, a seated ticket is for a seat
+// of its order and a failed order get none
+func TestCreateOrderIssuedTickets(t *testing.T) {
+	cases := []struct {
+		name    string
+		orders  int
+		balance int64
+	}{
+		{name: "every order paid", orders: 40, balance: 100000000},
+		{name: "some order failed", orders: 40, balance: 200000},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				orderUsecase := repos.orderUsecase()
+				seatUsecase := NewSeatUsecase(repos.Seat, repos.Event, repos.Order, repos.UnitOfWork)
+				kontek := systemContext()
+
+				event := newTestEvent(t, repos, "Issued",
+					domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 200},
+					domain.Ticket{Type: "SEATED", Price: domain.NewMoney(100000, domain.DefaultCurrency), Quantity: 20})
+				seatMap, err := seatUsecase.CreateSeatMap(domain.SeatMapRequest{EventID: event.ID, Sections: []domain.SeatSectionRequest{
+					{Name: "A", TicketID: event.Ticket[1].ID, Rows: []domain.SeatRowRequest{{Name: "1", Seats: 20}}},
+				}}, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				seats := seatMap.Sections[0].Rows[0].Seats
+				var users []int
+				for i := 1; i <= 10; i++ {
+					users = append(users, newTestUser(t, repos, fmt.Sprintf("user%d", i), tc.balance).ID)
+				}
+
+				var wg sync.WaitGroup
+				for i := 0; i < tc.orders; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						// an order of plain ticket and a seat, the same seat is wanted by two order
+						orderReq := domain.OrderRequest{UserID: users[i%len(users)], EventID: event.ID,
+							Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: i%3 + 1}},
+							Seats:  []int{seats[i/2%len(seats)].ID}}
+						orderUsecase.CreateOrder(orderReq, kontek)
+					}(i)
+				}
+				wg.Wait()
+
+				orders, err := repos.Order.GetAllOrders(kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				codes := map[string]int{}
+				fulfilled, failed := 0, 0
+				for _, order := range orders {
+					issued, err := repos.IssuedTicket.GetIssuedTicketsByOrderID(order.ID, kontek)
+					if err != nil {
+						t.Fatal(err)
+					}
+					want := 0
+					if order.Status == domain.OrderFulfilled {
+						fulfilled++
+						for _, ticket := range order.EventTicket {
+							want += ticket.Quantity
+						}
+					} else {
+						failed++
+					}
+					if len(issued) != want {
+						t.Errorf("order %d is %s with %d ticket, got %d issued", order.ID, order.Status, want, len(issued))
+					}
+					orderSeats := map[int]bool{}
+					for _, seat := range order.Seats {
+						orderSeats[seat.ID] = true
+					}
+					for _, ticket := range issued {
+						if other, taken := codes[ticket.Code]; taken || ticket.Code == "" {
+							t.Errorf("ticket %d and %d have the code %q", other, ticket.ID, ticket.Code)
+						}
+						codes[ticket.Code] = ticket.ID
+						if ticket.Status != domain.IssuedValid || ticket.UserID != order.User.ID {
+							t.Errorf("ticket %d of order %d is %s for user %d", ticket.ID, order.ID, ticket.Status, ticket.UserID)
+						}
+						if ticket.SeatID != 0 && !orderSeats[ticket.SeatID] {
+							t.Errorf("ticket %d of order %d is for seat %d of another order", ticket.ID, order.ID, ticket.SeatID)
+						}
+					}
+				}
+				if fulfilled == 0 || failed == 0 {
+					t.Errorf("%d order fulfilled and %d failed, the test need both", fulfilled, failed)
+				}
+			})
+		}
+	}
+}
+
+// the QR of a ticket pass the offline check with the public key only, a changed, foreign or late QR doesn't
+func TestVerifyTicketPass(t *testing.T) {
+	repos := newTestRepos(t, "memory")
+	kontek := systemContext()
+	_, passKey, _ := ed25519.GenerateKey(nil)
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	publicKey := passKey.Public().(ed25519.PublicKey)
+	issuedTicketUsecase := NewIssuedTicketUsecase(repos.IssuedTicket, repos.Event, repos.User, repos.UnitOfWork, passKey)
+
+	event := newTestEvent(t, repos, "Pass", domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10})
+	user := newTestUser(t, repos, "holder", 100000)
+	order, err := repos.orderUsecase().CreateOrder(domain.OrderRequest{UserID: user.ID, EventID: event.ID, Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: 1}}}, kontek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := repos.IssuedTicket.GetIssuedTicketsByOrderID(order.ID, kontek)
+	if err != nil || len(issued) != 1 {
+		t.Fatalf("got %d issued ticket %v", len(issued), err)
+	}
+	code := issued[0].Code
+	signed, err := issuedTicketUsecase.GetTicketPass(code, domain.WithUser(context.Background(), *user))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		signed    string
+		publicKey ed25519.PublicKey
+		now       time.Time
+		code      string
+	}{
+		{name: "valid", signed: signed, publicKey: publicKey, now: time.Now()},
+		// flip one byte of the payload, the signature stay the same
+		{name: "changed payload", signed: flipByte(signed, len(passPrefix)+3), publicKey: publicKey, now: time.Now(), code: domain.ErrInvalidPass.Code},
+		{name: "changed signature", signed: flipByte(signed, len(signed)-3), publicKey: publicKey, now: time.Now(), code: domain.ErrInvalidPass.Code},
+		{name: "no signature", signed: signed[:strings.LastIndexByte(signed, '.')], publicKey: publicKey, now: time.Now(), code: domain.ErrInvalidPass.Code},
+		{name: "foreign key", signed: signed, publicKey: otherKey.Public().(ed25519.PublicKey), now: time.Now(), code: domain.ErrInvalidPass.Code},
+		{name: "before the end", signed: signed, publicKey: publicKey, now: event.End.Add(-time.Second)},
+		{name: "at the end", signed: signed, publicKey: publicKey, now: event.End, code: domain.ErrPassExpired.Code},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pass, err := VerifyTicketPass(tc.signed, tc.publicKey, tc.now)
+			if errorCode(err) != tc.code {
+				t.Fatalf("pass got %q, want %q", errorCode(err), tc.code)
+			}
+			if err == nil && (pass.Code != code || pass.EventID != event.ID || pass.HolderName != user.Name) {
+				t.Errorf("pass %+v is not for ticket %s of %s", pass, code, user.Name)
+			}
+		})
+	}
+}
+
+// only the holder get the pass of a ticket that can still be used
+func TestGetTicketPass(t *testing.T) {
+	repos := newTestRepos(t, "memory")
+	kontek := systemContext()
+	_, passKey, _ := ed25519.GenerateKey(nil)
+	issuedTicketUsecase := NewIssuedTicketUsecase(repos.IssuedTicket, repos.Event, repos.User, repos.UnitOfWork, passKey)
+
+	event := newTestEvent(t, repos, "Pass", domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10})
+	holder := newTestUser(t, repos, "holder", 100000)
+	other := newTestUser(t, repos, "other", 0)
+	order, err := repos.orderUsecase().CreateOrder(domain.OrderRequest{UserID: holder.ID, EventID: event.ID, Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: 2}}}, kontek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued, err := repos.IssuedTicket.GetIssuedTicketsByOrderID(order.ID, kontek)
+	if err != nil || len(issued) != 2 {
+		t.Fatalf("got %d issued ticket %v", len(issued), err)
+	}
+	if _, err := issuedTicketUsecase.CheckInTicket(issued[1].Code, kontek); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		ticket string
+		user   domain.User
+		code   string
+	}{
+		{name: "holder", ticket: issued[0].Code, user: *holder},
+		{name: "typed in lower case", ticket: strings.ToLower(issued[0].Code), user: *holder},
+		{name: "other user", ticket: issued[0].Code, user: *other, code: domain.ErrNotYourTicket.Code},
+		{name: "used ticket", ticket: issued[1].Code, user: *holder, code: domain.ErrTicketUsed.Code},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := issuedTicketUsecase.GetTicketPass(tc.ticket, domain.WithUser(context.Background(), tc.user))
+			if errorCode(err) != tc.code {
+				t.Errorf("pass got %q, want %q", errorCode(err), tc.code)
+			}
+		})
+	}
+}
+
+// the signed pass with one byte changed
+func flipByte(signed string, i int) string {
+	changed := []byte(signed)
+	changed[i] ^= 1
+	return string(changed)
+}
@@ -2,30 +2,64 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/payment"
 	"pemesananTiketOnlineGo/internal/repository"
+	"slices"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
 // make a connection to repo
 type OrderUsecase struct {
-	OrderRepo repository.OrderRepoInterface
-	EventRepo repository.EventRepoInterface
-	UserRepo  repository.UserRepoInterface
+	OrderRepo        repository.OrderRepoInterface
+	EventRepo        repository.EventRepoInterface
+	UserRepo         repository.UserRepoInterface
+	PromoRepo        repository.PromoRepoInterface
+	PricingRepo      repository.PricingRepoInterface
+	SeatRepo         repository.SeatRepoInterface
+	WaitlistRepo     repository.WaitlistRepoInterface
+	QueueRepo        repository.QueueRepoInterface
+	IssuedTicketRepo repository.IssuedTicketRepoInterface
+	Payments         payment.Providers
+	UnitOfWork       repository.UnitOfWorkInterface
+	// the stock that come back is offered to the waitlist right after the commit
+	Waitlist OfferReturnedStock
+	// how long an order can wait for its payment before it expire
+	PaymentWindow time.Duration
 }
 
-func NewOrderUsecase(orderRepo repository.OrderRepoInterface, eventRepo repository.EventRepoInterface, userRepo repository.UserRepoInterface) OrderUsecaseInterface {
+func NewOrderUsecase(orderRepo repository.OrderRepoInterface, eventRepo repository.EventRepoInterface, userRepo repository.UserRepoInterface, promoRepo repository.PromoRepoInterface, pricingRepo repository.PricingRepoInterface, seatRepo repository.SeatRepoInterface, waitlistRepo repository.WaitlistRepoInterface, queueRepo repository.QueueRepoInterface, issuedTicketRepo repository.IssuedTicketRepoInterface, payments payment.Providers, unitOfWork repository.UnitOfWorkInterface, waitlist OfferReturnedStock, paymentWindow time.Duration) OrderUsecaseInterface {
 	return OrderUsecase{
-		OrderRepo: orderRepo,
-		EventRepo: eventRepo,
-		UserRepo:  userRepo,
+		OrderRepo:        orderRepo,
+		EventRepo:        eventRepo,
+		UserRepo:         userRepo,
+		PromoRepo:        promoRepo,
+		PricingRepo:      pricingRepo,
+		SeatRepo:         seatRepo,
+		WaitlistRepo:     waitlistRepo,
+		QueueRepo:        queueRepo,
+		IssuedTicketRepo: issuedTicketRepo,
+		Payments:         payments,
+		UnitOfWork:       unitOfWork,
+		Waitlist:         waitlist,
+		PaymentWindow:    paymentWindow,
 	}
 }
 
 type OrderUsecaseInterface interface {
 	CreateOrder
 	GetOrderByID
+	GetOrderByNumber
 	GetAllOrders
+	CancelOrder
+	RefundOrder
+	GetOrganizerSales
 }
 type CreateOrder interface {
 	CreateOrder(orederReq domain.OrderRequest, kontek context.Context) (*domain.Order, error)
@@ -33,63 +67,619 @@ type CreateOrder interface {
 type GetOrderByID interface {
 	GetOrderByID(id int, kontek context.Context) ([]domain.Order, error)
 }
+type GetOrderByNumber interface {
+	GetOrderByNumber(number string, kontek context.Context) (*domain.Order, error)
+}
 type GetAllOrders interface {
 	GetAllOrders(kontek context.Context) ([]domain.Order, error)
 }
+type CancelOrder interface {
+	CancelOrder(id int, kontek context.Context) (*domain.Order, error)
+}
+type RefundOrder interface {
+	RefundOrder(refundReq domain.RefundRequest, kontek context.Context) (*domain.Order, error)
+}
+type GetOrganizerSales interface {
+	GetOrganizerSales(organizerID int, kontek context.Context) (*domain.Sales, error)
+}
 
 func (uc OrderUsecase) CreateOrder(orderReq domain.OrderRequest, kontek context.Context) (*domain.Order, error) {
-	var order domain.Order
+	// without a method the order is paid from the wallet
+	method := orderReq.PaymentMethod
+	if method == "" {
+		method = domain.PaymentWallet
+	}
+	provider, err := uc.Payments.Get(method)
+	if err != nil {
+		return nil, err
+	}
+
+	// a user still waiting in the queue is turned away before touching the event
+	if err := checkQueue(uc.QueueRepo, orderReq.EventID, orderReq.UserID, orderReq.QueueToken, kontek); err != nil {
+		return nil, err
+	}
 
 	// get event first from event repo get by ID
 	event, err := uc.EventRepo.GetEventByID(orderReq.EventID, kontek)
 	if err != nil {
-		order.Status = "FAILED COULDN'T FIND EVENT ID🤬🚨🤬🚨"
 		return nil, err
 	}
+	if event.HasStarted(time.Now()) {
+		return nil, domain.ErrEventStarted
+	}
 
-	// this one is just for checking the user so i can use defer func
+	// check the user first so the order can be recorded with it
 	user, err := uc.UserRepo.GetUserByID(orderReq.UserID, kontek)
 	if err != nil {
-		order.Status = err.Error()
 		return nil, err
 	}
 
-	defer func() {
-		order.OrderDate = time.Now().Format("02-Jan-2006 15:04:05")
-		order.User.ID = user.ID
-		order.User.Name = user.Name
-		order.Event.Name = event.Name
-		order.Event.Date = event.Date
-		order.Event.Location = event.Location
-		order.Event.Description = event.Description
-		uc.OrderRepo.CreateOrder(&order, kontek)
-	}()
+	// every picked seat is one ticket of its ticket type
+	tickets, seats, err := seatedLines(uc.SeatRepo, event, orderReq.Ticket, orderReq.Seats, kontek)
+	if err != nil {
+		return nil, err
+	}
 
-	// check if the stock ticket is available and get the total value
-	total, err := uc.EventRepo.CheckTotalValue(orderReq.EventID, orderReq.Ticket, kontek)
+	order := newOrder(user, event, pricedTickets(event, tickets), method)
+
+	// stock check, stock decrement and saving the order happen in one transaction,
+	// it only wait for the purchase of the same event, user or promo
+	err = uc.UnitOfWork.WithinScope(kontek, purchaseScope(orderReq), func(kontek context.Context) error {
+		// the cap is checked inside the transaction so two parallel order of one user can't both pass
+		if err := checkPurchaseLimit(uc.OrderRepo, event, orderReq.UserID, tickets, kontek); err != nil {
+			return err
+		}
+
+		// the stock that came back is offered to the waitlist first
+		if err := checkWaitlist(uc.WaitlistRepo, uc.EventRepo, tickets, kontek); err != nil {
+			return err
+		}
+
+		// check if the stock ticket is available and get the total value
+		total, err := uc.EventRepo.CheckTotalValue(orderReq.EventID, tickets, kontek)
+		if err != nil {
+			return err
+		}
+		order.Subtotal = total
+		order.TotalPrice = total
+
+		// the promo is checked with the stock so its use cap can't be passed by parallel order
+		var promo *domain.Promo
+		if orderReq.PromoCode != "" {
+			if promo, err = applyPromo(uc.PromoRepo, &order, orderReq.PromoCode, kontek); err != nil {
+				return err
+			}
+		}
+		if err := chargeOrder(uc.PricingRepo, &order, kontek); err != nil {
+			return err
+		}
+
+		// decrease the total amount of ticket
+		if err := uc.EventRepo.DecrementTicketStock(orderReq.EventID, tickets, kontek); err != nil {
+			return err
+		}
+
+		order.Seats = orderSeats(seats)
+		if err := changeStatus(&order, domain.OrderPending, ""); err != nil {
+			return err
+		}
+		// the order wait for its payment from the start, so the reaper clean it up if the payment never finish
+		if uc.PaymentWindow > 0 {
+			due := time.Now().Add(uc.PaymentWindow)
+			order.PaymentDue = &due
+		}
+		if err := changeStatus(&order, domain.OrderAwaitingPayment, ""); err != nil {
+			return err
+		}
+		if _, err := uc.OrderRepo.CreateOrder(&order, kontek); err != nil {
+			return err
+		}
+		// the seat is taken only if nobody else hold or bought it in the meantime
+		if len(seats) > 0 {
+			if err := uc.SeatRepo.SellSeats(orderReq.Seats, order.ID, 0, kontek); err != nil {
+				return err
+			}
+		}
+		if promo != nil {
+			if err := redeemPromo(uc.PromoRepo, promo, &order, kontek); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		order.Status = "FAILED " + err.Error()
+		// the purchase is rolled back, still keep the failed order as a record
+		order.ID = 0
+		order.Status = ""
+		order.History = nil
+		order.PaymentDue = nil
+		order.Seats = nil
+		if statusErr := changeStatus(&order, domain.OrderFailed, failureReason(err)); statusErr != nil {
+			log.Error().Err(statusErr).Int("user", user.ID).Msg("Failed Order Record Failed")
+			return &order, err
+		}
+		if _, saveErr := uc.OrderRepo.CreateOrder(&order, kontek); saveErr != nil {
+			log.Error().Err(saveErr).Int("user", user.ID).Str("reason", order.FailureReason).Msg("Failed Order Record Failed")
+		}
 		return &order, err
 	}
 
-	// decrease the balance from user
-	_, err = uc.UserRepo.DecreaseBalance(orderReq.UserID, total, kontek)
+	return uc.payments().startPayment(provider, &order, kontek)
+}
+
+// the payment side of the order usecase
+func (uc OrderUsecase) payments() PaymentUsecase {
+	return PaymentUsecase{
+		OrderRepo:        uc.OrderRepo,
+		EventRepo:        uc.EventRepo,
+		PromoRepo:        uc.PromoRepo,
+		SeatRepo:         uc.SeatRepo,
+		IssuedTicketRepo: uc.IssuedTicketRepo,
+		UnitOfWork:       uc.UnitOfWork,
+		Payments:         uc.Payments,
+		Waitlist:         uc.Waitlist,
+	}
+}
+
+// cancel the whole order. an unpaid order only give the ticket back to the stock,
+// a paid one refund every ticket that is not refunded yet
+func (uc OrderUsecase) CancelOrder(id int, kontek context.Context) (*domain.Order, error) {
+	var order *domain.Order
+	err := uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		var err error
+		order, err = uc.OrderRepo.GetOrderByOrderID(id, kontek)
+		if err != nil {
+			return err
+		}
+		if !canAccess(order.User.ID, kontek) {
+			return domain.ErrNotYourOrder
+		}
+		if err := checkTransition(order, domain.OrderCancelled); err != nil {
+			return err
+		}
+
+		if order.Status == domain.OrderAwaitingPayment {
+			if err := uc.EventRepo.IncrementTicketStock(order.Event.ID, ticketLines(order.EventTicket), kontek); err != nil {
+				return err
+			}
+		} else {
+			refunded := ticketQuantity(order.Refunded)
+			var remaining []domain.Ticket
+			for _, ticket := range order.EventTicket {
+				if quantity := ticket.Quantity - refunded[ticket.ID]; quantity > 0 {
+					ticket.Quantity = quantity
+					remaining = append(remaining, ticket)
+				}
+			}
+			if err := uc.refund(order, remaining, kontek); err != nil {
+				return err
+			}
+			if err := voidTickets(uc.IssuedTicketRepo, order, remaining, nil, kontek); err != nil {
+				return err
+			}
+		}
+
+		if err := releaseOrderSeats(uc.SeatRepo, order, nil, kontek); err != nil {
+			return err
+		}
+		// the buyer can use the promo again
+		if err := uc.PromoRepo.ReleaseRedemption(order.ID, kontek); err != nil {
+			return err
+		}
+
+		if err := changeStatus(order, domain.OrderCancelled, ""); err != nil {
+			return err
+		}
+		return uc.OrderRepo.UpdateOrder(order, kontek)
+	})
 	if err != nil {
-		order.Status = "FAILED " + err.Error()
-		return &order, err
+		return nil, err
 	}
+	uc.Waitlist.OfferReturnedStock(ticketIDs(ticketLines(order.EventTicket)), kontek)
+	return order, nil
+}
 
-	// decrease the total amount of ticket
-	uc.EventRepo.DecrementTicketStock(orderReq.EventID, orderReq.Ticket, kontek)
+// refund part of the order
+func (uc OrderUsecase) RefundOrder(refundReq domain.RefundRequest, kontek context.Context) (*domain.Order, error) {
+	var order *domain.Order
+	err := uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		var err error
+		order, err = uc.OrderRepo.GetOrderByOrderID(refundReq.OrderID, kontek)
+		if err != nil {
+			return err
+		}
+		if !canAccess(order.User.ID, kontek) {
+			return domain.ErrNotYourOrder
+		}
 
-	order.PaymentMethod = "QRIS"
-	order.Status = "SUCCESS"
+		// a seated ticket is refunded by its seat
+		tickets, err := refundedSeats(order, refundReq.Seats)
+		if err != nil {
+			return err
+		}
+		// find the bought line of every requested ticket
+		for _, ticket := range refundReq.Ticket {
+			if slices.ContainsFunc(order.Seats, func(seat domain.Seat) bool { return seat.TicketID == ticket.ID }) {
+				return domain.ErrSeatRequired
+			}
+			found := false
+			for _, line := range order.EventTicket {
+				if line.ID == ticket.ID {
+					line.Quantity = ticket.Quantity
+					tickets = append(tickets, line)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return domain.NewError(domain.ErrNotFound, "TICKET_NOT_IN_ORDER", fmt.Sprintf("THERE'S NO TICKET TYPE %d IN THAT ORDER", ticket.ID))
+			}
+		}
+		if err := uc.refund(order, tickets, kontek); err != nil {
+			return err
+		}
+		if err := voidTickets(uc.IssuedTicketRepo, order, tickets, refundReq.Seats, kontek); err != nil {
+			return err
+		}
+		if err := releaseOrderSeats(uc.SeatRepo, order, refundReq.Seats, kontek); err != nil {
+			return err
+		}
 
-	return &order, nil
+		if err := changeStatus(order, domain.OrderRefunded, ""); err != nil {
+			return err
+		}
+		return uc.OrderRepo.UpdateOrder(order, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	uc.Waitlist.OfferReturnedStock(ticketIDs(ticketLines(order.Refunded)), kontek)
+	return order, nil
 }
+
+// put the ticket back to the stock, give the money back to the user and record it on the order
+func (uc OrderUsecase) refund(order *domain.Order, tickets []domain.Ticket, kontek context.Context) error {
+	if !domain.CanTransition(order.Status, domain.OrderRefunded) {
+		return domain.NewError(domain.ErrConflict, "ORDER_NOT_REFUNDABLE", "ORDER WITH STATUS "+order.Status+" CAN'T BE REFUNDED")
+	}
+
+	event, err := uc.EventRepo.GetEventByID(order.Event.ID, kontek)
+	if err != nil {
+		return err
+	}
+	if err := checkRefundPolicy(event, time.Now()); err != nil {
+		return err
+	}
+
+	if len(tickets) == 0 {
+		return domain.ErrNothingToRefund
+	}
+
+	// can't refund more than what is bought
+	refunded := ticketQuantity(order.Refunded)
+	bought := ticketQuantity(order.EventTicket)
+	for _, ticket := range tickets {
+		refunded[ticket.ID] += ticket.Quantity
+		if refunded[ticket.ID] > bought[ticket.ID] {
+			return domain.ErrRefundTooMuch
+		}
+	}
+	amount, err := refundAmount(order, tickets, refunded)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.EventRepo.IncrementTicketStock(event.ID, ticketLines(tickets), kontek); err != nil {
+		return err
+	}
+	provider, err := uc.Payments.Get(order.PaymentMethod)
+	if err != nil {
+		return err
+	}
+	if err := provider.Refund(order, amount, kontek); err != nil {
+		return err
+	}
+
+	order.Refunded = nil
+	for _, line := range order.EventTicket {
+		if refunded[line.ID] > 0 {
+			line.Quantity = refunded[line.ID]
+			order.Refunded = append(order.Refunded, line)
+		}
+	}
+	order.RefundAmount, err = order.RefundAmount.Add(amount)
+	return err
+}
+
 func (uc OrderUsecase) GetOrderByID(userID int, kontek context.Context) ([]domain.Order, error) {
+	if !canAccess(userID, kontek) {
+		return nil, domain.ErrNotYourOrder
+	}
 	return uc.OrderRepo.GetOrderByID(userID, kontek)
 }
+
+// the order of its public number, only its buyer can see it
+func (uc OrderUsecase) GetOrderByNumber(number string, kontek context.Context) (*domain.Order, error) {
+	order, err := uc.OrderRepo.GetOrderByNumber(strings.ToUpper(strings.TrimSpace(number)), kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !canAccess(order.User.ID, kontek) {
+		return nil, domain.ErrNotYourOrder
+	}
+	return order, nil
+}
+
 func (uc OrderUsecase) GetAllOrders(kontek context.Context) ([]domain.Order, error) {
 	return uc.OrderRepo.GetAllOrders(kontek)
 }
+
+// sum the paid orders of every event owned by the organizer
+func (uc OrderUsecase) GetOrganizerSales(organizerID int, kontek context.Context) (*domain.Sales, error) {
+	if !canAccess(organizerID, kontek) {
+		return nil, domain.ErrNotYourEvent
+	}
+	events, err := uc.EventRepo.GetAllEvents(kontek)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+
+	sales := domain.Sales{OrganizerID: organizerID, Events: []domain.EventSales{}}
+	for _, event := range events {
+		if event.OrganizerID != organizerID {
+			continue
+		}
+		orders, err := uc.OrderRepo.GetOrdersByEventID(event.ID, kontek)
+		if err != nil {
+			return nil, err
+		}
+
+		eventSales := domain.EventSales{EventID: event.ID, Name: event.Name, Orders: []domain.Order{}}
+		for _, order := range orders {
+			if !order.WasPaid() {
+				continue
+			}
+			refunded := ticketQuantity(order.Refunded)
+			for _, ticket := range order.EventTicket {
+				eventSales.TicketSold += ticket.Quantity - refunded[ticket.ID]
+			}
+			revenue, err := order.TotalPrice.Sub(order.RefundAmount)
+			if err != nil {
+				return nil, err
+			}
+			if eventSales.Revenue, err = eventSales.Revenue.Add(revenue); err != nil {
+				return nil, err
+			}
+			eventSales.Orders = append(eventSales.Orders, order)
+		}
+		sales.TicketSold += eventSales.TicketSold
+		if sales.Revenue, err = sales.Revenue.Add(eventSales.Revenue); err != nil {
+			return nil, err
+		}
+		sales.Events = append(sales.Events, eventSales)
+	}
+	return &sales, nil
+}
+
+// every write of a purchase belong to its event, its user or its promo
+func purchaseScope(orderReq domain.OrderRequest) []string {
+	keys := []string{repository.EventKey(orderReq.EventID), repository.UserKey(orderReq.UserID)}
+	if orderReq.PromoCode != "" {
+		keys = append(keys, repository.PromoKey(orderReq.PromoCode))
+	}
+	return keys
+}
+
+// the scope of a saved order. its event, user and promo never change, so the order can be read
+// before its scope is taken
+func orderScope(order *domain.Order) []string {
+	return purchaseScope(domain.OrderRequest{EventID: order.Event.ID, UserID: order.User.ID, PromoCode: order.PromoCode})
+}
+
+// the order is paid, every person of it get a ticket at once
+func fulfilOrder(issuedTicketRepo repository.IssuedTicketRepoInterface, order *domain.Order, kontek context.Context) error {
+	if err := changeStatus(order, domain.OrderPaid, ""); err != nil {
+		return err
+	}
+	if err := issueTickets(issuedTicketRepo, order, kontek); err != nil {
+		return err
+	}
+	return changeStatus(order, domain.OrderFulfilled, "")
+}
+
+// move the order to the next status and record the step in its history
+func changeStatus(order *domain.Order, status string, reason string) error {
+	if err := checkTransition(order, status); err != nil {
+		return err
+	}
+	order.History = append(order.History, domain.OrderStatusChange{From: order.Status, To: status, Reason: reason, At: time.Now()})
+	order.Status = status
+	if status == domain.OrderFailed {
+		order.FailureReason = reason
+	}
+	return nil
+}
+
+// the state machine must allow the move from the current status
+func checkTransition(order *domain.Order, status string) error {
+	if !domain.CanTransition(order.Status, status) {
+		return domain.NewError(domain.ErrConflict, "INVALID_ORDER_TRANSITION", "ORDER WITH STATUS "+order.Status+" CAN'T BE "+status)
+	}
+	return nil
+}
+
+// the error code is the failure reason, an error that is not ours keep its text
+func failureReason(err error) string {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return err.Error()
+}
+
+// the ticket type of every line, for the waitlist of the stock that came back
+func ticketIDs(tickets []domain.TicketLine) []int {
+	ids := make([]int, 0, len(tickets))
+	for _, ticket := range tickets {
+		ids = append(ids, ticket.ID)
+	}
+	return ids
+}
+
+// make the order record from the buyer, the event and the bought ticket
+func newOrder(user *domain.User, event *domain.Event, tickets []domain.Ticket, method string) domain.Order {
+	var order domain.Order
+	order.OrderDate = time.Now().Format("02-Jan-2006 15:04:05")
+	order.User.ID = user.ID
+	order.User.Name = user.Name
+	order.Event.ID = event.ID
+	order.Event.Name = event.Name
+	order.Event.Start = event.Start
+	order.Event.End = event.End
+	order.Event.TimeZone = event.TimeZone
+	order.Event.Location = event.Location
+	order.Event.Description = event.Description
+	order.EventTicket = tickets
+	order.PaymentMethod = method
+	return order
+}
+
+// fill the ID, type and price of the bought ticket from the event, one line for every event ticket
+func pricedTickets(event *domain.Event, tickets []domain.TicketLine) []domain.Ticket {
+	var priced []domain.Ticket
+	for _, eventTicket := range event.Ticket {
+		quantity := 0
+		for _, ticket := range tickets {
+			if eventTicket.ID == ticket.ID {
+				quantity += ticket.Quantity
+			}
+		}
+		if quantity > 0 {
+			priced = append(priced, domain.Ticket{ID: eventTicket.ID, Type: eventTicket.Type, Price: eventTicket.Price, Quantity: quantity})
+		}
+	}
+	return priced
+}
+
+// total price of the ticket lines, every line must be in the same currency
+func ticketsTotal(tickets []domain.Ticket) (domain.Money, error) {
+	var total domain.Money
+	for _, ticket := range tickets {
+		price, err := ticket.Price.Mul(ticket.Quantity)
+		if err != nil {
+			return domain.Money{}, err
+		}
+		if total, err = total.Add(price); err != nil {
+			return domain.Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// the money given back for the ticket. with a discount, fee or tax every ticket give back its share of what was paid,
+// and the last refund give back the rest so the rounding never keep or add money
+func refundAmount(order *domain.Order, tickets []domain.Ticket, refunded map[int]int) (domain.Money, error) {
+	amount, err := ticketsTotal(tickets)
+	if err != nil || order.TotalPrice.Equal(order.Subtotal) {
+		return amount, err
+	}
+	for _, line := range order.EventTicket {
+		if refunded[line.ID] >= line.Quantity {
+			continue
+		}
+		// a free ticket give back nothing until the last refund
+		if order.Subtotal.IsZero() {
+			return domain.Money{Currency: order.TotalPrice.Currency}, nil
+		}
+		return amount.Scale(order.TotalPrice.Amount, order.Subtotal.Amount), nil
+	}
+	return order.TotalPrice.Sub(order.RefundAmount)
+}
+
+// the ticket type and quantity of every line, to give it back to the stock
+func ticketLines(tickets []domain.Ticket) []domain.TicketLine {
+	lines := make([]domain.TicketLine, 0, len(tickets))
+	for _, ticket := range tickets {
+		lines = append(lines, domain.TicketLine{ID: ticket.ID, Quantity: ticket.Quantity})
+	}
+	return lines
+}
+
+// quantity of every ticket ID in the list
+func ticketQuantity(tickets []domain.Ticket) map[int]int {
+	quantity := map[int]int{}
+	for _, ticket := range tickets {
+		quantity[ticket.ID] += ticket.Quantity
+	}
+	return quantity
+}
+
+// the event must allow refund and the refund must be before the cutoff
+func checkRefundPolicy(event *domain.Event, now time.Time) error {
+	if event.Refund == nil || !event.Refund.Refundable {
+		return domain.ErrRefundNotAllowed
+	}
+	if !now.Before(event.Start.Add(-time.Duration(event.Refund.CutoffHours) * time.Hour)) {
+		return domain.NewError(domain.ErrForbidden, "REFUND_CLOSED", fmt.Sprintf("REFUND IS CLOSED %d HOURS BEFORE THE EVENT", event.Refund.CutoffHours))
+	}
+	return nil
+}
+
+// every ticket type cap, per order and per user over all the order of the user for the event
+func checkPurchaseLimit(orderRepo repository.OrderRepoInterface, event *domain.Event, userID int, tickets []domain.TicketLine, kontek context.Context) error {
+	requested := map[int]int{}
+	for _, ticket := range tickets {
+		requested[ticket.ID] += ticket.Quantity
+	}
+
+	var owned map[int]int
+	for _, eventTicket := range event.Ticket {
+		quantity := requested[eventTicket.ID]
+		if quantity == 0 {
+			continue
+		}
+		if eventTicket.MaxPerOrder > 0 && quantity > eventTicket.MaxPerOrder {
+			return domain.NewError(domain.ErrInvalidInput, "ORDER_LIMIT_EXCEEDED", fmt.Sprintf("MAX %d TICKET %s PER ORDER", eventTicket.MaxPerOrder, eventTicket.Type))
+		}
+		if eventTicket.MaxPerUser == 0 {
+			continue
+		}
+		if owned == nil {
+			var err error
+			if owned, err = ownedTickets(orderRepo, event.ID, userID, kontek); err != nil {
+				return err
+			}
+		}
+		if owned[eventTicket.ID]+quantity > eventTicket.MaxPerUser {
+			return domain.NewError(domain.ErrConflict, "USER_LIMIT_EXCEEDED", fmt.Sprintf("MAX %d TICKET %s PER USER, YOU ALREADY HAVE %d", eventTicket.MaxPerUser, eventTicket.Type, owned[eventTicket.ID]))
+		}
+	}
+	return nil
+}
+
+// ticket of the event the user already bought or is paying, a failed, expired, cancelled or refunded ticket is not counted
+func ownedTickets(orderRepo repository.OrderRepoInterface, eventID, userID int, kontek context.Context) (map[int]int, error) {
+	owned := map[int]int{}
+	orders, err := orderRepo.GetOrderByID(userID, kontek)
+	if errors.Is(err, domain.ErrUserHasNoOrder) {
+		return owned, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		if order.Event.ID != eventID {
+			continue
+		}
+		switch order.Status {
+		case domain.OrderFailed, domain.OrderExpired, domain.OrderCancelled:
+			continue
+		}
+		refunded := ticketQuantity(order.Refunded)
+		for _, line := range order.EventTicket {
+			owned[line.ID] += line.Quantity - refunded[line.ID]
+		}
+	}
+	return owned, nil
+}
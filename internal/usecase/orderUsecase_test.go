@@ -0,0 +1,451 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// many buyer order at the same time, no ticket is oversold and no money is lost
+func TestCreateOrderParallel(t *testing.T) {
+	cases := []struct {
+		name    string
+		orders  int
+		users   int
+		balance int64
+		stock   int
+		// only run without -short
+		large bool
+	}{
+		// more ticket is ordered than there is stock
+		{name: "scarce stock", orders: 120, users: 20, balance: 100000000, stock: 40},
+		// the buyer run out of money before the stock run out
+		{name: "poor buyer", orders: 120, users: 20, balance: 60000, stock: 500},
+		// thousands of order, most of them fight for the last ticket
+		{name: "thousands of order", orders: 3000, users: 200, balance: 300000, stock: 1000, large: true},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				if tc.large && testing.Short() {
+					t.Skip("thousands of order is skipped with -short")
+				}
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				orderUsecase := repos.orderUsecase()
+				walletUsecase := NewWalletUsecase(repos.Wallet, repos.User, repos.Order)
+				kontek := systemContext()
+
+				event := newTestEvent(t, repos, "Parallel",
+					domain.Ticket{Type: "VIP", Price: domain.NewMoney(50000, domain.DefaultCurrency), Quantity: tc.stock},
+					domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: tc.stock})
+				var startBalance int64
+				var users []int
+				for i := 1; i <= tc.users; i++ {
+					users = append(users, newTestUser(t, repos, fmt.Sprintf("user%d", i), tc.balance).ID)
+					startBalance += tc.balance
+				}
+
+				var wg sync.WaitGroup
+				for i := 0; i < tc.orders; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						orderReq := domain.OrderRequest{
+							UserID:  users[i%len(users)],
+							EventID: event.ID,
+							Ticket:  []domain.TicketLine{{ID: event.Ticket[i%2].ID, Quantity: i%3 + 1}},
+						}
+						// a failed order is expected, the check is on what got through
+						orderUsecase.CreateOrder(orderReq, kontek)
+					}(i)
+				}
+				wg.Wait()
+
+				orders, err := repos.Order.GetAllOrders(kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sold := map[int]int{}
+				var paid int64
+				for _, order := range orders {
+					if order.Status != domain.OrderFulfilled {
+						continue
+					}
+					paid += order.TotalPrice.Amount
+					for _, ticket := range order.EventTicket {
+						sold[ticket.ID] += ticket.Quantity
+					}
+				}
+				if paid == 0 {
+					t.Fatal("no order was fulfilled")
+				}
+
+				stock, err := repos.Event.GetEventByID(event.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i, ticket := range stock.Ticket {
+					if ticket.Quantity < 0 {
+						t.Errorf("ticket %s oversold, stock is %d", ticket.Type, ticket.Quantity)
+					}
+					if ticket.Quantity+sold[ticket.ID] != event.Ticket[i].Quantity {
+						t.Errorf("ticket %s stock %d + sold %d != %d", ticket.Type, ticket.Quantity, sold[ticket.ID], event.Ticket[i].Quantity)
+					}
+				}
+
+				var endBalance int64
+				for _, userID := range users {
+					balance, err := repos.Wallet.GetBalance(userID, kontek)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if balance.IsNegative() {
+						t.Errorf("user %d balance is negative %s", userID, balance)
+					}
+					endBalance += balance.Amount
+				}
+				if endBalance+paid != startBalance {
+					t.Errorf("balance lost, %d + %d != %d", endBalance, paid, startBalance)
+				}
+
+				// the purchase of the ledger add up to the total of the fulfilled order
+				entries, err := repos.Wallet.GetAllEntries(kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var debited int64
+				for _, entry := range entries {
+					if entry.Kind == domain.WalletPurchase {
+						debited -= entry.Amount.Amount
+					}
+				}
+				if debited != paid {
+					t.Errorf("ledger debited %d, the fulfilled order total %d", debited, paid)
+				}
+				report, err := walletUsecase.Reconcile(kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !report.Balanced {
+					t.Errorf("wallet ledger doesn't match the orders %+v", report.Mismatches)
+				}
+			})
+		}
+	}
+}
+
+// the old locking, every purchase wait for every other purchase
+type exclusiveUnitOfWork struct {
+	repository.UnitOfWorkInterface
+}
+
+func (uow exclusiveUnitOfWork) WithinScope(kontek context.Context, keys []string, fn func(kontek context.Context) error) error {
+	return uow.WithinTransaction(kontek, fn)
+}
+
+// buy the ticket of many event at the same time with one lock for everything
+func BenchmarkPurchaseGlobal(b *testing.B) {
+	benchmarkPurchase(b, func(uow repository.UnitOfWorkInterface) repository.UnitOfWorkInterface {
+		return exclusiveUnitOfWork{uow}
+	})
+}
+
+// buy the ticket of many event at the same time with the lock of every event and user
+func BenchmarkPurchaseScoped(b *testing.B) {
+	benchmarkPurchase(b, func(uow repository.UnitOfWorkInterface) repository.UnitOfWorkInterface {
+		return uow
+	})
+}
+
+// order repo that take a while to write, like a repo that write to the disk
+type slowOrderRepo struct {
+	repository.OrderRepoInterface
+	wait time.Duration
+}
+
+func (repo slowOrderRepo) CreateOrder(order *domain.Order, kontek context.Context) (*domain.Order, error) {
+	time.Sleep(repo.wait)
+	return repo.OrderRepoInterface.CreateOrder(order, kontek)
+}
+
+func (repo slowOrderRepo) UpdateOrder(order *domain.Order, kontek context.Context) error {
+	time.Sleep(repo.wait)
+	return repo.OrderRepoInterface.UpdateOrder(order, kontek)
+}
+
+// every purchase run once with no wait inside the lock and once with a write that wait
+func benchmarkPurchase(b *testing.B, lock func(uow repository.UnitOfWorkInterface) repository.UnitOfWorkInterface) {
+	for _, wait := range []time.Duration{0, 100 * time.Microsecond} {
+		b.Run(fmt.Sprintf("wait %s", wait), func(b *testing.B) {
+			purchaseInParallel(b, lock, wait)
+		})
+	}
+}
+
+func purchaseInParallel(b *testing.B, lock func(uow repository.UnitOfWorkInterface) repository.UnitOfWorkInterface, wait time.Duration) {
+	const events, users = 50, 200
+	repos := newTestRepos(b, "memory")
+	repos.UnitOfWork = lock(repos.UnitOfWork)
+	if wait > 0 {
+		repos.Order = slowOrderRepo{repos.Order, wait}
+	}
+	orderUsecase := repos.orderUsecase()
+	kontek := systemContext()
+
+	// every event has enough stock for all of the order, so no order fail
+	var eventIDs, ticketIDs []int
+	for i := 1; i <= events; i++ {
+		event, err := repos.Event.CreateEvent(&domain.Event{Name: fmt.Sprintf("Bench %d", i), Start: time.Now().Add(24 * time.Hour), End: time.Now().Add(27 * time.Hour), Description: "Bench", Location: "Bench",
+			Ticket: []domain.Ticket{{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: b.N}}}, kontek)
+		if err != nil {
+			b.Fatal(err)
+		}
+		eventIDs = append(eventIDs, event.ID)
+		ticketIDs = append(ticketIDs, event.Ticket[0].ID)
+	}
+	var userIDs []int
+	for i := 1; i <= users; i++ {
+		user, err := repos.User.CreateUser(&domain.User{Name: fmt.Sprintf("bench%d", i)}, kontek)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := repos.Wallet.AddEntry(&domain.WalletEntry{UserID: user.ID, Kind: domain.WalletTopUp, Amount: domain.NewMoney(int64(b.N)*2500000, domain.DefaultCurrency)}, kontek); err != nil {
+			b.Fatal(err)
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+
+	var next atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := int(next.Add(1))
+			orderReq := domain.OrderRequest{
+				UserID:  userIDs[i%users],
+				EventID: eventIDs[i%events],
+				Ticket:  []domain.TicketLine{{ID: ticketIDs[i%events], Quantity: 1}},
+			}
+			if _, err := orderUsecase.CreateOrder(orderReq, kontek); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+// one user order the same capped ticket many times at the same time, the cap of the order
+// and of the user hold
+func TestCreateOrderPurchaseLimit(t *testing.T) {
+	cases := []struct {
+		name     string
+		quantity int
+		parallel int
+		// the ticket the user can own in the end and the code of the order that went over
+		owned int
+		code  string
+	}{
+		{name: "over the order cap", quantity: 4, parallel: 1, owned: 0, code: "ORDER_LIMIT_EXCEEDED"},
+		{name: "over the user cap in parallel", quantity: 1, parallel: 20, owned: 5, code: "USER_LIMIT_EXCEEDED"},
+		{name: "rest of the user cap", quantity: 3, parallel: 10, owned: 3, code: "USER_LIMIT_EXCEEDED"},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				orderUsecase := repos.orderUsecase()
+				kontek := systemContext()
+
+				event := newTestEvent(t, repos, "Limit",
+					domain.Ticket{Type: "VIP", Price: domain.NewMoney(50000, domain.DefaultCurrency), Quantity: 100, MaxPerOrder: 3, MaxPerUser: 5})
+				user := newTestUser(t, repos, "scalper", 100000000)
+
+				var mutek sync.Mutex
+				codes := map[string]int{}
+				var wg sync.WaitGroup
+				for i := 0; i < tc.parallel; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, err := orderUsecase.CreateOrder(domain.OrderRequest{UserID: user.ID, EventID: event.ID, Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: tc.quantity}}}, kontek)
+						mutek.Lock()
+						codes[errorCode(err)]++
+						mutek.Unlock()
+					}()
+				}
+				wg.Wait()
+
+				for code := range codes {
+					if code != "" && code != tc.code {
+						t.Errorf("order failed with %s, want %s", code, tc.code)
+					}
+				}
+				if codes[tc.code] == 0 {
+					t.Errorf("no order failed with %s", tc.code)
+				}
+				owned, err := ownedTickets(repos.Order, event.ID, user.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if owned[event.Ticket[0].ID] != tc.owned {
+					t.Errorf("user own %d ticket, want %d", owned[event.Ticket[0].ID], tc.owned)
+				}
+			})
+		}
+	}
+}
+
+// a refund or a cancel give the ticket back to the stock and the money back to the wallet,
+// and what is already given back can't be given again
+func TestRefundAndCancelOrder(t *testing.T) {
+	// one step on the bought order, a refund of the quantity or a cancel, and the code it fail with
+	type step struct {
+		refund int
+		cancel bool
+		code   string
+	}
+	cases := []struct {
+		name  string
+		steps []step
+	}{
+		{name: "refund part then the rest", steps: []step{{refund: 1}, {refund: 2}, {refund: 1, code: "REFUND_QUANTITY_EXCEEDED"}}},
+		{name: "refund more than bought", steps: []step{{refund: 4, code: "REFUND_QUANTITY_EXCEEDED"}, {refund: 3}, {cancel: true, code: "NOTHING_TO_REFUND"}}},
+		{name: "cancel twice", steps: []step{{cancel: true}, {cancel: true, code: "INVALID_ORDER_TRANSITION"}, {refund: 1, code: "ORDER_NOT_REFUNDABLE"}}},
+		{name: "refund then cancel", steps: []step{{refund: 1}, {cancel: true}, {refund: 1, code: "ORDER_NOT_REFUNDABLE"}}},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				orderUsecase := repos.orderUsecase()
+				walletUsecase := NewWalletUsecase(repos.Wallet, repos.User, repos.Order)
+				kontek := systemContext()
+
+				event, err := repos.Event.CreateEvent(&domain.Event{Name: "Refund", Start: time.Now().Add(24 * time.Hour), End: time.Now().Add(27 * time.Hour), Description: "Refund", Location: "Refund",
+					Refund: &domain.RefundPolicy{Refundable: true},
+					Ticket: []domain.Ticket{{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10}}}, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				user := newTestUser(t, repos, "buyer", 100000)
+				order, err := orderUsecase.CreateOrder(domain.OrderRequest{UserID: user.ID, EventID: event.ID, Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: 3}}}, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for i, step := range tc.steps {
+					if step.cancel {
+						_, err = orderUsecase.CancelOrder(order.ID, kontek)
+					} else {
+						_, err = orderUsecase.RefundOrder(domain.RefundRequest{OrderID: order.ID, Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: step.refund}}}, kontek)
+					}
+					if errorCode(err) != step.code {
+						t.Fatalf("step %d got %v, want %q", i+1, err, step.code)
+					}
+				}
+
+				// every case end with all of the ticket given back
+				stock, err := repos.Event.GetEventByID(event.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stock.Ticket[0].Quantity != 10 {
+					t.Errorf("stock is %d, want 10", stock.Ticket[0].Quantity)
+				}
+				balance, err := repos.Wallet.GetBalance(user.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if balance.Amount != 100000 {
+					t.Errorf("balance is %s, want 100000", balance)
+				}
+
+				saved, err := repos.Order.GetOrderByOrderID(order.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if saved.RefundAmount.Amount != order.TotalPrice.Amount {
+					t.Errorf("order refunded %s, want %s", saved.RefundAmount, order.TotalPrice)
+				}
+				entries, err := repos.Wallet.GetAllEntries(kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var refunded int64
+				for _, entry := range entries {
+					if entry.Kind == domain.WalletRefund {
+						refunded += entry.Amount.Amount
+					}
+				}
+				if refunded != order.TotalPrice.Amount {
+					t.Errorf("ledger refunded %d, want %d", refunded, order.TotalPrice.Amount)
+				}
+				report, err := walletUsecase.Reconcile(kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !report.Balanced {
+					t.Errorf("wallet ledger doesn't match the orders %+v", report.Mismatches)
+				}
+			})
+		}
+	}
+}
+
+// every move of the state machine, the allowed one is recorded in the history and the rest is refused
+// without touching the order
+func TestChangeStatus(t *testing.T) {
+	statuses := []string{"", domain.OrderPending, domain.OrderAwaitingPayment, domain.OrderPaid, domain.OrderFulfilled,
+		domain.OrderCancelled, domain.OrderRefunded, domain.OrderExpired, domain.OrderFailed}
+	// written out here, not read from the domain, so a change of the machine has to change the test too
+	allowed := map[string][]string{
+		"":                          {domain.OrderPending, domain.OrderFailed},
+		domain.OrderPending:         {domain.OrderAwaitingPayment, domain.OrderPaid, domain.OrderFailed},
+		domain.OrderAwaitingPayment: {domain.OrderPaid, domain.OrderFailed, domain.OrderExpired, domain.OrderCancelled},
+		domain.OrderPaid:            {domain.OrderFulfilled, domain.OrderRefunded, domain.OrderCancelled},
+		domain.OrderFulfilled:       {domain.OrderRefunded, domain.OrderCancelled},
+		domain.OrderRefunded:        {domain.OrderRefunded, domain.OrderCancelled},
+	}
+	for _, from := range statuses {
+		for _, to := range statuses[1:] {
+			t.Run(fmt.Sprintf("%q to %s", from, to), func(t *testing.T) {
+				before := domain.OrderStatusChange{From: "", To: from}
+				order := domain.Order{Status: from, History: []domain.OrderStatusChange{before}}
+				err := changeStatus(&order, to, "WHY")
+
+				if !slices.Contains(allowed[from], to) {
+					if errorCode(err) != "INVALID_ORDER_TRANSITION" {
+						t.Fatalf("got %v, want INVALID_ORDER_TRANSITION", err)
+					}
+					if order.Status != from || len(order.History) != 1 || order.FailureReason != "" {
+						t.Errorf("refused move changed the order to %+v", order)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if order.Status != to {
+					t.Errorf("status is %s, want %s", order.Status, to)
+				}
+				if len(order.History) != 2 || order.History[0] != before {
+					t.Fatalf("history is %+v, want the old step and one more", order.History)
+				}
+				if step := order.History[1]; step.From != from || step.To != to || step.Reason != "WHY" || step.At.IsZero() {
+					t.Errorf("new step is %+v, want %q to %s because WHY", step, from, to)
+				}
+				// only a failed order keep the reason as its failure
+				if (order.FailureReason == "WHY") != (to == domain.OrderFailed) {
+					t.Errorf("failure reason is %q for %s", order.FailureReason, to)
+				}
+			})
+		}
+	}
+}
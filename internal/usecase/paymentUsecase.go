@@ -0,0 +1,322 @@
+package usecase
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/payment"
+	"pemesananTiketOnlineGo/internal/repository"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// make a connection to repo and the payment provider
+type PaymentUsecase struct {
+	OrderRepo        repository.OrderRepoInterface
+	EventRepo        repository.EventRepoInterface
+	PromoRepo        repository.PromoRepoInterface
+	SeatRepo         repository.SeatRepoInterface
+	IssuedTicketRepo repository.IssuedTicketRepoInterface
+	UnitOfWork       repository.UnitOfWorkInterface
+	Payments         payment.Providers
+	Simulator        payment.SimulatorInterface
+	// the stock that come back is offered to the waitlist right after the commit
+	Waitlist OfferReturnedStock
+}
+
+func NewPaymentUsecase(orderRepo repository.OrderRepoInterface, eventRepo repository.EventRepoInterface, promoRepo repository.PromoRepoInterface, seatRepo repository.SeatRepoInterface, issuedTicketRepo repository.IssuedTicketRepoInterface, unitOfWork repository.UnitOfWorkInterface, payments payment.Providers, simulator payment.SimulatorInterface, waitlist OfferReturnedStock) PaymentUsecaseInterface {
+	return PaymentUsecase{
+		OrderRepo:        orderRepo,
+		EventRepo:        eventRepo,
+		PromoRepo:        promoRepo,
+		SeatRepo:         seatRepo,
+		IssuedTicketRepo: issuedTicketRepo,
+		UnitOfWork:       unitOfWork,
+		Payments:         payments,
+		Simulator:        simulator,
+		Waitlist:         waitlist,
+	}
+}
+
+type PaymentUsecaseInterface interface {
+	PaymentCallback
+	ConfirmPayment
+	ScriptSimulator
+	SettleSimulated
+	ExpireUnpaidOrders
+	RunPaymentReaper
+}
+type PaymentCallback interface {
+	PaymentCallback(callback domain.PaymentCallback, kontek context.Context) (*domain.Order, error)
+}
+type ConfirmPayment interface {
+	ConfirmPayment(orderID int, kontek context.Context) (*domain.Order, error)
+}
+type ScriptSimulator interface {
+	ScriptSimulator(script domain.SimulatorScript, kontek context.Context) error
+}
+type SettleSimulated interface {
+	SettleSimulated(settlement domain.SimulatorSettlement, kontek context.Context) error
+}
+type ExpireUnpaidOrders interface {
+	ExpireUnpaidOrders(kontek context.Context) (int, error)
+}
+type RunPaymentReaper interface {
+	RunPaymentReaper(kontek context.Context, interval time.Duration)
+}
+
+// the provider tell that a payment is paid or failed
+func (uc PaymentUsecase) PaymentCallback(callback domain.PaymentCallback, kontek context.Context) (*domain.Order, error) {
+	provider, err := uc.Payments.Get(callback.Method)
+	if err != nil {
+		return nil, err
+	}
+	paid, err := provider.Callback(callback, kontek)
+	if err != nil {
+		return nil, err
+	}
+	return uc.applyPayment(paid, kontek)
+}
+
+// ask the provider for the payment status instead of waiting for the callback
+func (uc PaymentUsecase) ConfirmPayment(orderID int, kontek context.Context) (*domain.Order, error) {
+	order, err := uc.OrderRepo.GetOrderByOrderID(orderID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !canAccess(order.User.ID, kontek) {
+		return nil, domain.ErrNotYourOrder
+	}
+	provider, err := uc.Payments.Get(order.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	paid, err := provider.Confirm(order, kontek)
+	if err != nil {
+		return nil, err
+	}
+	return uc.applyPayment(paid, kontek)
+}
+
+func (uc PaymentUsecase) ScriptSimulator(script domain.SimulatorScript, kontek context.Context) error {
+	if !isAdmin(kontek) {
+		return domain.ErrRoleNotAllowed
+	}
+	uc.Simulator.Script(script)
+	return nil
+}
+
+func (uc PaymentUsecase) SettleSimulated(settlement domain.SimulatorSettlement, kontek context.Context) error {
+	if !isAdmin(kontek) {
+		return domain.ErrRoleNotAllowed
+	}
+	return uc.Simulator.Settle(settlement)
+}
+
+// expire every order that is not paid before its payment due and return the ticket to the stock
+func (uc PaymentUsecase) ExpireUnpaidOrders(kontek context.Context) (int, error) {
+	orders, err := uc.OrderRepo.GetExpiredOrders(time.Now(), kontek)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, unpaid := range orders {
+		// the money can be taken without the order knowing it, like a wallet debit whose order was
+		// not saved after it, so the provider is asked before giving up on the order
+		if provider, err := uc.Payments.Get(unpaid.PaymentMethod); err == nil {
+			if paid, err := provider.Confirm(&unpaid, kontek); err == nil && paid.Status == domain.PaymentPaid {
+				if _, err := uc.applyPayment(paid, kontek); err != nil {
+					log.Error().Err(err).Int("order", unpaid.ID).Msg("Payment Reaper Failed")
+				}
+				continue
+			}
+		}
+
+		stillUnpaid := false
+		err := uc.UnitOfWork.WithinScope(kontek, orderScope(&unpaid), func(kontek context.Context) error {
+			// the payment can come after the order was listed
+			order, err := uc.OrderRepo.GetOrderByOrderID(unpaid.ID, kontek)
+			if err != nil {
+				return err
+			}
+			if order.Status != domain.OrderAwaitingPayment {
+				return nil
+			}
+			stillUnpaid = true
+			if err := uc.releaseOrder(order, kontek); err != nil {
+				return err
+			}
+			if err := changeStatus(order, domain.OrderExpired, "PAYMENT_WINDOW_PASSED"); err != nil {
+				return err
+			}
+			return uc.OrderRepo.UpdateOrder(order, kontek)
+		})
+		// one order that can't be expired must not keep the stock of every other order
+		if err != nil {
+			log.Error().Err(err).Int("order", unpaid.ID).Msg("Payment Reaper Failed")
+			continue
+		}
+		if stillUnpaid {
+			expired++
+			uc.Waitlist.OfferReturnedStock(ticketIDs(ticketLines(unpaid.EventTicket)), kontek)
+		}
+	}
+	return expired, nil
+}
+
+// run the reaper every interval until kontek is done
+func (uc PaymentUsecase) RunPaymentReaper(kontek context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-kontek.Done():
+			return
+		case <-ticker.C:
+			expired, err := uc.ExpireUnpaidOrders(kontek)
+			if err != nil {
+				log.Error().Err(err).Msg("Payment Reaper Failed")
+				continue
+			}
+			if expired > 0 {
+				log.Info().Int("expired", expired).Msg("Payment Reaper Success")
+			}
+		}
+	}
+}
+
+// start the payment of the saved order. the gateway can take long, so it is asked outside of the
+// transaction and its answer is saved in a second short one. wallet is paid at once and the other
+// wait for the callback until the payment window pass
+func (uc PaymentUsecase) startPayment(provider payment.PaymentProviderInterface, order *domain.Order, kontek context.Context) (*domain.Order, error) {
+	paid, err := provider.Initiate(order, kontek)
+	if err != nil {
+		if failed, failErr := uc.failPayment(order.ID, err, kontek); failErr == nil {
+			order = failed
+		}
+		return order, err
+	}
+	saved, err := uc.applyPayment(paid, kontek)
+	if err != nil {
+		return order, err
+	}
+	saved.Payment = paid
+	return saved, nil
+}
+
+// move the waiting order to paid, or fail it and give the ticket back to the stock.
+// the same status sent twice is ignored so the provider can retry the callback, and
+// money that come after the order is expired or cancelled is given back
+func (uc PaymentUsecase) applyPayment(paid *domain.Payment, kontek context.Context) (*domain.Order, error) {
+	// the payment only lock the event, user and promo of its order so it never wait for other purchase
+	order, err := uc.OrderRepo.GetOrderByOrderID(paid.OrderID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	released := false
+	err = uc.UnitOfWork.WithinScope(kontek, orderScope(order), func(kontek context.Context) error {
+		var err error
+		order, err = uc.OrderRepo.GetOrderByOrderID(paid.OrderID, kontek)
+		if err != nil {
+			return err
+		}
+		if order.PaymentMethod != paid.Method {
+			return domain.ErrPaymentNotFound
+		}
+		// the order get its reference from the first answer of the provider, that can be the callback
+		// when it come before the reference from the start of the payment is saved
+		if order.PaymentRef == "" {
+			order.PaymentRef = paid.Reference
+		}
+		if order.PaymentRef != paid.Reference {
+			return domain.ErrPaymentNotFound
+		}
+
+		switch {
+		case paid.Status == domain.PaymentPending:
+		case order.Status == domain.OrderAwaitingPayment && paid.Status == domain.PaymentPaid:
+			if err := fulfilOrder(uc.IssuedTicketRepo, order, kontek); err != nil {
+				return err
+			}
+		case order.Status == domain.OrderAwaitingPayment && paid.Status == domain.PaymentFailed:
+			if err := uc.releaseOrder(order, kontek); err != nil {
+				return err
+			}
+			released = true
+			if err := changeStatus(order, domain.OrderFailed, domain.ErrPaymentFailed.Code); err != nil {
+				return err
+			}
+		case order.Status == domain.OrderFailed && paid.Status == domain.PaymentFailed:
+		case order.WasPaid() && paid.Status == domain.PaymentPaid:
+		case (order.Status == domain.OrderExpired || order.Status == domain.OrderCancelled) && paid.Status == domain.PaymentPaid:
+			if order.RefundAmount.Equal(order.TotalPrice) {
+				break
+			}
+			provider, err := uc.Payments.Get(order.PaymentMethod)
+			if err != nil {
+				return err
+			}
+			if err := provider.Refund(order, order.TotalPrice, kontek); err != nil {
+				return err
+			}
+			order.RefundAmount = order.TotalPrice
+		default:
+			return domain.ErrOrderNotPending
+		}
+		return uc.OrderRepo.UpdateOrder(order, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if released {
+		uc.Waitlist.OfferReturnedStock(ticketIDs(ticketLines(order.EventTicket)), kontek)
+	}
+	return order, nil
+}
+
+// the payment could not be started, nothing is paid so the order fail and its ticket go back to the stock
+func (uc PaymentUsecase) failPayment(orderID int, cause error, kontek context.Context) (*domain.Order, error) {
+	order, err := uc.OrderRepo.GetOrderByOrderID(orderID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	released := false
+	err = uc.UnitOfWork.WithinScope(kontek, orderScope(order), func(kontek context.Context) error {
+		var err error
+		order, err = uc.OrderRepo.GetOrderByOrderID(orderID, kontek)
+		if err != nil {
+			return err
+		}
+		// the reaper or a callback got to the order first
+		if order.Status != domain.OrderAwaitingPayment || order.PaymentRef != "" {
+			return nil
+		}
+		if err := uc.releaseOrder(order, kontek); err != nil {
+			return err
+		}
+		released = true
+		if err := changeStatus(order, domain.OrderFailed, failureReason(cause)); err != nil {
+			return err
+		}
+		return uc.OrderRepo.UpdateOrder(order, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if released {
+		uc.Waitlist.OfferReturnedStock(ticketIDs(ticketLines(order.EventTicket)), kontek)
+	}
+	return order, nil
+}
+
+// give the ticket, the seat and the promo of an order that will never be paid back
+func (uc PaymentUsecase) releaseOrder(order *domain.Order, kontek context.Context) error {
+	if err := uc.EventRepo.IncrementTicketStock(order.Event.ID, ticketLines(order.EventTicket), kontek); err != nil {
+		return err
+	}
+	if err := releaseOrderSeats(uc.SeatRepo, order, nil, kontek); err != nil {
+		return err
+	}
+	return uc.PromoRepo.ReleaseRedemption(order.ID, kontek)
+}
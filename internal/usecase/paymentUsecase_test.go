@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/payment"
+	"testing"
+	"time"
+)
+
+// an order whose event is gone can't be expired, the reaper still return the stock of every other order
+func TestExpireUnpaidOrdersSkipFailedOrder(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			t.Parallel()
+			repos := newTestRepos(t, backend)
+			// the gateway never settle, so the order wait until its payment window pass
+			simulator := payment.NewSimulator([]byte("secret"), repos.Payment)
+			simulator.Script(domain.SimulatorScript{Method: domain.PaymentQRIS, Outcome: domain.SimulateManual})
+			orderUsecase := repos.orderUsecase()
+			orderUsecase.Payments = payment.Providers{domain.PaymentQRIS: simulator}
+			orderUsecase.PaymentWindow = time.Millisecond
+			paymentUsecase := orderUsecase.payments()
+			kontek := systemContext()
+
+			gone := newTestEvent(t, repos, "Gone", domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10})
+			kept := newTestEvent(t, repos, "Kept", domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10})
+			user := newTestUser(t, repos, "buyer", 0)
+			var orders []*domain.Order
+			for _, event := range []*domain.Event{gone, kept} {
+				order, err := orderUsecase.CreateOrder(domain.OrderRequest{UserID: user.ID, EventID: event.ID, PaymentMethod: domain.PaymentQRIS, Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: 4}}}, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if order.Status != domain.OrderAwaitingPayment {
+					t.Fatalf("order is %s, want AWAITING_PAYMENT", order.Status)
+				}
+				orders = append(orders, order)
+			}
+			if err := repos.Event.DeleteEvent(gone.ID, kontek); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			expired, err := paymentUsecase.ExpireUnpaidOrders(kontek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expired != 1 {
+				t.Errorf("expired %d order, want 1", expired)
+			}
+			order, err := repos.Order.GetOrderByOrderID(orders[1].ID, kontek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if order.Status != domain.OrderExpired {
+				t.Errorf("order is %s, want EXPIRED", order.Status)
+			}
+			stock, err := repos.Event.GetEventByID(kept.ID, kontek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stock.Ticket[0].Quantity != 10 {
+				t.Errorf("stock is %d after the reaper, want 10", stock.Ticket[0].Quantity)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+	"strings"
+)
+
+// make a connection to repo
+type PricingUsecase struct {
+	PricingRepo repository.PricingRepoInterface
+}
+
+func NewPricingUsecase(pricingRepo repository.PricingRepoInterface) PricingUsecaseInterface {
+	return PricingUsecase{
+		PricingRepo: pricingRepo,
+	}
+}
+
+type PricingUsecaseInterface interface {
+	GetAllPricings
+	SetPricing
+}
+type GetAllPricings interface {
+	GetAllPricings(kontek context.Context) ([]domain.Pricing, error)
+}
+type SetPricing interface {
+	SetPricing(pricing domain.Pricing, kontek context.Context) (*domain.Pricing, error)
+}
+
+func (uc PricingUsecase) GetAllPricings(kontek context.Context) ([]domain.Pricing, error) {
+	return uc.PricingRepo.GetAllPricings(kontek)
+}
+
+// only admin set the fee and the tax, the new pricing is used by the next order
+func (uc PricingUsecase) SetPricing(pricing domain.Pricing, kontek context.Context) (*domain.Pricing, error) {
+	if !isAdmin(kontek) {
+		return nil, domain.ErrRoleNotAllowed
+	}
+	pricing.Currency = strings.ToUpper(pricing.Currency)
+	if !pricing.Valid() {
+		return nil, domain.ErrInvalidPricing
+	}
+	pricing.TicketFee.Currency = pricing.Currency
+	pricing.OrderFee.Currency = pricing.Currency
+	if pricing.Rounding == "" {
+		pricing.Rounding = domain.RoundHalfUp
+	}
+	if err := uc.PricingRepo.SetPricing(&pricing, kontek); err != nil {
+		return nil, err
+	}
+	return &pricing, nil
+}
+
+// add the platform fee and the tax of the order currency to its total, the total must already have the discount.
+// a currency without pricing has no fee and no tax
+func chargeOrder(pricingRepo repository.PricingRepoInterface, order *domain.Order, kontek context.Context) error {
+	currency := order.Subtotal.Currency
+	if currency == "" {
+		currency = domain.DefaultCurrency
+	}
+	pricing, err := pricingRepo.GetPricing(currency, kontek)
+	if errors.Is(err, domain.ErrPricingNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	quantity := 0
+	for _, ticket := range order.EventTicket {
+		quantity += ticket.Quantity
+	}
+	fee, tax, err := pricing.Charge(quantity, order.TotalPrice)
+	if err != nil {
+		return err
+	}
+	if order.TotalPrice, err = order.TotalPrice.Add(fee); err != nil {
+		return err
+	}
+	if !pricing.TaxIncluded {
+		if order.TotalPrice, err = order.TotalPrice.Add(tax); err != nil {
+			return err
+		}
+	}
+	order.Fee = fee
+	order.Tax = tax
+	order.TaxRate = pricing.TaxRate
+	order.TaxIncluded = pricing.TaxIncluded
+	return nil
+}
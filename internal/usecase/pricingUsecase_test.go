@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"pemesananTiketOnlineGo/internal/domain"
+	"testing"
+)
+
+// the fee and the tax of the pricing are added to the order and the buyer pay the total
+func TestCreateOrderPricing(t *testing.T) {
+	fee := domain.Pricing{Currency: domain.DefaultCurrency, TicketFee: domain.NewMoney(1000, domain.DefaultCurrency), OrderFee: domain.NewMoney(500, domain.DefaultCurrency)}
+	cases := []struct {
+		name    string
+		pricing *domain.Pricing
+		// the order is 2 ticket of 25000, every amount is in minor unit
+		fee, tax, total int64
+	}{
+		{name: "no pricing", total: 50000},
+		{name: "fee only", pricing: &fee, fee: 2500, total: 52500},
+		{name: "tax on the ticket", pricing: withTax(fee, func(p *domain.Pricing) {}), fee: 2500, tax: 5500, total: 58000},
+		{name: "tax on the fee", pricing: withTax(fee, func(p *domain.Pricing) { p.TaxOnFee = true }), fee: 2500, tax: 5775, total: 58275},
+		{name: "tax included", pricing: withTax(domain.Pricing{Currency: domain.DefaultCurrency}, func(p *domain.Pricing) { p.TaxIncluded = true }), tax: 4955, total: 50000},
+		{name: "round half up", pricing: withTax(fee, func(p *domain.Pricing) { p.TaxOnFee, p.RoundTo = true, 100 }), fee: 2500, tax: 5800, total: 58300},
+		{name: "round down", pricing: withTax(fee, func(p *domain.Pricing) { p.TaxOnFee, p.RoundTo, p.Rounding = true, 100, domain.RoundDown }), fee: 2500, tax: 5700, total: 58200},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				orderUsecase := repos.orderUsecase()
+				kontek := systemContext()
+
+				if tc.pricing != nil {
+					if _, err := NewPricingUsecase(repos.Pricing).SetPricing(*tc.pricing, kontek); err != nil {
+						t.Fatal(err)
+					}
+				}
+				event := newTestEvent(t, repos, "Pricing",
+					domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10})
+				user := newTestUser(t, repos, "buyer", 100000)
+
+				order, err := orderUsecase.CreateOrder(domain.OrderRequest{UserID: user.ID, EventID: event.ID, Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: 2}}}, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if order.Fee.Amount != tc.fee || order.Tax.Amount != tc.tax || order.TotalPrice.Amount != tc.total {
+					t.Errorf("fee %s tax %s total %s, want %d %d %d", order.Fee, order.Tax, order.TotalPrice, tc.fee, tc.tax, tc.total)
+				}
+
+				// the wallet is debited the total, fee and tax included
+				balance, err := repos.Wallet.GetBalance(user.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if balance.Amount+tc.total != 100000 {
+					t.Errorf("balance %s after paying %d from 100000", balance, tc.total)
+				}
+			})
+		}
+	}
+}
+
+// the pricing with 11% tax, changed by set
+func withTax(pricing domain.Pricing, set func(p *domain.Pricing)) *domain.Pricing {
+	pricing.TaxRate = 1100
+	set(&pricing)
+	return &pricing
+}
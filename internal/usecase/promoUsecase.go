@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+	"slices"
+	"strings"
+	"time"
+)
+
+// make a connection to repo
+type PromoUsecase struct {
+	PromoRepo repository.PromoRepoInterface
+	EventRepo repository.EventRepoInterface
+}
+
+func NewPromoUsecase(promoRepo repository.PromoRepoInterface, eventRepo repository.EventRepoInterface) PromoUsecaseInterface {
+	return PromoUsecase{
+		PromoRepo: promoRepo,
+		EventRepo: eventRepo,
+	}
+}
+
+type PromoUsecaseInterface interface {
+	CreatePromo
+	GetPromoByCode
+	GetAllPromos
+	UpdatePromo
+}
+type CreatePromo interface {
+	CreatePromo(promo domain.Promo, kontek context.Context) (*domain.Promo, error)
+}
+type GetPromoByCode interface {
+	GetPromoByCode(code string, kontek context.Context) (*domain.Promo, error)
+}
+type GetAllPromos interface {
+	GetAllPromos(kontek context.Context) ([]domain.Promo, error)
+}
+type UpdatePromo interface {
+	UpdatePromo(promo domain.Promo, kontek context.Context) (*domain.Promo, error)
+}
+
+// an admin can make a promo for every event, an organizer only for an event they own
+func (uc PromoUsecase) CreatePromo(promo domain.Promo, kontek context.Context) (*domain.Promo, error) {
+	promo.Code = promoCode(promo.Code)
+	if err := uc.checkPromo(&promo, kontek); err != nil {
+		return nil, err
+	}
+	if caller, ok := domain.CurrentUser(kontek); ok {
+		promo.CreatedBy = caller.ID
+	}
+	return uc.PromoRepo.CreatePromo(&promo, kontek)
+}
+
+func (uc PromoUsecase) GetPromoByCode(code string, kontek context.Context) (*domain.Promo, error) {
+	promo, err := uc.PromoRepo.GetPromoByCode(promoCode(code), kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !uc.canManagePromo(promo, kontek) {
+		return nil, domain.ErrPromoNotFound
+	}
+	return promo, nil
+}
+
+// an organizer only see the promo of the event they own
+func (uc PromoUsecase) GetAllPromos(kontek context.Context) ([]domain.Promo, error) {
+	promos, err := uc.PromoRepo.GetAllPromos(kontek)
+	if err != nil {
+		return nil, err
+	}
+	visible := []domain.Promo{}
+	for _, promo := range promos {
+		if uc.canManagePromo(&promo, kontek) {
+			visible = append(visible, promo)
+		}
+	}
+	return visible, nil
+}
+
+// change the discount, scope, cap or window of a promo, the code can't be changed
+func (uc PromoUsecase) UpdatePromo(promo domain.Promo, kontek context.Context) (*domain.Promo, error) {
+	old, err := uc.PromoRepo.GetPromoByCode(promoCode(promo.Code), kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !uc.canManagePromo(old, kontek) {
+		return nil, domain.ErrPromoNotFound
+	}
+	promo.ID = old.ID
+	if err := uc.checkPromo(&promo, kontek); err != nil {
+		return nil, err
+	}
+	if err := uc.PromoRepo.UpdatePromo(&promo, kontek); err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+// the promo is valid, the caller can manage its event and every ticket type in its scope belong to the event
+func (uc PromoUsecase) checkPromo(promo *domain.Promo, kontek context.Context) error {
+	slices.Sort(promo.TicketIDs)
+	promo.TicketIDs = slices.Compact(promo.TicketIDs)
+	if !promo.Valid() {
+		return domain.ErrInvalidPromo
+	}
+
+	if promo.EventID == 0 {
+		// a promo for every event is only made by an admin
+		if !isAdmin(kontek) {
+			return domain.ErrNotYourEvent
+		}
+		return nil
+	}
+	event, err := uc.EventRepo.GetEventByID(promo.EventID, kontek)
+	if err != nil {
+		return err
+	}
+	if !canManageEvent(event, kontek) {
+		return domain.ErrNotYourEvent
+	}
+	for _, id := range promo.TicketIDs {
+		if !slices.ContainsFunc(event.Ticket, func(ticket domain.Ticket) bool { return ticket.ID == id }) {
+			return domain.ErrTicketNotFound
+		}
+	}
+	return nil
+}
+
+// admin can manage every promo, organizer only the promo of the event they own
+func (uc PromoUsecase) canManagePromo(promo *domain.Promo, kontek context.Context) bool {
+	if isAdmin(kontek) {
+		return true
+	}
+	if promo.EventID == 0 {
+		return false
+	}
+	event, err := uc.EventRepo.GetEventByID(promo.EventID, kontek)
+	return err == nil && canManageEvent(event, kontek)
+}
+
+// the code is kept in upper case so the buyer can type it in any case
+func promoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// check the promo can be used by the order and take its discount off the total.
+// the order must have its subtotal, the redemption is saved by redeemPromo once the order has an ID
+func applyPromo(promoRepo repository.PromoRepoInterface, order *domain.Order, code string, kontek context.Context) (*domain.Promo, error) {
+	promo, err := promoRepo.GetPromoByCode(promoCode(code), kontek)
+	if err != nil {
+		return nil, err
+	}
+	if err := promo.CheckActive(time.Now()); err != nil {
+		return nil, err
+	}
+	discount, err := promo.Discount(order.Event.ID, order.EventTicket)
+	if err != nil {
+		return nil, err
+	}
+	if promo.MaxPerUser > 0 {
+		used, err := promoRepo.CountRedemptions(promo.ID, order.User.ID, kontek)
+		if err != nil {
+			return nil, err
+		}
+		if used >= promo.MaxPerUser {
+			return nil, domain.ErrPromoUserLimit
+		}
+	}
+
+	if order.TotalPrice, err = order.Subtotal.Sub(discount); err != nil {
+		return nil, err
+	}
+	order.Discount = discount
+	order.PromoCode = promo.Code
+	return promo, nil
+}
+
+// record the use of the promo by the saved order
+func redeemPromo(promoRepo repository.PromoRepoInterface, promo *domain.Promo, order *domain.Order, kontek context.Context) error {
+	return promoRepo.CreateRedemption(&domain.PromoRedemption{
+		PromoID:  promo.ID,
+		UserID:   order.User.ID,
+		OrderID:  order.ID,
+		Discount: order.Discount,
+	}, kontek)
+}
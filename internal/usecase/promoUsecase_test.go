@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+	"sync"
+	"testing"
+)
+
+// the discount of a promo only count the ticket in its scope and never go over their price
+func TestCreateOrderPromoDiscount(t *testing.T) {
+	cases := []struct {
+		name  string
+		promo domain.Promo
+		// the index of the ticket type in scope, nil is every type of the event
+		scope    []int
+		discount int64
+		code     string
+	}{
+		{name: "percent", promo: domain.Promo{Kind: domain.PromoPercent, Percent: 10}, discount: 10000},
+		{name: "fixed", promo: domain.Promo{Kind: domain.PromoFixed, Amount: domain.NewMoney(7500, domain.DefaultCurrency)}, discount: 7500},
+		{name: "fixed over the price", promo: domain.Promo{Kind: domain.PromoFixed, Amount: domain.NewMoney(500000, domain.DefaultCurrency)}, discount: 100000},
+		{name: "percent of the scope", promo: domain.Promo{Kind: domain.PromoPercent, Percent: 50}, scope: []int{1}, discount: 25000},
+		{name: "nothing in scope", promo: domain.Promo{Kind: domain.PromoPercent, Percent: 50}, scope: []int{2}, code: domain.ErrPromoNotForOrder.Code},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				orderUsecase := repos.orderUsecase()
+				kontek := systemContext()
+
+				event := newTestEvent(t, repos, "Promo",
+					domain.Ticket{Type: "VIP", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10},
+					domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10},
+					domain.Ticket{Type: "LATE", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 10})
+				user := newTestUser(t, repos, "buyer", 100000000)
+				promo := tc.promo
+				promo.Code = "TEST"
+				promo.EventID = event.ID
+				for _, i := range tc.scope {
+					promo.TicketIDs = append(promo.TicketIDs, event.Ticket[i].ID)
+				}
+				if _, err := NewPromoUsecase(repos.Promo, repos.Event).CreatePromo(promo, kontek); err != nil {
+					t.Fatal(err)
+				}
+
+				order, err := orderUsecase.CreateOrder(domain.OrderRequest{UserID: user.ID, EventID: event.ID, PromoCode: "test",
+					Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: 2}, {ID: event.Ticket[1].ID, Quantity: 2}}}, kontek)
+				if errorCode(err) != tc.code {
+					t.Fatalf("order failed with %q, want %q", errorCode(err), tc.code)
+				}
+				if err != nil {
+					return
+				}
+				if order.Discount.Amount != tc.discount {
+					t.Errorf("discount %s, want %d", order.Discount, tc.discount)
+				}
+				if total, _ := order.Subtotal.Sub(order.Discount); order.TotalPrice.Amount < total.Amount {
+					t.Errorf("total %s is below the subtotal %s less the discount %s", order.TotalPrice, order.Subtotal, order.Discount)
+				}
+			})
+		}
+	}
+}
+
+// many buyer use a promo at the same time, it is never used more than its cap
+func TestCreateOrderPromoCap(t *testing.T) {
+	cases := []struct {
+		name       string
+		orders     int
+		users      int
+		maxUses    int
+		maxPerUser int
+		// the redemption that must be left in the end
+		used int
+	}{
+		{name: "total cap", orders: 60, users: 60, maxUses: 20, used: 20},
+		{name: "user cap", orders: 60, users: 10, maxPerUser: 2, used: 20},
+		{name: "both cap", orders: 60, users: 10, maxUses: 15, maxPerUser: 2, used: 15},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				orderUsecase := repos.orderUsecase()
+				kontek := systemContext()
+
+				event := newTestEvent(t, repos, "Promo",
+					domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 500})
+				var users []int
+				for i := 1; i <= tc.users; i++ {
+					users = append(users, newTestUser(t, repos, fmt.Sprintf("user%d", i), 100000000).ID)
+				}
+				if _, err := repos.Promo.CreatePromo(&domain.Promo{Code: "RUSH", Kind: domain.PromoPercent, Percent: 10, EventID: event.ID, MaxUses: tc.maxUses, MaxPerUser: tc.maxPerUser}, kontek); err != nil {
+					t.Fatal(err)
+				}
+
+				var wg sync.WaitGroup
+				for i := 0; i < tc.orders; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						orderUsecase.CreateOrder(domain.OrderRequest{UserID: users[i%len(users)], EventID: event.ID, PromoCode: "RUSH",
+							Ticket: []domain.TicketLine{{ID: event.Ticket[0].ID, Quantity: 1}}}, kontek)
+					}(i)
+				}
+				wg.Wait()
+
+				orders, err := repos.Order.GetAllOrders(kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				discounted := 0
+				perUser := map[int]int{}
+				for _, order := range orders {
+					if order.Status == domain.OrderFulfilled && order.PromoCode != "" {
+						discounted++
+						perUser[order.User.ID]++
+					}
+				}
+				if discounted != tc.used {
+					t.Errorf("promo used %d times, want %d", discounted, tc.used)
+				}
+				for userID, used := range perUser {
+					if tc.maxPerUser > 0 && used > tc.maxPerUser {
+						t.Errorf("user %d used the promo %d times, the cap is %d", userID, used, tc.maxPerUser)
+					}
+				}
+				promo, err := repos.Promo.GetPromoByCode("RUSH", kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if promo.Used != discounted {
+					t.Errorf("promo count %d use, the order %d", promo.Used, discounted)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// make a connection to repo, the queue get its own unit of work so a user joining the queue
+// never wait for a purchase to finish
+type QueueUsecase struct {
+	QueueRepo  repository.QueueRepoInterface
+	EventRepo  repository.EventRepoInterface
+	UnitOfWork repository.UnitOfWorkInterface
+}
+
+func NewQueueUsecase(queueRepo repository.QueueRepoInterface, eventRepo repository.EventRepoInterface, unitOfWork repository.UnitOfWorkInterface) QueueUsecaseInterface {
+	return QueueUsecase{
+		QueueRepo:  queueRepo,
+		EventRepo:  eventRepo,
+		UnitOfWork: unitOfWork,
+	}
+}
+
+type QueueUsecaseInterface interface {
+	SetWaitingRoom
+	GetWaitingRoom
+	JoinQueue
+	GetQueueStatus
+	AdmitQueue
+	RunQueueAdmission
+}
+type SetWaitingRoom interface {
+	SetWaitingRoom(roomReq domain.WaitingRoomRequest, kontek context.Context) (*domain.WaitingRoom, error)
+}
+type GetWaitingRoom interface {
+	GetWaitingRoom(eventID int, kontek context.Context) (*domain.WaitingRoom, error)
+}
+type JoinQueue interface {
+	JoinQueue(eventID int, userID int, kontek context.Context) (*domain.QueueEntry, error)
+}
+type GetQueueStatus interface {
+	GetQueueStatus(token string, kontek context.Context) (*domain.QueueEntry, error)
+}
+type AdmitQueue interface {
+	AdmitQueue(kontek context.Context) (int, error)
+}
+type RunQueueAdmission interface {
+	RunQueueAdmission(kontek context.Context, interval time.Duration)
+}
+
+// open, tune or close the waiting room of an event. when it is closed the user still waiting are let in,
+// the sale doesn't need an admission anymore
+func (uc QueueUsecase) SetWaitingRoom(roomReq domain.WaitingRoomRequest, kontek context.Context) (*domain.WaitingRoom, error) {
+	if roomReq.Active && (roomReq.AdmitPerMinute == 0 || roomReq.AdmitWindowSeconds == 0) {
+		return nil, domain.ErrInvalidRoom
+	}
+	event, err := uc.EventRepo.GetEventByID(roomReq.EventID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageEvent(event, kontek) {
+		return nil, domain.ErrNotYourEvent
+	}
+
+	now := time.Now()
+	err = uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		old, err := uc.QueueRepo.GetWaitingRoom(event.ID, kontek)
+		if err != nil && !errors.Is(err, domain.ErrRoomNotFound) {
+			return err
+		}
+
+		room := domain.WaitingRoom{
+			EventID:            event.ID,
+			Active:             roomReq.Active,
+			AdmitPerMinute:     roomReq.AdmitPerMinute,
+			AdmitWindowSeconds: roomReq.AdmitWindowSeconds,
+			LastAdmitAt:        now,
+		}
+		if old != nil {
+			// closing keep the setting for the next opening
+			if !room.Active && room.AdmitPerMinute == 0 {
+				room.AdmitPerMinute = old.AdmitPerMinute
+			}
+			if !room.Active && room.AdmitWindowSeconds == 0 {
+				room.AdmitWindowSeconds = old.AdmitWindowSeconds
+			}
+			// the rate keep counting while the room stay open
+			if old.Active && room.Active {
+				room.LastAdmitAt = old.LastAdmitAt
+			}
+		}
+		if err := uc.QueueRepo.SaveWaitingRoom(&room, kontek); err != nil {
+			return err
+		}
+		if room.Active {
+			return nil
+		}
+
+		waiting, err := uc.QueueRepo.GetQueueByStatus(event.ID, domain.QueueWaiting, 0, kontek)
+		if err != nil {
+			return err
+		}
+		for _, entry := range waiting {
+			if err := uc.admit(&entry, room, now, kontek); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.GetWaitingRoom(event.ID, kontek)
+}
+
+// the setting of the room with how many user wait and how many can buy now
+func (uc QueueUsecase) GetWaitingRoom(eventID int, kontek context.Context) (*domain.WaitingRoom, error) {
+	room, err := uc.QueueRepo.GetWaitingRoom(eventID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	room.Waiting, err = uc.QueueRepo.CountQueue(eventID, domain.QueueWaiting, 0, kontek)
+	if err != nil {
+		return nil, err
+	}
+	room.Admitted, err = uc.QueueRepo.CountQueue(eventID, domain.QueueAdmitted, 0, kontek)
+	if err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// take a place at the back of the queue. a user that is still waiting or admitted get their place back,
+// so joining again after a refresh doesn't lose it
+func (uc QueueUsecase) JoinQueue(eventID int, userID int, kontek context.Context) (*domain.QueueEntry, error) {
+	room, err := uc.QueueRepo.GetWaitingRoom(eventID, kontek)
+	if errors.Is(err, domain.ErrRoomNotFound) || (err == nil && !room.Active) {
+		return nil, domain.ErrRoomNotActive
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *domain.QueueEntry
+	err = uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		now := time.Now()
+		old, err := uc.QueueRepo.GetQueueEntryByUser(eventID, userID, kontek)
+		if err != nil && !errors.Is(err, domain.ErrQueueNotFound) {
+			return err
+		}
+		if old != nil && (old.Status == domain.QueueWaiting || old.IsAdmitted(now)) {
+			entry = old
+			return nil
+		}
+
+		token, err := newQueueToken()
+		if err != nil {
+			return err
+		}
+		entry = &domain.QueueEntry{
+			EventID:  eventID,
+			UserID:   userID,
+			Token:    token,
+			Status:   domain.QueueWaiting,
+			JoinedAt: now,
+		}
+		_, err = uc.QueueRepo.CreateQueueEntry(entry, kontek)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.withPosition(entry, kontek)
+}
+
+// the place in the queue of the token, the token is the secret of the place so it is enough to see it
+func (uc QueueUsecase) GetQueueStatus(token string, kontek context.Context) (*domain.QueueEntry, error) {
+	entry, err := uc.QueueRepo.GetQueueEntryByToken(token, kontek)
+	if err != nil {
+		return nil, err
+	}
+	// the worker may not have expired it yet
+	if entry.Status == domain.QueueAdmitted && !entry.IsAdmitted(time.Now()) {
+		entry.Status = domain.QueueExpired
+	}
+	return uc.withPosition(entry, kontek)
+}
+
+// expire the admission that ran out and let the next user in every active room, as many as its rate
+// allow since the last time. a room that had nobody to let in doesn't save up its rate for later
+func (uc QueueUsecase) AdmitQueue(kontek context.Context) (int, error) {
+	rooms, err := uc.QueueRepo.GetActiveWaitingRooms(kontek)
+	if err != nil {
+		return 0, err
+	}
+
+	admitted := 0
+	for _, room := range rooms {
+		err := uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+			count, err := uc.admitRoom(room.EventID, kontek)
+			admitted += count
+			return err
+		})
+		if err != nil {
+			return admitted, err
+		}
+	}
+	return admitted, nil
+}
+
+// run the admission every interval until kontek is done
+func (uc QueueUsecase) RunQueueAdmission(kontek context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-kontek.Done():
+			return
+		case <-ticker.C:
+			admitted, err := uc.AdmitQueue(kontek)
+			if err != nil {
+				log.Error().Err(err).Msg("Queue Admission Failed")
+				continue
+			}
+			if admitted > 0 {
+				log.Info().Int("admitted", admitted).Msg("Queue Admission Success")
+			}
+		}
+	}
+}
+
+func (uc QueueUsecase) admitRoom(eventID int, kontek context.Context) (int, error) {
+	// read again inside the transaction, the room may be closed or tuned since
+	room, err := uc.QueueRepo.GetWaitingRoom(eventID, kontek)
+	if err != nil {
+		return 0, err
+	}
+	if !room.Active {
+		return 0, nil
+	}
+
+	now := time.Now()
+	admitted, err := uc.QueueRepo.GetQueueByStatus(eventID, domain.QueueAdmitted, 0, kontek)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range admitted {
+		if entry.IsAdmitted(now) {
+			continue
+		}
+		entry.Status = domain.QueueExpired
+		if err := uc.QueueRepo.UpdateQueueEntry(&entry, kontek); err != nil {
+			return 0, err
+		}
+	}
+
+	allowed := int(int64(room.AdmitPerMinute) * int64(now.Sub(room.LastAdmitAt)) / int64(time.Minute))
+	if allowed <= 0 {
+		return 0, nil
+	}
+	waiting, err := uc.QueueRepo.GetQueueByStatus(eventID, domain.QueueWaiting, allowed, kontek)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range waiting {
+		if err := uc.admit(&entry, *room, now, kontek); err != nil {
+			return 0, err
+		}
+	}
+
+	// the part of a user that isn't let in yet is kept for the next round
+	room.LastAdmitAt = room.LastAdmitAt.Add(time.Duration(allowed) * time.Minute / time.Duration(room.AdmitPerMinute))
+	if len(waiting) < allowed {
+		room.LastAdmitAt = now
+	}
+	if err := uc.QueueRepo.SaveWaitingRoom(room, kontek); err != nil {
+		return 0, err
+	}
+	return len(waiting), nil
+}
+
+// let the user buy until the admission window of the room pass
+func (uc QueueUsecase) admit(entry *domain.QueueEntry, room domain.WaitingRoom, now time.Time, kontek context.Context) error {
+	expiresAt := now.Add(time.Duration(room.AdmitWindowSeconds) * time.Second)
+	entry.Status = domain.QueueAdmitted
+	entry.AdmittedAt = &now
+	entry.ExpiresAt = &expiresAt
+	return uc.QueueRepo.UpdateQueueEntry(entry, kontek)
+}
+
+// fill the place in the line of a waiting entry
+func (uc QueueUsecase) withPosition(entry *domain.QueueEntry, kontek context.Context) (*domain.QueueEntry, error) {
+	if entry.Status != domain.QueueWaiting {
+		return entry, nil
+	}
+	ahead, err := uc.QueueRepo.CountQueue(entry.EventID, domain.QueueWaiting, entry.ID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	entry.Position = ahead + 1
+	return entry, nil
+}
+
+// random secret of a place in the queue
+func newQueueToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// while the event has an active waiting room only a user admitted by the queue can buy. it is checked
+// before the purchase transaction, so the user still waiting never reach the stock lock
+func checkQueue(queueRepo repository.QueueRepoInterface, eventID int, userID int, token string, kontek context.Context) error {
+	room, err := queueRepo.GetWaitingRoom(eventID, kontek)
+	if errors.Is(err, domain.ErrRoomNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !room.Active {
+		return nil
+	}
+	if token == "" {
+		return domain.ErrQueueRequired
+	}
+
+	entry, err := queueRepo.GetQueueEntryByToken(token, kontek)
+	if errors.Is(err, domain.ErrQueueNotFound) {
+		return domain.ErrNotYourQueue
+	}
+	if err != nil {
+		return err
+	}
+	if entry.EventID != eventID || entry.UserID != userID {
+		return domain.ErrNotYourQueue
+	}
+	if entry.Status == domain.QueueWaiting {
+		return domain.ErrNotAdmitted
+	}
+	if !entry.IsAdmitted(time.Now()) {
+		return domain.ErrAdmissionExpired
+	}
+	return nil
+}
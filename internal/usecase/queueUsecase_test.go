@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+	"sync"
+	"testing"
+	"time"
+)
+
+// every user join the waiting room twice at the same time while the room let them in,
+// each user keep one place, the line has no gap and the room keep its rate
+func TestJoinQueueParallel(t *testing.T) {
+	cases := []struct {
+		name           string
+		users          int
+		admitPerMinute int
+	}{
+		{name: "slow room", users: 40, admitPerMinute: 600},
+		{name: "fast room", users: 40, admitPerMinute: 6000},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				queueUsecase := NewQueueUsecase(repos.Queue, repos.Event, repos.UnitOfWork)
+				kontek := systemContext()
+
+				event := newTestEvent(t, repos, "Rush",
+					domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 100})
+				var users []int
+				for i := 1; i <= tc.users; i++ {
+					users = append(users, newTestUser(t, repos, fmt.Sprintf("user%d", i), 0).ID)
+				}
+				room := domain.WaitingRoomRequest{EventID: event.ID, Active: true, AdmitPerMinute: tc.admitPerMinute, AdmitWindowSeconds: 60}
+				if _, err := queueUsecase.SetWaitingRoom(room, kontek); err != nil {
+					t.Fatal(err)
+				}
+				opened := time.Now()
+
+				tokens := make([][2]string, len(users))
+				var joined sync.WaitGroup
+				for i, userID := range users {
+					for k := 0; k < 2; k++ {
+						joined.Add(1)
+						go func(i int, k int, userID int) {
+							defer joined.Done()
+							entry, err := queueUsecase.JoinQueue(event.ID, userID, kontek)
+							if err != nil {
+								t.Errorf("user %d failed to join the queue %v", userID, err)
+								return
+							}
+							tokens[i][k] = entry.Token
+						}(i, k, userID)
+					}
+				}
+				done := make(chan struct{})
+				go func() {
+					joined.Wait()
+					close(done)
+				}()
+				// keep letting user in for a while after everyone joined, so the rate is measured
+				for joining := true; joining || time.Since(opened) < 300*time.Millisecond; {
+					select {
+					case <-done:
+						joining, done = false, nil
+					case <-time.After(10 * time.Millisecond):
+					}
+					if _, err := queueUsecase.AdmitQueue(kontek); err != nil {
+						t.Fatal(err)
+					}
+				}
+				elapsed := time.Since(opened)
+
+				admitted := 0
+				positions := map[int]bool{}
+				for i, pair := range tokens {
+					if pair[0] == "" || pair[0] != pair[1] {
+						t.Errorf("user %d got two places in the queue %v", users[i], pair)
+						continue
+					}
+					entry, err := queueUsecase.GetQueueStatus(pair[0], kontek)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if entry.Status == domain.QueueAdmitted {
+						admitted++
+						continue
+					}
+					positions[entry.Position] = true
+				}
+				for position := 1; position <= len(users)-admitted; position++ {
+					if !positions[position] {
+						t.Errorf("nobody is at position %d of the queue", position)
+					}
+				}
+				if admitted == 0 {
+					t.Error("the queue let nobody in")
+				}
+				if limit := int(int64(room.AdmitPerMinute)*int64(elapsed)/int64(time.Minute)) + 1; admitted > limit {
+					t.Errorf("queue let %d user in within %s, the rate allow %d", admitted, elapsed, limit)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+	"slices"
+)
+
+// make a connection to repo
+type SeatUsecase struct {
+	SeatRepo   repository.SeatRepoInterface
+	EventRepo  repository.EventRepoInterface
+	OrderRepo  repository.OrderRepoInterface
+	UnitOfWork repository.UnitOfWorkInterface
+}
+
+func NewSeatUsecase(seatRepo repository.SeatRepoInterface, eventRepo repository.EventRepoInterface, orderRepo repository.OrderRepoInterface, unitOfWork repository.UnitOfWorkInterface) SeatUsecaseInterface {
+	return SeatUsecase{
+		SeatRepo:   seatRepo,
+		EventRepo:  eventRepo,
+		OrderRepo:  orderRepo,
+		UnitOfWork: unitOfWork,
+	}
+}
+
+type SeatUsecaseInterface interface {
+	CreateSeatMap
+	GetSeatMap
+}
+type CreateSeatMap interface {
+	CreateSeatMap(seatMap domain.SeatMapRequest, kontek context.Context) (*domain.SeatMap, error)
+}
+type GetSeatMap interface {
+	GetSeatMap(eventID int, kontek context.Context) (*domain.SeatMap, error)
+}
+
+// add sections, rows and seats to an event. the ticket type of a section become seated,
+// its stock is the number of its available seats from now on
+func (uc SeatUsecase) CreateSeatMap(seatMap domain.SeatMapRequest, kontek context.Context) (*domain.SeatMap, error) {
+	event, err := uc.EventRepo.GetEventByID(seatMap.EventID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageEvent(event, kontek) {
+		return nil, domain.ErrNotYourEvent
+	}
+
+	err = uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		old, err := uc.SeatRepo.GetSeatsByEventID(event.ID, kontek)
+		if err != nil {
+			return err
+		}
+		// a section is sold as one ticket type, also when it get more row later
+		sectionTicket := map[string]int{}
+		for _, seat := range old {
+			sectionTicket[seat.Section] = seat.TicketID
+		}
+
+		var seats []domain.Seat
+		added := map[int]int{}
+		for _, section := range seatMap.Sections {
+			if !slices.ContainsFunc(event.Ticket, func(ticket domain.Ticket) bool { return ticket.ID == section.TicketID }) {
+				return domain.ErrTicketNotFound
+			}
+			if ticketID, exist := sectionTicket[section.Name]; exist && ticketID != section.TicketID {
+				return domain.ErrSectionTicket
+			}
+			sectionTicket[section.Name] = section.TicketID
+			for _, row := range section.Rows {
+				for number := 1; number <= row.Seats; number++ {
+					seats = append(seats, domain.Seat{EventID: event.ID, TicketID: section.TicketID, Section: section.Name, Row: row.Name, Number: number})
+				}
+				added[section.TicketID] += row.Seats
+			}
+		}
+		// a ticket that is already sold has no seat, so the type can only get seats before its first sale
+		for _, ticket := range event.Ticket {
+			if added[ticket.ID] == 0 || ticket.Seated {
+				continue
+			}
+			sold, err := uc.soldWithoutSeat(event.ID, ticket, kontek)
+			if err != nil {
+				return err
+			}
+			if sold {
+				return domain.ErrUnseatedSold
+			}
+		}
+		if _, err := uc.SeatRepo.CreateSeats(seats, kontek); err != nil {
+			return err
+		}
+
+		for _, ticket := range event.Ticket {
+			if added[ticket.ID] == 0 {
+				continue
+			}
+			if ticket.Seated {
+				if err := uc.EventRepo.IncrementTicketStock(event.ID, []domain.TicketLine{{ID: ticket.ID, Quantity: added[ticket.ID]}}, kontek); err != nil {
+					return err
+				}
+				continue
+			}
+			ticket.Seated = true
+			ticket.Quantity = added[ticket.ID]
+			if err := uc.EventRepo.UpdateTicket(&ticket, kontek); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uc.GetSeatMap(event.ID, kontek)
+}
+
+// every seat of the event with its status, by section and row in the order they were made
+func (uc SeatUsecase) GetSeatMap(eventID int, kontek context.Context) (*domain.SeatMap, error) {
+	if _, err := uc.EventRepo.GetEventByID(eventID, kontek); err != nil {
+		return nil, err
+	}
+	seats, err := uc.SeatRepo.GetSeatsByEventID(eventID, kontek)
+	if err != nil {
+		return nil, err
+	}
+
+	seatMap := domain.SeatMap{EventID: eventID, Sections: []domain.SeatSection{}}
+	for _, seat := range seats {
+		i := slices.IndexFunc(seatMap.Sections, func(section domain.SeatSection) bool { return section.Name == seat.Section })
+		if i < 0 {
+			seatMap.Sections = append(seatMap.Sections, domain.SeatSection{Name: seat.Section, TicketID: seat.TicketID})
+			i = len(seatMap.Sections) - 1
+		}
+		section := &seatMap.Sections[i]
+		j := slices.IndexFunc(section.Rows, func(row domain.SeatRow) bool { return row.Name == seat.Row })
+		if j < 0 {
+			section.Rows = append(section.Rows, domain.SeatRow{Name: seat.Row})
+			j = len(section.Rows) - 1
+		}
+		if seat.Status == domain.SeatAvailable {
+			section.Available++
+			seatMap.Available++
+		}
+		// the section and the row are already told by the map
+		section.Rows[j].Seats = append(section.Rows[j].Seats, domain.Seat{ID: seat.ID, Number: seat.Number, Status: seat.Status})
+	}
+	return &seatMap, nil
+}
+
+// the ticket type is held, or an order that is not given back has it
+func (uc SeatUsecase) soldWithoutSeat(eventID int, ticket domain.Ticket, kontek context.Context) (bool, error) {
+	if ticket.Held > 0 {
+		return true, nil
+	}
+	orders, err := uc.OrderRepo.GetOrdersByEventID(eventID, kontek)
+	if err != nil {
+		return false, err
+	}
+	for _, order := range orders {
+		switch order.Status {
+		case domain.OrderFailed, domain.OrderExpired, domain.OrderCancelled:
+			continue
+		}
+		refunded := ticketQuantity(order.Refunded)
+		for _, line := range order.EventTicket {
+			if line.ID == ticket.ID && line.Quantity > refunded[line.ID] {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// the ticket lines of an order or a reservation with one ticket for every picked seat.
+// a seated ticket type can only be bought by picking its seat
+func seatedLines(seatRepo repository.SeatRepoInterface, event *domain.Event, tickets []domain.TicketLine, seatIDs []int, kontek context.Context) ([]domain.TicketLine, []domain.Seat, error) {
+	for _, ticket := range tickets {
+		if slices.ContainsFunc(event.Ticket, func(eventTicket domain.Ticket) bool { return eventTicket.ID == ticket.ID && eventTicket.Seated }) {
+			return nil, nil, domain.ErrSeatRequired
+		}
+	}
+	lines := slices.Clone(tickets)
+	if len(seatIDs) == 0 {
+		if len(lines) == 0 {
+			return nil, nil, domain.ErrNoTicket
+		}
+		return lines, nil, nil
+	}
+
+	seats, err := seatRepo.GetSeatsByIDs(event.ID, seatIDs, kontek)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, seat := range seats {
+		i := slices.IndexFunc(lines[len(tickets):], func(line domain.TicketLine) bool { return line.ID == seat.TicketID })
+		if i < 0 {
+			lines = append(lines, domain.TicketLine{ID: seat.TicketID, Quantity: 1})
+			continue
+		}
+		lines[len(tickets)+i].Quantity++
+	}
+	return lines, seats, nil
+}
+
+// the seat as it is kept on the order
+func orderSeats(seats []domain.Seat) []domain.Seat {
+	sold := make([]domain.Seat, 0, len(seats))
+	for _, seat := range seats {
+		sold = append(sold, domain.Seat{ID: seat.ID, EventID: seat.EventID, TicketID: seat.TicketID, Section: seat.Section, Row: seat.Row, Number: seat.Number, Status: domain.SeatSold})
+	}
+	return sold
+}
+
+// give the sold seats of the order back to the seat map, every one of them when ids is nil
+func releaseOrderSeats(seatRepo repository.SeatRepoInterface, order *domain.Order, ids []int, kontek context.Context) error {
+	var released []int
+	seats := make([]domain.Seat, 0, len(order.Seats))
+	for _, seat := range order.Seats {
+		if seat.Status == domain.SeatSold && (ids == nil || slices.Contains(ids, seat.ID)) {
+			seat.Status = domain.SeatReleased
+			released = append(released, seat.ID)
+		}
+		seats = append(seats, seat)
+	}
+	if len(released) == 0 {
+		return nil
+	}
+	order.Seats = seats
+	return seatRepo.ReleaseSeats(released, kontek)
+}
+
+// the seat and its ticket of a refund, every seat must be sold in the order
+func refundedSeats(order *domain.Order, seatIDs []int) ([]domain.Ticket, error) {
+	var tickets []domain.Ticket
+	for _, id := range seatIDs {
+		i := slices.IndexFunc(order.Seats, func(seat domain.Seat) bool { return seat.ID == id && seat.Status == domain.SeatSold })
+		if i < 0 {
+			return nil, domain.NewError(domain.ErrNotFound, "SEAT_NOT_IN_ORDER", fmt.Sprintf("SEAT %d IS NOT SOLD IN THAT ORDER", id))
+		}
+		ticketID := order.Seats[i].TicketID
+		if j := slices.IndexFunc(tickets, func(ticket domain.Ticket) bool { return ticket.ID == ticketID }); j >= 0 {
+			tickets[j].Quantity++
+			continue
+		}
+		for _, line := range order.EventTicket {
+			if line.ID == ticketID {
+				line.Quantity = 1
+				tickets = append(tickets, line)
+				break
+			}
+		}
+	}
+	return tickets, nil
+}
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"fmt"
+	"math/rand"
+	"pemesananTiketOnlineGo/internal/domain"
+	"sync"
+	"testing"
+)
+
+// many buyer pick the same seat at the same time, no seat is sold twice and the seat map
+// match the order
+func TestCreateOrderSeatsParallel(t *testing.T) {
+	cases := []struct {
+		name    string
+		orders  int
+		balance int64
+	}{
+		// every order can pay, it only fail on a taken seat
+		{name: "taken seat", orders: 60, balance: 100000000},
+		// most order can't pay, their seat go back to the map
+		{name: "failed payment", orders: 60, balance: 150000},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				orderUsecase := repos.orderUsecase()
+				seatUsecase := NewSeatUsecase(repos.Seat, repos.Event, repos.Order, repos.UnitOfWork)
+				kontek := systemContext()
+
+				event := newTestEvent(t, repos, "Seats",
+					domain.Ticket{Type: "SEATED", Price: domain.NewMoney(100000, domain.DefaultCurrency), Quantity: 40})
+				seatMap, err := seatUsecase.CreateSeatMap(domain.SeatMapRequest{EventID: event.ID, Sections: []domain.SeatSectionRequest{
+					{Name: "A", TicketID: event.Ticket[0].ID, Rows: []domain.SeatRowRequest{{Name: "1", Seats: 20}, {Name: "2", Seats: 20}}},
+				}}, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var seatIDs []int
+				for _, row := range seatMap.Sections[0].Rows {
+					for _, seat := range row.Seats {
+						seatIDs = append(seatIDs, seat.ID)
+					}
+				}
+				var users []int
+				for i := 1; i <= 10; i++ {
+					users = append(users, newTestUser(t, repos, fmt.Sprintf("user%d", i), tc.balance).ID)
+				}
+
+				var wg sync.WaitGroup
+				for i := 0; i < tc.orders; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						orderReq := domain.OrderRequest{UserID: users[i%len(users)], EventID: event.ID}
+						random := rand.New(rand.NewSource(int64(i)))
+						for _, pick := range random.Perm(len(seatIDs))[:i%3+1] {
+							orderReq.Seats = append(orderReq.Seats, seatIDs[pick])
+						}
+						orderUsecase.CreateOrder(orderReq, kontek)
+					}(i)
+				}
+				wg.Wait()
+
+				orders, err := repos.Order.GetAllOrders(kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				seatOrder := map[int]int{}
+				sold := 0
+				for _, order := range orders {
+					if order.Status != domain.OrderFulfilled {
+						continue
+					}
+					for _, ticket := range order.EventTicket {
+						sold += ticket.Quantity
+					}
+					for _, seat := range order.Seats {
+						if other, taken := seatOrder[seat.ID]; taken {
+							t.Errorf("seat %d sold to order %d and %d", seat.ID, other, order.ID)
+						}
+						seatOrder[seat.ID] = order.ID
+					}
+				}
+				if len(seatOrder) == 0 {
+					t.Fatal("no seat was sold")
+				}
+				if len(seatOrder) != sold {
+					t.Errorf("%d seat sold for %d seated ticket", len(seatOrder), sold)
+				}
+
+				// every seat sold by an order is sold in the seat map and the other is still available
+				seats, err := repos.Seat.GetSeatsByEventID(event.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, seat := range seats {
+					orderID, inOrder := seatOrder[seat.ID]
+					if inOrder && (seat.Status != domain.SeatSold || seat.OrderID != orderID) {
+						t.Errorf("seat %d is %s for order %d, the order say %d", seat.ID, seat.Status, seat.OrderID, orderID)
+					}
+					if !inOrder && seat.Status != domain.SeatAvailable {
+						t.Errorf("seat %d of no order is %s", seat.ID, seat.Status)
+					}
+				}
+				stock, err := repos.Event.GetEventByID(event.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stock.Ticket[0].Quantity+sold != len(seatIDs) {
+					t.Errorf("stock %d + sold %d != %d seat", stock.Ticket[0].Quantity, sold, len(seatIDs))
+				}
+			})
+		}
+	}
+}
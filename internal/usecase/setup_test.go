@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/payment"
+	"pemesananTiketOnlineGo/internal/repository"
+	"testing"
+	"time"
+)
+
+// every storage backend the usecase run on
+var backends = []string{"memory", "sqlite"}
+
+// the repos of one test, on a fresh memory store or a fresh sqlite file
+type testRepos struct {
+	Event        repository.EventRepoInterface
+	User         repository.UserRepoInterface
+	Order        repository.OrderRepoInterface
+	Wallet       repository.WalletRepoInterface
+	Promo        repository.PromoRepoInterface
+	Pricing      repository.PricingRepoInterface
+	Seat         repository.SeatRepoInterface
+	Waitlist     repository.WaitlistRepoInterface
+	Queue        repository.QueueRepoInterface
+	IssuedTicket repository.IssuedTicketRepoInterface
+	Hold         repository.HoldRepoInterface
+	Payment      repository.PaymentRepoInterface
+	UnitOfWork   repository.UnitOfWorkInterface
+}
+
+func newTestRepos(t testing.TB, backend string) testRepos {
+	t.Helper()
+	if backend == "memory" {
+		return testRepos{
+			Event:        repository.NewEventRepo(),
+			User:         repository.NewUserRepo(),
+			Order:        repository.NewOrderRepo(),
+			Wallet:       repository.NewWalletRepo(),
+			Promo:        repository.NewPromoRepo(),
+			Pricing:      repository.NewPricingRepo(),
+			Seat:         repository.NewSeatRepo(),
+			Waitlist:     repository.NewWaitlistRepo(),
+			Queue:        repository.NewQueueRepo(),
+			IssuedTicket: repository.NewIssuedTicketRepo(),
+			Hold:         repository.NewHoldRepo(),
+			Payment:      repository.NewPaymentRepo(),
+			UnitOfWork:   repository.NewUnitOfWork(),
+		}
+	}
+	db, err := repository.OpenSqlite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return testRepos{
+		Event:        repository.NewEventRepoSql(db),
+		User:         repository.NewUserRepoSql(db),
+		Order:        repository.NewOrderRepoSql(db),
+		Wallet:       repository.NewWalletRepoSql(db),
+		Promo:        repository.NewPromoRepoSql(db),
+		Pricing:      repository.NewPricingRepoSql(db),
+		Seat:         repository.NewSeatRepoSql(db),
+		Waitlist:     repository.NewWaitlistRepoSql(db),
+		Queue:        repository.NewQueueRepoSql(db),
+		IssuedTicket: repository.NewIssuedTicketRepoSql(db),
+		Hold:         repository.NewHoldRepoSql(db),
+		Payment:      repository.NewPaymentRepoSql(db),
+		UnitOfWork:   repository.NewUnitOfWorkSql(db),
+	}
+}
+
+// waitlist usecase whose offer last for the window
+func (repos testRepos) waitlistUsecase(window time.Duration) WaitlistUsecase {
+	return NewWaitlistUsecase(repos.Waitlist, repos.Event, repos.Hold, repos.Order, repos.UnitOfWork, window).(WaitlistUsecase)
+}
+
+// order usecase that pay from the wallet
+func (repos testRepos) orderUsecase() OrderUsecase {
+	return NewOrderUsecase(repos.Order, repos.Event, repos.User, repos.Promo, repos.Pricing, repos.Seat, repos.Waitlist, repos.Queue, repos.IssuedTicket,
+		payment.Providers{domain.PaymentWallet: payment.NewWalletProvider(repos.Wallet)}, repos.UnitOfWork, repos.waitlistUsecase(time.Minute), 0).(OrderUsecase)
+}
+
+// hold usecase whose hold last for the window, a negative window hold an already expired ticket
+func (repos testRepos) holdUsecase(window time.Duration) HoldUsecase {
+	return NewHoldUsecase(repos.Hold, repos.Order, repos.Event, repos.User, repos.Promo, repos.Pricing, repos.Seat, repos.Waitlist, repos.Queue, repos.IssuedTicket,
+		payment.Providers{domain.PaymentWallet: payment.NewWalletProvider(repos.Wallet)}, repos.UnitOfWork, repos.waitlistUsecase(time.Minute), window).(HoldUsecase)
+}
+
+// the seeding and the check of a test run as the server itself
+func systemContext() context.Context {
+	return domain.WithSystem(context.Background())
+}
+
+// an event a day from now with the ticket types
+func newTestEvent(t *testing.T, repos testRepos, name string, tickets ...domain.Ticket) *domain.Event {
+	t.Helper()
+	event, err := repos.Event.CreateEvent(&domain.Event{Name: name, Start: time.Now().Add(24 * time.Hour), End: time.Now().Add(27 * time.Hour), Description: name, Location: name, Ticket: tickets}, systemContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+// a user with the balance in minor unit of the default currency
+func newTestUser(t *testing.T, repos testRepos, name string, balance int64) *domain.User {
+	t.Helper()
+	user, err := repos.User.CreateUser(&domain.User{Name: name}, systemContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance > 0 {
+		if _, err := repos.Wallet.AddEntry(&domain.WalletEntry{UserID: user.ID, Kind: domain.WalletTopUp, Amount: domain.NewMoney(balance, domain.DefaultCurrency)}, systemContext()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return user
+}
+
+// the machine readable code of a domain error, empty when there is no error
+func errorCode(err error) string {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
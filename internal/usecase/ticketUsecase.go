@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+)
+
+// make a connection to repo
+type TicketUsecase struct {
+	EventRepo repository.EventRepoInterface
+	// the restocked ticket is offered to the waitlist right after the update
+	Waitlist OfferReturnedStock
+}
+
+func NewTicketUsecase(eventRepo repository.EventRepoInterface, waitlist OfferReturnedStock) TicketUsecaseInterface {
+	return TicketUsecase{
+		EventRepo: eventRepo,
+		Waitlist:  waitlist,
+	}
+}
+
+type TicketUsecaseInterface interface {
+	CreateTicket
+	GetTicketByID
+	UpdateTicket
+	CloseTicket
+}
+type CreateTicket interface {
+	CreateTicket(ticket domain.Ticket, kontek context.Context) (*domain.Ticket, error)
+}
+type GetTicketByID interface {
+	GetTicketByID(id int, kontek context.Context) (*domain.Ticket, error)
+}
+type UpdateTicket interface {
+	UpdateTicket(ticket domain.Ticket, kontek context.Context) (*domain.Ticket, error)
+}
+type CloseTicket interface {
+	CloseTicket(id int, kontek context.Context) (*domain.Ticket, error)
+}
+
+// add a new ticket type to an event the caller can manage
+func (uc TicketUsecase) CreateTicket(ticket domain.Ticket, kontek context.Context) (*domain.Ticket, error) {
+	event, err := uc.EventRepo.GetEventByID(ticket.EventID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageEvent(event, kontek) {
+		return nil, domain.ErrNotYourEvent
+	}
+	if err := checkTicketType(event, ticket); err != nil {
+		return nil, err
+	}
+	// a ticket type become seated when a seat map is made for it
+	ticket.Seated = false
+	return uc.EventRepo.CreateTicket(&ticket, kontek)
+}
+
+func (uc TicketUsecase) GetTicketByID(id int, kontek context.Context) (*domain.Ticket, error) {
+	return uc.EventRepo.GetTicketByID(id, kontek)
+}
+
+// rename, reprice, restock or close a ticket type, the ticket type stay in its event
+func (uc TicketUsecase) UpdateTicket(ticket domain.Ticket, kontek context.Context) (*domain.Ticket, error) {
+	old, err := uc.EventRepo.GetTicketByID(ticket.ID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	event, err := uc.EventRepo.GetEventByID(old.EventID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageEvent(event, kontek) {
+		return nil, domain.ErrNotYourEvent
+	}
+	ticket.EventID = old.EventID
+	// the stock of a seated ticket type is its available seat
+	ticket.Seated = old.Seated
+	if ticket.Seated {
+		ticket.Quantity = old.Quantity
+	}
+	if err := checkTicketType(event, ticket); err != nil {
+		return nil, err
+	}
+	if err := uc.EventRepo.UpdateTicket(&ticket, kontek); err != nil {
+		return nil, err
+	}
+	// the new stock belong to the waiting user first
+	if ticket.Quantity > old.Quantity {
+		uc.Waitlist.OfferReturnedStock([]int{ticket.ID}, kontek)
+	}
+	return &ticket, nil
+}
+
+// stop selling a ticket type, the sold and held ticket are not touched
+func (uc TicketUsecase) CloseTicket(id int, kontek context.Context) (*domain.Ticket, error) {
+	ticket, err := uc.EventRepo.GetTicketByID(id, kontek)
+	if err != nil {
+		return nil, err
+	}
+	event, err := uc.EventRepo.GetEventByID(ticket.EventID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageEvent(event, kontek) {
+		return nil, domain.ErrNotYourEvent
+	}
+	ticket.Closed = true
+	if err := uc.EventRepo.UpdateTicket(ticket, kontek); err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// the name of a ticket type is unique inside its event, every type is sold in the same currency
+// and its sale window end after it start
+func checkTicketType(event *domain.Event, ticket domain.Ticket) error {
+	if !ticket.ValidSaleWindow() {
+		return domain.ErrInvalidSaleWindow
+	}
+	tickets := []domain.Ticket{ticket}
+	for _, eventTicket := range event.Ticket {
+		if eventTicket.ID == ticket.ID {
+			continue
+		}
+		if eventTicket.Type == ticket.Type {
+			return domain.ErrTicketTypeExist
+		}
+		tickets = append(tickets, eventTicket)
+	}
+	return checkTicketCurrency(tickets)
+}
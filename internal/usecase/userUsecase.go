@@ -4,16 +4,20 @@ import (
 	"context"
 	"pemesananTiketOnlineGo/internal/domain"
 	"pemesananTiketOnlineGo/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // make a connection to repo
 type UserUsecase struct {
-	UserRepo repository.UserRepoInterface
+	UserRepo   repository.UserRepoInterface
+	WalletRepo repository.WalletRepoInterface
 }
 
-func NewUserUsecase(UserRepo repository.UserRepoInterface) UserUsecaseInterface {
+func NewUserUsecase(UserRepo repository.UserRepoInterface, WalletRepo repository.WalletRepoInterface) UserUsecaseInterface {
 	return UserUsecase{
-		UserRepo: UserRepo,
+		UserRepo:   UserRepo,
+		WalletRepo: WalletRepo,
 	}
 }
 
@@ -24,6 +28,7 @@ type UserUsecaseInterface interface {
 	UpdateUser
 	DeleteUser
 	GetAllUsers
+	SetUserRole
 }
 type CreateUser interface {
 	CreateUser(User domain.User, kontek context.Context) (*domain.User, error)
@@ -35,7 +40,7 @@ type GetUserByName interface {
 	GetUserByName(name string, kontek context.Context) (*domain.User, error)
 }
 type UpdateUser interface {
-	UpdateUser(User domain.User, kontek context.Context) error
+	UpdateUser(User domain.User, kontek context.Context) (*domain.User, error)
 }
 type DeleteUser interface {
 	DeleteUser(id int, kontek context.Context) error
@@ -43,22 +48,108 @@ type DeleteUser interface {
 type GetAllUsers interface {
 	GetAllUsers(kontek context.Context) ([]domain.User, error)
 }
+type SetUserRole interface {
+	SetUserRole(roleReq domain.RoleRequest, kontek context.Context) (*domain.User, error)
+}
 
+// register a user, only the hash of the password is kept
 func (uc UserUsecase) CreateUser(User domain.User, kontek context.Context) (*domain.User, error) {
+	if User.Password == "" {
+		return nil, domain.ErrPasswordRequired
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(User.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	User.Password = ""
+	User.PasswordHash = string(hash)
+	// only admin can give another role with SetUserRole
+	User.Role = domain.RoleCustomer
+	// the wallet start empty, money only come in by top up
+	User.Balance = domain.Money{}
 	return uc.UserRepo.CreateUser(&User, kontek)
 }
 func (uc UserUsecase) GetUserByID(id int, kontek context.Context) (*domain.User, error) {
-	return uc.UserRepo.GetUserByID(id, kontek)
+	if !canAccess(id, kontek) {
+		return nil, domain.ErrNotYourAccount
+	}
+	user, err := uc.UserRepo.GetUserByID(id, kontek)
+	if err != nil {
+		return nil, err
+	}
+	return uc.withBalance(user, kontek)
 }
 func (uc UserUsecase) GetUserByName(name string, kontek context.Context) (*domain.User, error) {
-	return uc.UserRepo.GetUserByName(name, kontek)
+	user, err := uc.UserRepo.GetUserByName(name, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if !canAccess(user.ID, kontek) {
+		return nil, domain.ErrNotYourAccount
+	}
+	return uc.withBalance(user, kontek)
 }
-func (uc UserUsecase) UpdateUser(User domain.User, kontek context.Context) error {
-	return uc.UserRepo.UpdateUser(&User, kontek)
+
+// only the name and the password can be changed, the balance only move through the wallet
+func (uc UserUsecase) UpdateUser(User domain.User, kontek context.Context) (*domain.User, error) {
+	user, err := uc.GetUserByID(User.ID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	user.Name = User.Name
+	if User.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(User.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = string(hash)
+	}
+	if err := uc.UserRepo.UpdateUser(user, kontek); err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 func (uc UserUsecase) DeleteUser(id int, kontek context.Context) error {
+	if !canAccess(id, kontek) {
+		return domain.ErrNotYourAccount
+	}
 	return uc.UserRepo.DeleteUser(id, kontek)
 }
 func (uc UserUsecase) GetAllUsers(kontek context.Context) ([]domain.User, error) {
-	return uc.UserRepo.GetAllUsers(kontek)
-}
\ No newline at end of file
+	users, err := uc.UserRepo.GetAllUsers(kontek)
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		if _, err := uc.withBalance(&users[i], kontek); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// give a role to a user, only admin can do it
+func (uc UserUsecase) SetUserRole(roleReq domain.RoleRequest, kontek context.Context) (*domain.User, error) {
+	if !isAdmin(kontek) {
+		return nil, domain.ErrRoleNotAllowed
+	}
+	user, err := uc.UserRepo.GetUserByID(roleReq.UserID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	user.Role = roleReq.Role
+	if err := uc.UserRepo.UpdateUser(user, kontek); err != nil {
+		return nil, err
+	}
+	return uc.withBalance(user, kontek)
+}
+
+// the balance is not saved on the user, it is the sum of the wallet ledger
+func (uc UserUsecase) withBalance(user *domain.User, kontek context.Context) (*domain.User, error) {
+	balance, err := uc.WalletRepo.GetBalance(user.ID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	user.Balance = balance
+	return user, nil
+}
@@ -0,0 +1,395 @@
+package usecase
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// make a connection to repo
+type WaitlistUsecase struct {
+	WaitlistRepo repository.WaitlistRepoInterface
+	EventRepo    repository.EventRepoInterface
+	HoldRepo     repository.HoldRepoInterface
+	OrderRepo    repository.OrderRepoInterface
+	UnitOfWork   repository.UnitOfWorkInterface
+	// how long the user has to pay the offered reservation
+	OfferWindow time.Duration
+}
+
+func NewWaitlistUsecase(waitlistRepo repository.WaitlistRepoInterface, eventRepo repository.EventRepoInterface, holdRepo repository.HoldRepoInterface, orderRepo repository.OrderRepoInterface, unitOfWork repository.UnitOfWorkInterface, offerWindow time.Duration) WaitlistUsecaseInterface {
+	return WaitlistUsecase{
+		WaitlistRepo: waitlistRepo,
+		EventRepo:    eventRepo,
+		HoldRepo:     holdRepo,
+		OrderRepo:    orderRepo,
+		UnitOfWork:   unitOfWork,
+		OfferWindow:  offerWindow,
+	}
+}
+
+type WaitlistUsecaseInterface interface {
+	JoinWaitlist
+	LeaveWaitlist
+	GetWaitlistByUserID
+	OfferWaitlist
+	OfferReturnedStock
+	RunWaitlistOffers
+}
+type JoinWaitlist interface {
+	JoinWaitlist(waitlistReq domain.WaitlistRequest, kontek context.Context) (*domain.WaitlistEntry, error)
+}
+type LeaveWaitlist interface {
+	LeaveWaitlist(id int, kontek context.Context) (*domain.WaitlistEntry, error)
+}
+type GetWaitlistByUserID interface {
+	GetWaitlistByUserID(userID int, kontek context.Context) ([]domain.WaitlistEntry, error)
+}
+type OfferWaitlist interface {
+	OfferWaitlist(kontek context.Context) (int, error)
+}
+type OfferReturnedStock interface {
+	OfferReturnedStock(ticketIDs []int, kontek context.Context)
+}
+type RunWaitlistOffers interface {
+	RunWaitlistOffers(kontek context.Context, interval time.Duration)
+}
+
+// wait for a ticket type that can't be bought now because it is sold out
+func (uc WaitlistUsecase) JoinWaitlist(waitlistReq domain.WaitlistRequest, kontek context.Context) (*domain.WaitlistEntry, error) {
+	event, err := uc.EventRepo.GetEventByID(waitlistReq.EventID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	if event.HasStarted(time.Now()) {
+		return nil, domain.ErrEventStarted
+	}
+	i := slices.IndexFunc(event.Ticket, func(ticket domain.Ticket) bool { return ticket.ID == waitlistReq.TicketID })
+	if i < 0 {
+		return nil, domain.ErrTicketNotFound
+	}
+	// a seat is picked by the buyer, so it can't be offered
+	if event.Ticket[i].Seated {
+		return nil, domain.ErrSeatRequired
+	}
+	if err := event.Ticket[i].CheckSale(time.Now()); err != nil {
+		return nil, err
+	}
+
+	entry := domain.WaitlistEntry{
+		UserID:   waitlistReq.UserID,
+		EventID:  event.ID,
+		TicketID: waitlistReq.TicketID,
+		Quantity: waitlistReq.Quantity,
+		Status:   domain.WaitlistWaiting,
+		JoinedAt: time.Now(),
+	}
+	lines := []domain.TicketLine{{ID: entry.TicketID, Quantity: entry.Quantity}}
+	err = uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		open, err := uc.WaitlistRepo.GetOpenWaitlist(entry.TicketID, kontek)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(open, func(other domain.WaitlistEntry) bool { return other.UserID == entry.UserID }) {
+			return domain.ErrWaitlistJoined
+		}
+		if err := checkPurchaseLimit(uc.OrderRepo, event, entry.UserID, lines, kontek); err != nil {
+			return err
+		}
+		// only a user who can't buy it now join the line
+		ticket, err := uc.EventRepo.GetTicketByID(entry.TicketID, kontek)
+		if err != nil {
+			return err
+		}
+		if ticket.Quantity-waitingQuantity(open) >= entry.Quantity {
+			return domain.ErrNotSoldOut
+		}
+
+		if _, err := uc.WaitlistRepo.CreateWaitlistEntry(&entry, kontek); err != nil {
+			return err
+		}
+		entry.Position = waitingAhead(open, entry.ID) + 1
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// stop waiting, an offered reservation is released with its own endpoint instead
+func (uc WaitlistUsecase) LeaveWaitlist(id int, kontek context.Context) (*domain.WaitlistEntry, error) {
+	var entry *domain.WaitlistEntry
+	err := uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		var err error
+		entry, err = uc.WaitlistRepo.GetWaitlistEntryByID(id, kontek)
+		if err != nil {
+			return err
+		}
+		if !canAccess(entry.UserID, kontek) {
+			return domain.ErrNotYourWaitlist
+		}
+		if entry.Status != domain.WaitlistWaiting {
+			return domain.NewError(domain.ErrConflict, "WAITLIST_NOT_WAITING", "WAITLIST ENTRY IS ALREADY "+entry.Status)
+		}
+		entry.Status = domain.WaitlistLeft
+		return uc.WaitlistRepo.UpdateWaitlistEntry(entry, kontek)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// every entry of the user with the place in the line of the one still waiting
+func (uc WaitlistUsecase) GetWaitlistByUserID(userID int, kontek context.Context) ([]domain.WaitlistEntry, error) {
+	if !canAccess(userID, kontek) {
+		return nil, domain.ErrNotYourWaitlist
+	}
+	entries, err := uc.WaitlistRepo.GetWaitlistByUserID(userID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if entry.Status != domain.WaitlistWaiting {
+			continue
+		}
+		open, err := uc.WaitlistRepo.GetOpenWaitlist(entry.TicketID, kontek)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].Position = waitingAhead(open, entry.ID) + 1
+	}
+	return entries, nil
+}
+
+// settle the offer that is paid or lapsed, then offer the stock that is back to the waiting user.
+// every ticket type serve its line first come first served, a user that want more than the stock
+// wait for more stock and the user behind never jump ahead of them
+func (uc WaitlistUsecase) OfferWaitlist(kontek context.Context) (int, error) {
+	open, err := uc.WaitlistRepo.GetOpenWaitlist(0, kontek)
+	if err != nil {
+		return 0, err
+	}
+
+	var ticketIDs []int
+	for _, entry := range open {
+		// one entry that can't be settled must not keep the line of every other ticket
+		if entry.Status == domain.WaitlistOffered {
+			if err := uc.settleOffer(entry.ID, kontek); err != nil {
+				log.Error().Err(err).Int("waitlist", entry.ID).Msg("Waitlist Offer Failed")
+			}
+		}
+		if !slices.Contains(ticketIDs, entry.TicketID) {
+			ticketIDs = append(ticketIDs, entry.TicketID)
+		}
+	}
+
+	offered := 0
+	for _, ticketID := range ticketIDs {
+		err := uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+			count, err := uc.offerTicket(ticketID, kontek)
+			offered += count
+			return err
+		})
+		if err != nil {
+			log.Error().Err(err).Int("ticket", ticketID).Msg("Waitlist Offer Failed")
+		}
+	}
+	return offered, nil
+}
+
+// offer the stock that just came back to the waiting user at once, the poller is only the fallback.
+// the stock is already back when this run, so a failure is only logged and the poller try again
+func (uc WaitlistUsecase) OfferReturnedStock(ticketIDs []int, kontek context.Context) {
+	for _, ticketID := range ticketIDs {
+		open, err := uc.WaitlistRepo.GetOpenWaitlist(ticketID, kontek)
+		if err != nil {
+			log.Error().Err(err).Int("ticket", ticketID).Msg("Waitlist Offer Failed")
+			continue
+		}
+		// nobody is waiting, the stock stay for the next buyer without locking anything
+		if !slices.ContainsFunc(open, func(entry domain.WaitlistEntry) bool { return entry.Status == domain.WaitlistWaiting }) {
+			continue
+		}
+		err = uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+			_, err := uc.offerTicket(ticketID, kontek)
+			return err
+		})
+		if err != nil {
+			log.Error().Err(err).Int("ticket", ticketID).Msg("Waitlist Offer Failed")
+		}
+	}
+}
+
+// run the offer every interval until kontek is done
+func (uc WaitlistUsecase) RunWaitlistOffers(kontek context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-kontek.Done():
+			return
+		case <-ticker.C:
+			offered, err := uc.OfferWaitlist(kontek)
+			if err != nil {
+				log.Error().Err(err).Msg("Waitlist Offer Failed")
+				continue
+			}
+			if offered > 0 {
+				log.Info().Int("offered", offered).Msg("Waitlist Offer Success")
+			}
+		}
+	}
+}
+
+// the offer is claimed once its reservation is paid, and done once it is released or expired
+func (uc WaitlistUsecase) settleOffer(id int, kontek context.Context) error {
+	return uc.UnitOfWork.WithinTransaction(kontek, func(kontek context.Context) error {
+		entry, err := uc.WaitlistRepo.GetWaitlistEntryByID(id, kontek)
+		if err != nil {
+			return err
+		}
+		if entry.Status != domain.WaitlistOffered {
+			return nil
+		}
+		hold, err := uc.HoldRepo.GetHoldByID(entry.HoldID, kontek)
+		if err != nil {
+			return err
+		}
+		switch hold.Status {
+		case domain.HoldConfirmed:
+			entry.Status = domain.WaitlistClaimed
+		case domain.HoldReleased, domain.HoldExpired:
+			entry.Status = domain.WaitlistExpired
+		default:
+			return nil
+		}
+		return uc.WaitlistRepo.UpdateWaitlistEntry(entry, kontek)
+	})
+}
+
+// reserve the stock of the ticket type for the waiting user in the order they joined
+func (uc WaitlistUsecase) offerTicket(ticketID int, kontek context.Context) (int, error) {
+	open, err := uc.WaitlistRepo.GetOpenWaitlist(ticketID, kontek)
+	if err != nil {
+		return 0, err
+	}
+	ticket, err := uc.EventRepo.GetTicketByID(ticketID, kontek)
+	if err != nil {
+		return 0, err
+	}
+	event, err := uc.EventRepo.GetEventByID(ticket.EventID, kontek)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	// the ticket can never be bought again, nobody is left waiting for it
+	if event.HasStarted(now) || ticket.CheckSale(now) == domain.ErrSaleEnded {
+		for _, entry := range open {
+			if entry.Status != domain.WaitlistWaiting {
+				continue
+			}
+			entry.Status = domain.WaitlistExpired
+			if err := uc.WaitlistRepo.UpdateWaitlistEntry(&entry, kontek); err != nil {
+				return 0, err
+			}
+		}
+		return 0, nil
+	}
+	if ticket.CheckSale(now) != nil {
+		return 0, nil
+	}
+
+	offered := 0
+	stock := ticket.Quantity
+	for _, entry := range open {
+		if entry.Status != domain.WaitlistWaiting {
+			continue
+		}
+		if stock < entry.Quantity {
+			break
+		}
+		hold := domain.Hold{
+			UserID:    entry.UserID,
+			EventID:   entry.EventID,
+			Ticket:    []domain.TicketLine{{ID: entry.TicketID, Quantity: entry.Quantity}},
+			Status:    domain.HoldHeld,
+			ExpiresAt: now.Add(uc.OfferWindow),
+		}
+		if err := uc.EventRepo.HoldTicketStock(hold.EventID, hold.Ticket, kontek); err != nil {
+			return 0, err
+		}
+		if _, err := uc.HoldRepo.CreateHold(&hold, kontek); err != nil {
+			return 0, err
+		}
+		stock -= entry.Quantity
+
+		entry.Status = domain.WaitlistOffered
+		entry.HoldID = hold.ID
+		entry.OfferExpiresAt = &hold.ExpiresAt
+		if err := uc.WaitlistRepo.UpdateWaitlistEntry(&entry, kontek); err != nil {
+			return 0, err
+		}
+		offered++
+	}
+	return offered, nil
+}
+
+// the stock that come back belong to the waiting user first, a buyer only get what is left after the waitlist
+func checkWaitlist(waitlistRepo repository.WaitlistRepoInterface, eventRepo repository.EventRepoInterface, tickets []domain.TicketLine, kontek context.Context) error {
+	requested := map[int]int{}
+	var ticketIDs []int
+	for _, ticket := range tickets {
+		if _, exist := requested[ticket.ID]; !exist {
+			ticketIDs = append(ticketIDs, ticket.ID)
+		}
+		requested[ticket.ID] += ticket.Quantity
+	}
+	for _, id := range ticketIDs {
+		open, err := waitlistRepo.GetOpenWaitlist(id, kontek)
+		if err != nil {
+			return err
+		}
+		// without a waiting user the stock is checked with the sale
+		waiting := waitingQuantity(open)
+		if waiting == 0 {
+			continue
+		}
+		ticket, err := eventRepo.GetTicketByID(id, kontek)
+		if err != nil {
+			return err
+		}
+		if ticket.Quantity-waiting < requested[id] {
+			return domain.ErrNotEnoughStock
+		}
+	}
+	return nil
+}
+
+// ticket wanted by the user still waiting in the line
+func waitingQuantity(open []domain.WaitlistEntry) int {
+	waiting := 0
+	for _, entry := range open {
+		if entry.Status == domain.WaitlistWaiting {
+			waiting += entry.Quantity
+		}
+	}
+	return waiting
+}
+
+// how many user still wait in the line before the entry
+func waitingAhead(open []domain.WaitlistEntry, id int) int {
+	ahead := 0
+	for _, entry := range open {
+		if entry.Status == domain.WaitlistWaiting && entry.ID < id {
+			ahead++
+		}
+	}
+	return ahead
+}
@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"fmt"
+	"pemesananTiketOnlineGo/internal/domain"
+	"testing"
+	"time"
+)
+
+// a restock is offered to the line at once in the order the user joined, a user that want more than
+// the stock wait for more and the user behind never jump ahead of them
+func TestUpdateTicketOfferWaitlist(t *testing.T) {
+	cases := []struct {
+		name string
+		// the quantity every user wait for, in the order they joined
+		wants   []int
+		restock int
+		status  []string
+	}{
+		{name: "first come first served", wants: []int{1, 1, 1}, restock: 2, status: []string{domain.WaitlistOffered, domain.WaitlistOffered, domain.WaitlistWaiting}},
+		{name: "first in line want more", wants: []int{2, 1}, restock: 1, status: []string{domain.WaitlistWaiting, domain.WaitlistWaiting}},
+		{name: "enough for everybody", wants: []int{1, 2}, restock: 3, status: []string{domain.WaitlistOffered, domain.WaitlistOffered}},
+	}
+	for _, backend := range backends {
+		for _, tc := range cases {
+			t.Run(backend+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				repos := newTestRepos(t, backend)
+				waitlist := repos.waitlistUsecase(time.Minute)
+				ticketUsecase := NewTicketUsecase(repos.Event, waitlist)
+				kontek := systemContext()
+
+				event := newTestEvent(t, repos, "Sold Out", domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 0})
+				var entries []*domain.WaitlistEntry
+				for i, quantity := range tc.wants {
+					user := newTestUser(t, repos, fmt.Sprintf("waiting%d", i+1), 0)
+					entry, err := waitlist.JoinWaitlist(domain.WaitlistRequest{UserID: user.ID, EventID: event.ID, TicketID: event.Ticket[0].ID, Quantity: quantity}, kontek)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if entry.Position != i+1 {
+						t.Errorf("user %d is at %d in the line, want %d", i+1, entry.Position, i+1)
+					}
+					entries = append(entries, entry)
+				}
+
+				ticket, err := repos.Event.GetTicketByID(event.Ticket[0].ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ticket.Quantity += tc.restock
+				if _, err := ticketUsecase.UpdateTicket(*ticket, kontek); err != nil {
+					t.Fatal(err)
+				}
+
+				held := 0
+				for i, entry := range entries {
+					saved, err := repos.Waitlist.GetWaitlistEntryByID(entry.ID, kontek)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if saved.Status != tc.status[i] {
+						t.Errorf("user %d is %s, want %s", i+1, saved.Status, tc.status[i])
+					}
+					if saved.Status == domain.WaitlistOffered {
+						held += saved.Quantity
+					}
+				}
+				stock, err := repos.Event.GetTicketByID(event.Ticket[0].ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stock.Held != held || stock.Quantity != tc.restock-held {
+					t.Errorf("stock is %d with %d held, want %d with %d held", stock.Quantity, stock.Held, tc.restock-held, held)
+				}
+			})
+		}
+	}
+}
+
+// an offer that is not paid in time go back to the stock and to the next user in the line
+func TestWaitlistOfferExpire(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			t.Parallel()
+			repos := newTestRepos(t, backend)
+			waitlist := repos.waitlistUsecase(time.Millisecond)
+			ticketUsecase := NewTicketUsecase(repos.Event, waitlist)
+			holdUsecase := repos.holdUsecase(time.Minute)
+			holdUsecase.Waitlist = waitlist
+			kontek := systemContext()
+
+			event := newTestEvent(t, repos, "Sold Out", domain.Ticket{Type: "GA", Price: domain.NewMoney(25000, domain.DefaultCurrency), Quantity: 0})
+			var entries []*domain.WaitlistEntry
+			for i := 1; i <= 3; i++ {
+				user := newTestUser(t, repos, fmt.Sprintf("waiting%d", i), 0)
+				entry, err := waitlist.JoinWaitlist(domain.WaitlistRequest{UserID: user.ID, EventID: event.ID, TicketID: event.Ticket[0].ID, Quantity: 1}, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				entries = append(entries, entry)
+			}
+			ticket, err := repos.Event.GetTicketByID(event.Ticket[0].ID, kontek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ticket.Quantity = 1
+			if _, err := ticketUsecase.UpdateTicket(*ticket, kontek); err != nil {
+				t.Fatal(err)
+			}
+
+			// the first offer lapse, the reaper give it to the second user and the poller settle the first
+			time.Sleep(10 * time.Millisecond)
+			if _, err := holdUsecase.ReapExpiredHolds(kontek); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := waitlist.OfferWaitlist(kontek); err != nil {
+				t.Fatal(err)
+			}
+
+			want := []string{domain.WaitlistExpired, domain.WaitlistOffered, domain.WaitlistWaiting}
+			for i, entry := range entries {
+				saved, err := repos.Waitlist.GetWaitlistEntryByID(entry.ID, kontek)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if saved.Status != want[i] {
+					t.Errorf("user %d is %s, want %s", i+1, saved.Status, want[i])
+				}
+			}
+		})
+	}
+}
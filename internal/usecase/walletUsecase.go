@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"pemesananTiketOnlineGo/internal/domain"
+	"pemesananTiketOnlineGo/internal/repository"
+)
+
+// make a connection to repo
+type WalletUsecase struct {
+	WalletRepo repository.WalletRepoInterface
+	UserRepo   repository.UserRepoInterface
+	OrderRepo  repository.OrderRepoInterface
+}
+
+func NewWalletUsecase(walletRepo repository.WalletRepoInterface, userRepo repository.UserRepoInterface, orderRepo repository.OrderRepoInterface) WalletUsecaseInterface {
+	return WalletUsecase{
+		WalletRepo: walletRepo,
+		UserRepo:   userRepo,
+		OrderRepo:  orderRepo,
+	}
+}
+
+type WalletUsecaseInterface interface {
+	AdjustBalance
+	GetStatement
+	Reconcile
+}
+type AdjustBalance interface {
+	AdjustBalance(adjustmentReq domain.AdjustmentRequest, kontek context.Context) (*domain.WalletEntry, error)
+}
+type GetStatement interface {
+	GetStatement(userID int, kontek context.Context) (*domain.Statement, error)
+}
+type Reconcile interface {
+	Reconcile(kontek context.Context) (*domain.Reconciliation, error)
+}
+
+// correct the balance of a user or credit the money they paid at the counter, only admin can do it
+func (uc WalletUsecase) AdjustBalance(adjustmentReq domain.AdjustmentRequest, kontek context.Context) (*domain.WalletEntry, error) {
+	if !isAdmin(kontek) {
+		return nil, domain.ErrRoleNotAllowed
+	}
+	if _, err := uc.UserRepo.GetUserByID(adjustmentReq.UserID, kontek); err != nil {
+		return nil, err
+	}
+	return uc.WalletRepo.AddEntry(&domain.WalletEntry{
+		UserID: adjustmentReq.UserID,
+		Kind:   domain.WalletAdjustment,
+		Amount: adjustmentReq.Amount,
+		Note:   adjustmentReq.Note,
+	}, kontek)
+}
+
+func (uc WalletUsecase) GetStatement(userID int, kontek context.Context) (*domain.Statement, error) {
+	if !canAccess(userID, kontek) {
+		return nil, domain.ErrNotYourAccount
+	}
+	entries, err := uc.WalletRepo.GetEntriesByUserID(userID, kontek)
+	if err != nil {
+		return nil, err
+	}
+	statement := domain.Statement{UserID: userID, Balance: domain.NewMoney(0, domain.DefaultCurrency), Entries: entries}
+	for _, entry := range entries {
+		if statement.Balance, err = statement.Balance.Add(entry.Amount); err != nil {
+			return nil, err
+		}
+	}
+	return &statement, nil
+}
+
+// check that every order paid from the wallet is debited once with its total, every refund is credited,
+// no entry point to an unknown order and no balance ever went below zero
+func (uc WalletUsecase) Reconcile(kontek context.Context) (*domain.Reconciliation, error) {
+	if !isAdmin(kontek) {
+		return nil, domain.ErrRoleNotAllowed
+	}
+	entries, err := uc.WalletRepo.GetAllEntries(kontek)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := uc.OrderRepo.GetAllOrders(kontek)
+	if err != nil {
+		return nil, err
+	}
+
+	report := domain.Reconciliation{OrdersChecked: len(orders), EntriesChecked: len(entries), Mismatches: []domain.Mismatch{}}
+	mismatch := func(m domain.Mismatch) {
+		report.Mismatches = append(report.Mismatches, m)
+	}
+
+	// sum of money that can't be added together is reported instead of stopping the check
+	add := func(sum domain.Money, amount domain.Money, entry domain.WalletEntry) domain.Money {
+		total, err := sum.Add(amount)
+		if err != nil {
+			mismatch(domain.Mismatch{OrderID: entry.OrderID, UserID: entry.UserID, Reason: "CURRENCY_MISMATCH", Expected: sum, Actual: amount})
+			return sum
+		}
+		return total
+	}
+
+	// sum the ledger per order and walk the balance of every user
+	debited := map[int]domain.Money{}
+	credited := map[int]domain.Money{}
+	entryUser := map[int]int{}
+	balance := map[int]domain.Money{}
+	for _, entry := range entries {
+		switch entry.Kind {
+		case domain.WalletPurchase:
+			debited[entry.OrderID] = add(debited[entry.OrderID], entry.Amount.Neg(), entry)
+			entryUser[entry.OrderID] = entry.UserID
+		case domain.WalletRefund:
+			credited[entry.OrderID] = add(credited[entry.OrderID], entry.Amount, entry)
+			entryUser[entry.OrderID] = entry.UserID
+		}
+		balance[entry.UserID] = add(balance[entry.UserID], entry.Amount, entry)
+		if balance[entry.UserID].IsNegative() {
+			mismatch(domain.Mismatch{UserID: entry.UserID, Reason: "BALANCE_BELOW_ZERO", Expected: domain.NewMoney(0, entry.Amount.Currency), Actual: balance[entry.UserID]})
+		}
+	}
+
+	known := map[int]bool{}
+	for _, order := range orders {
+		known[order.ID] = true
+		// only order paid from the wallet has money in the ledger
+		var paid, refunded domain.Money
+		if order.PaymentMethod == domain.PaymentWallet && order.WasPaid() {
+			paid = order.TotalPrice
+			refunded = order.RefundAmount
+		}
+		if !debited[order.ID].Equal(paid) {
+			mismatch(domain.Mismatch{OrderID: order.ID, UserID: order.User.ID, Reason: "PURCHASE_NOT_MATCH_ORDER_TOTAL", Expected: paid, Actual: debited[order.ID]})
+		}
+		if !credited[order.ID].Equal(refunded) {
+			mismatch(domain.Mismatch{OrderID: order.ID, UserID: order.User.ID, Reason: "REFUND_NOT_MATCH_ORDER_REFUND", Expected: refunded, Actual: credited[order.ID]})
+		}
+		if userID, exist := entryUser[order.ID]; exist && userID != order.User.ID {
+			mismatch(domain.Mismatch{OrderID: order.ID, UserID: userID, Reason: "ENTRY_USER_NOT_ORDER_USER"})
+		}
+	}
+	for orderID, userID := range entryUser {
+		if !known[orderID] {
+			mismatch(domain.Mismatch{OrderID: orderID, UserID: userID, Reason: "ENTRY_FOR_UNKNOWN_ORDER", Actual: add(credited[orderID], debited[orderID].Neg(), domain.WalletEntry{OrderID: orderID, UserID: userID})})
+		}
+	}
+
+	report.Balanced = len(report.Mismatches) == 0
+	return &report, nil
+}